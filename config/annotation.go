@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AnnotationSyntax describes the outline comments holding annotations: '#', optional spaces,
+// Prefix, the key, Separator and the value, e.g. "# @owner payments" with the default syntax.
+// The value may be empty, e.g. "# @deprecated"
+type AnnotationSyntax struct {
+	Prefix    string // "@" when empty
+	Separator string // spaces when empty, e.g. "=" for "# @owner=payments"
+}
+
+// DefaultAnnotationSyntax is the "# @key value" syntax
+var DefaultAnnotationSyntax = AnnotationSyntax{Prefix: "@"}
+
+func (s AnnotationSyntax) prefix() string {
+	if s.Prefix == "" {
+		return "@"
+	}
+	return s.Prefix
+}
+
+// Parse returns the key and value of an annotation comment, ok is false for other comments
+func (s AnnotationSyntax) Parse(comment string) (key, value string, ok bool) {
+	if !strings.HasPrefix(comment, "#") {
+		return "", "", false
+	}
+	text := strings.TrimLeft(comment[1:], " \t")
+	if !strings.HasPrefix(text, s.prefix()) {
+		return "", "", false
+	}
+	text = strings.TrimRight(text[len(s.prefix()):], " \t\r")
+
+	if s.Separator == "" {
+		key, value = text, ""
+		if i := strings.IndexAny(text, " \t"); i >= 0 {
+			key, value = text[:i], text[i:]
+		}
+	} else {
+		key, value, _ = strings.Cut(text, s.Separator)
+		key = strings.TrimRight(key, " \t")
+	}
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	return key, strings.TrimLeft(value, " \t"), true
+}
+
+// Validate checks that an annotation is read back as is once formatted: the key must not be empty nor hold
+// spaces or the separator, and neither the key nor the value may hold a line break, which would end the
+// comment and let the rest of the value be read as directives
+func (s AnnotationSyntax) Validate(key, value string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("annotation key must not be empty")
+	case strings.ContainsAny(key, "\r\n") || strings.ContainsAny(value, "\r\n"):
+		return fmt.Errorf("annotation %q must not contain a line break", key)
+	case strings.ContainsAny(key, " \t"):
+		return fmt.Errorf("annotation key %q must not contain spaces", key)
+	case s.Separator != "" && strings.Contains(key, s.Separator):
+		return fmt.Errorf("annotation key %q must not contain the separator %q", key, s.Separator)
+	}
+	return nil
+}
+
+// Format returns the comment of an annotation, key and value must pass Validate
+func (s AnnotationSyntax) Format(key, value string) string {
+	if value == "" {
+		return "# " + s.prefix() + key
+	}
+	separator := s.Separator
+	if separator == "" {
+		separator = " "
+	}
+	return "# " + s.prefix() + key + separator + value
+}
+
+// Annotations returns the annotations of comments, the first one wins when a key is repeated
+func (s AnnotationSyntax) Annotations(comments []string) map[string]string {
+	annotations := make(map[string]string)
+	for _, comment := range comments {
+		if key, value, ok := s.Parse(comment); ok {
+			if _, ok := annotations[key]; !ok {
+				annotations[key] = value
+			}
+		}
+	}
+	return annotations
+}
+
+// Comments returns comments updated to hold annotations: the comments of changed annotations are
+// rewritten in place, those of removed (or repeated) keys are dropped, and new annotations are
+// appended in key order. comments is returned as is when it already holds annotations
+func (s AnnotationSyntax) Comments(comments []string, annotations map[string]string) []string {
+	if sameAnnotations(s.Annotations(comments), annotations) {
+		return comments
+	}
+
+	updated := make([]string, 0, len(comments)+len(annotations))
+	written := make(map[string]struct{})
+	for _, comment := range comments {
+		key, value, ok := s.Parse(comment)
+		if !ok {
+			updated = append(updated, comment)
+			continue
+		}
+		newValue, keep := annotations[key]
+		if _, done := written[key]; !keep || done {
+			continue
+		}
+		written[key] = struct{}{}
+		if newValue != value {
+			comment = s.Format(key, newValue)
+		}
+		updated = append(updated, comment)
+	}
+
+	added := make([]string, 0)
+	for key := range annotations {
+		if _, ok := written[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		updated = append(updated, s.Format(key, annotations[key]))
+	}
+	return updated
+}
+
+func sameAnnotations(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Annotator represents a directive exposing the annotations of its outline comments.
+type Annotator interface {
+	GetAnnotations() map[string]string
+	SetAnnotations(annotations map[string]string)
+	GetAnnotationSyntax() AnnotationSyntax
+	SetAnnotationSyntax(syntax AnnotationSyntax)
+}
+
+// DefaultAnnotations represents the default annotation holder.
+// Annotations is nil unless the directive was parsed with annotations or one was set,
+// the dumper then writes it into the outline comments of the directive.
+type DefaultAnnotations struct {
+	Annotations      map[string]string
+	AnnotationSyntax AnnotationSyntax
+}
+
+// GetAnnotations returns the annotations, changes to the map are written by the dumper.
+func (d *DefaultAnnotations) GetAnnotations() map[string]string {
+	return d.Annotations
+}
+
+// SetAnnotations sets the annotations, nil keeps the outline comments as they are.
+func (d *DefaultAnnotations) SetAnnotations(annotations map[string]string) {
+	d.Annotations = annotations
+}
+
+// SetAnnotation sets the value of an annotation, it returns an error and sets nothing when the annotation
+// does not pass AnnotationSyntax.Validate.
+func (d *DefaultAnnotations) SetAnnotation(key, value string) error {
+	if err := d.AnnotationSyntax.Validate(key, value); err != nil {
+		return err
+	}
+	if d.Annotations == nil {
+		d.Annotations = make(map[string]string)
+	}
+	d.Annotations[key] = value
+	return nil
+}
+
+// GetAnnotationSyntax returns the syntax of the annotation comments.
+func (d *DefaultAnnotations) GetAnnotationSyntax() AnnotationSyntax {
+	return d.AnnotationSyntax
+}
+
+// SetAnnotationSyntax sets the syntax of the annotation comments.
+func (d *DefaultAnnotations) SetAnnotationSyntax(syntax AnnotationSyntax) {
+	d.AnnotationSyntax = syntax
+}
+
+// AnnotatedComment returns the outline comments of d updated with its annotations
+func AnnotatedComment(d IDirective) []string {
+	if d.GetAnnotations() == nil {
+		return d.GetComment()
+	}
+	return d.GetAnnotationSyntax().Comments(d.GetComment(), d.GetAnnotations())
+}
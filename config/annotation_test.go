@@ -0,0 +1,103 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAnnotationSyntax_Parse(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		syntax  AnnotationSyntax
+		comment string
+		key     string
+		value   string
+		ok      bool
+	}{
+		{"default", DefaultAnnotationSyntax, "# @owner payments", "owner", "payments", true},
+		{"no space after #", DefaultAnnotationSyntax, "#@ticket OPS-123", "ticket", "OPS-123", true},
+		{"value with spaces", DefaultAnnotationSyntax, "# @note  keep   me ", "note", "keep   me", true},
+		{"no value", DefaultAnnotationSyntax, "# @deprecated", "deprecated", "", true},
+		{"zero syntax is the default one", AnnotationSyntax{}, "# @owner payments", "owner", "payments", true},
+		{"plain comment", DefaultAnnotationSyntax, "# owner payments", "", "", false},
+		{"no key", DefaultAnnotationSyntax, "# @ payments", "", "", false},
+		{"separator", AnnotationSyntax{Prefix: "gonginx:", Separator: "="}, "# gonginx:managed-by = terraform", "managed-by", "terraform", true},
+		{"missing separator", AnnotationSyntax{Prefix: "gonginx:", Separator: "="}, "# gonginx:managed by terraform", "", "", false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			key, value, ok := tt.syntax.Parse(tt.comment)
+			assert.Equal(t, ok, tt.ok)
+			assert.Equal(t, key, tt.key)
+			assert.Equal(t, value, tt.value)
+		})
+	}
+}
+
+func TestAnnotationSyntax_Comments(t *testing.T) {
+	t.Parallel()
+	comments := []string{"# payments API", "# @owner payments", "# @ticket OPS-123", "#@owner billing"}
+	syntax := DefaultAnnotationSyntax
+	assert.DeepEqual(t, syntax.Annotations(comments), map[string]string{"owner": "payments", "ticket": "OPS-123"})
+
+	// unchanged annotations keep the comments as they are
+	assert.DeepEqual(t, syntax.Comments(comments, map[string]string{"owner": "payments", "ticket": "OPS-123"}), comments)
+
+	// changed annotations are rewritten in place, removed ones dropped and new ones appended
+	assert.DeepEqual(t, syntax.Comments(comments, map[string]string{"owner": "search", "managed-by": "terraform", "deprecated": ""}), []string{
+		"# payments API",
+		"# @owner search",
+		"# @deprecated",
+		"# @managed-by terraform",
+	})
+
+	syntax = AnnotationSyntax{Prefix: "gonginx:", Separator: "="}
+	assert.DeepEqual(t, syntax.Comments(nil, map[string]string{"owner": "payments"}), []string{"# gonginx:owner=payments"})
+}
+
+func TestAnnotationSyntax_Validate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		syntax     AnnotationSyntax
+		key, value string
+		err        string
+	}{
+		{syntax: DefaultAnnotationSyntax, key: "owner", value: "payments team"},
+		{syntax: DefaultAnnotationSyntax, key: "", err: "annotation key must not be empty"},
+		{syntax: DefaultAnnotationSyntax, key: "owner", value: "x\nworker_processes 99;", err: `annotation "owner" must not contain a line break`},
+		{syntax: DefaultAnnotationSyntax, key: "owner", value: "x\r", err: `annotation "owner" must not contain a line break`},
+		{syntax: DefaultAnnotationSyntax, key: "my owner", err: `annotation key "my owner" must not contain spaces`},
+		{syntax: AnnotationSyntax{Separator: "="}, key: "a=b", value: "c", err: `annotation key "a=b" must not contain the separator "="`},
+		{syntax: AnnotationSyntax{Separator: "="}, key: "a", value: "b=c"},
+	}
+	for _, tt := range tests {
+		err := tt.syntax.Validate(tt.key, tt.value)
+		if tt.err == "" {
+			assert.NilError(t, err)
+			continue
+		}
+		assert.Error(t, err, tt.err)
+	}
+
+	d := &Directive{Name: "listen"}
+	assert.Error(t, d.SetAnnotation("owner", "x\nworker_processes 99;"), `annotation "owner" must not contain a line break`)
+	assert.Assert(t, d.GetAnnotations() == nil)
+}
+
+func TestAnnotatedComment(t *testing.T) {
+	t.Parallel()
+	d := &Directive{Name: "listen", Comment: []string{"# @owner payments"}}
+	assert.DeepEqual(t, AnnotatedComment(d), []string{"# @owner payments"})
+
+	// once set, the annotations replace the ones of the comments
+	assert.NilError(t, d.SetAnnotation("ticket", "OPS-1"))
+	assert.DeepEqual(t, AnnotatedComment(d), []string{"# @ticket OPS-1"})
+
+	d.SetAnnotations(DefaultAnnotationSyntax.Annotations(d.Comment))
+	delete(d.GetAnnotations(), "owner")
+	assert.DeepEqual(t, AnnotatedComment(d), []string{})
+}
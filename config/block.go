@@ -5,7 +5,9 @@ type Block struct {
 	Directives  []IDirective
 	IsLuaBlock  bool
 	LiteralCode string
+	Language    string // language of LiteralCode for raw-content blocks other than Lua, e.g. "js"
 	Parent      IDirective
+	DefaultBraces
 }
 
 // SetParent sets the parent directive.
@@ -23,6 +25,11 @@ func (b *Block) GetDirectives() []IDirective {
 	return b.Directives
 }
 
+// SetDirectives replaces the directives of the block.
+func (b *Block) SetDirectives(directives []IDirective) {
+	b.Directives = directives
+}
+
 // GetCodeBlock returns the literal code block.
 func (b *Block) GetCodeBlock() string {
 	return b.LiteralCode
@@ -47,3 +54,99 @@ func (b *Block) FindDirectives(directiveName string) []IDirective {
 
 	return directives
 }
+
+// directiveParameters returns the parameters of the first directive of block named name, ok is false without one
+func directiveParameters(block IBlock, name string) (parameters []Parameter, ok bool) {
+	if block == nil {
+		return nil, false
+	}
+	for _, d := range block.GetDirectives() {
+		if d.GetName() == name {
+			return d.GetParameters(), true
+		}
+	}
+	return nil, false
+}
+
+// setDirective sets the parameters of the first directive of block named name, appending it when missing
+func setDirective(block IBlock, parent IDirective, name string, parameters ...Parameter) IBlock {
+	if block != nil {
+		for _, d := range block.GetDirectives() {
+			if dir, ok := d.(*Directive); ok && dir.Name == name {
+				dir.Parameters = parameters
+				return block
+			}
+		}
+	}
+	return appendDirective(block, &Directive{Name: name, Parameters: parameters, Parent: parent})
+}
+
+// setFlag sets an on/off directive of block, appending it when missing
+func setFlag(block IBlock, parent IDirective, name string, on bool) IBlock {
+	p := Parameter{}
+	p.SetBool(on)
+	return setDirective(block, parent, name, p)
+}
+
+// replaceDirectives replaces the directives of block named name, the new ones taking the place of the first
+// one, or the top of the block. The comments of the replaced directives are kept by position
+func replaceDirectives(block IBlock, parent IDirective, name string, replacements []*Directive) IBlock {
+	var existing []IDirective
+	if block != nil {
+		existing = block.GetDirectives()
+	}
+	var previous []IDirective
+	directives := make([]IDirective, 0, len(existing)+len(replacements))
+	at := -1
+	for _, d := range existing {
+		if d.GetName() == name {
+			if at < 0 {
+				at = len(directives)
+			}
+			previous = append(previous, d)
+			continue
+		}
+		directives = append(directives, d)
+	}
+	if at < 0 {
+		at = 0
+	}
+
+	added := make([]IDirective, 0, len(replacements))
+	for i, d := range replacements {
+		d.Parent = parent
+		if i < len(previous) {
+			d.Comment = previous[i].GetComment()
+			d.InlineComment = previous[i].GetInlineComment()
+		}
+		added = append(added, d)
+	}
+	directives = append(directives[:at], append(added, directives[at:]...)...)
+	return setDirectives(block, directives)
+}
+
+// appendDirective appends a directive to block, before the comments that end it
+func appendDirective(block IBlock, directive IDirective) IBlock {
+	directives := []IDirective{}
+	if block != nil {
+		directives = block.GetDirectives()
+	}
+	others, trailing := splitTrailingComment(directives)
+	updated := make([]IDirective, 0, len(directives)+1)
+	updated = append(updated, others...)
+	updated = append(updated, directive)
+	return setDirectives(block, append(updated, trailing...))
+}
+
+// setDirectives sets the directives of block, a *Block being created when block is another type
+func setDirectives(block IBlock, directives []IDirective) IBlock {
+	b, ok := block.(*Block)
+	if !ok {
+		b = &Block{}
+		if block != nil {
+			b.Parent = block.GetParent()
+		}
+	}
+	b.Directives = directives
+	return b
+}
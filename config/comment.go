@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comment represents outline comments that do not precede a directive, such as the comments
+// at the end of a block or of a file, or a commented-out section followed by a blank line and
+// the closing brace. It is kept in place among the block directives, GetName returns ""
+type Comment struct {
+	Comment []string
+	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
+	Parent IDirective
+	Line   int
+}
+
+// NewComment creates a standalone comment, each line starting with #
+func NewComment(lines ...string) *Comment {
+	return &Comment{Comment: lines}
+}
+
+// ValidateComment checks that a comment line is read back as a comment once dumped: it must start with #,
+// after spaces, and must not contain a line break
+func ValidateComment(comment string) error {
+	if !strings.HasPrefix(strings.TrimLeft(comment, " \t"), "#") {
+		return fmt.Errorf("comment %q must start with #", comment)
+	}
+	if strings.ContainsAny(comment, "\r\n") {
+		return fmt.Errorf("comment %q must not contain a line break", comment)
+	}
+	return nil
+}
+
+// SetLine sets the line number.
+func (c *Comment) SetLine(line int) {
+	c.Line = line
+}
+
+// GetLine returns the line number.
+func (c *Comment) GetLine() int {
+	return c.Line
+}
+
+// SetParent sets the parent directive.
+func (c *Comment) SetParent(parent IDirective) {
+	c.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (c *Comment) GetParent() IDirective {
+	return c.Parent
+}
+
+// GetName returns an empty name, a comment is not a directive.
+func (c *Comment) GetName() string {
+	return ""
+}
+
+// GetParameters returns no parameters.
+func (c *Comment) GetParameters() []Parameter {
+	return []Parameter{}
+}
+
+// GetBlock returns no block.
+func (c *Comment) GetBlock() IBlock {
+	return nil
+}
+
+// GetComment returns the comment lines.
+func (c *Comment) GetComment() []string {
+	return c.Comment
+}
+
+// SetComment sets the comment lines.
+func (c *Comment) SetComment(comment []string) {
+	c.Comment = comment
+}
+
+// splitTrailingComment splits the comment ending a block from the other directives,
+// so that blocks listing some directives apart, such as their servers, keep it last
+func splitTrailingComment(directives []IDirective) (others, trailing []IDirective) {
+	if n := len(directives); n > 0 {
+		if _, ok := directives[n-1].(*Comment); ok {
+			return directives[:n-1], directives[n-1:]
+		}
+	}
+	return directives, nil
+}
@@ -6,6 +6,8 @@ import "fmt"
 type Config struct {
 	*Block
 	FilePath string
+	// TrailingTrivia is the source after the last directive, kept when parsing with trivia.
+	TrailingTrivia string
 }
 
 // Global wrappers provide extension points for custom directive handling.
@@ -13,6 +15,12 @@ var (
 	BlockWrappers     = map[string]func(*Directive) (IDirective, error){}
 	DirectiveWrappers = map[string]func(*Directive) (IDirective, error){}
 	IncludeWrappers   = map[string]func(*Directive) (IDirective, error){}
+	// SubsystemBlockWrappers are the block wrappers of the stream and mail subsystems, keyed by the
+	// subsystem block then by directive name. Inside such a block they win over BlockWrappers
+	SubsystemBlockWrappers = map[string]map[string]func(*Directive) (IDirective, error){
+		"stream": {},
+		"mail":   {},
+	}
 )
 
 //TODO(tufan): move that part inti dumper package
@@ -57,7 +65,7 @@ func (c *Config) FindUpstreams() []*Upstream {
 	var upstreams []*Upstream
 	directives := c.Block.FindDirectives("upstream")
 	for _, directive := range directives {
-		upstream, ok := directive.(*Upstream)
+		upstream, ok := asUpstream(directive)
 		if !ok {
 			continue
 		}
@@ -67,8 +75,19 @@ func (c *Config) FindUpstreams() []*Upstream {
 	return upstreams
 }
 
+// FindTypes finds the types blocks of the whole config, included files too, in config order
+func (c *Config) FindTypes() []*Types {
+	var types []*Types
+	for _, directive := range c.Block.FindDirectives("types") {
+		if t, ok := directive.(*Types); ok {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
 // UnexpectedUpstreamTypeError indicates a directive named "upstream" that is
-// not represented by *config.Upstream in the AST.
+// not represented by *config.Upstream or *config.StreamUpstream in the AST.
 type UnexpectedUpstreamTypeError struct {
 	Index int
 	Got   any
@@ -79,12 +98,12 @@ func (e *UnexpectedUpstreamTypeError) Error() string {
 }
 
 // FindUpstreamsStrict returns all upstream blocks or an error when a matched
-// "upstream" directive is not typed as *Upstream.
+// "upstream" directive is not typed as *Upstream or *StreamUpstream.
 func (c *Config) FindUpstreamsStrict() ([]*Upstream, error) {
 	var upstreams []*Upstream
 	directives := c.Block.FindDirectives("upstream")
 	for i, directive := range directives {
-		upstream, ok := directive.(*Upstream)
+		upstream, ok := asUpstream(directive)
 		if !ok {
 			return nil, &UnexpectedUpstreamTypeError{
 				Index: i,
@@ -96,10 +115,55 @@ func (c *Config) FindUpstreamsStrict() ([]*Upstream, error) {
 	return upstreams, nil
 }
 
+// asUpstream returns the *Upstream of an upstream directive, that of the http or of the stream subsystem
+func asUpstream(d IDirective) (*Upstream, bool) {
+	switch upstream := d.(type) {
+	case *Upstream:
+		return upstream, true
+	case *StreamUpstream:
+		return upstream.Upstream, true
+	}
+	return nil, false
+}
+
 func init() {
 	BlockWrappers["http"] = func(directive *Directive) (IDirective, error) {
 		return NewHTTP(directive)
 	}
+	// an invalid condition keeps the plain directive, parser.WithArgumentValidation reports it
+	BlockWrappers["if"] = func(directive *Directive) (IDirective, error) {
+		if i, err := NewIf(directive); err == nil {
+			return i, nil
+		}
+		return directive, nil
+	}
+	// a geo block that cannot be read keeps the plain directive, parser.WithArgumentValidation reports it
+	BlockWrappers["geo"] = func(directive *Directive) (IDirective, error) {
+		if g, err := NewGeo(directive); err == nil {
+			return g, nil
+		}
+		return directive, nil
+	}
+	// invalid percentages keep the plain directive, parser.WithArgumentValidation reports them
+	BlockWrappers["split_clients"] = func(directive *Directive) (IDirective, error) {
+		if s, err := NewSplitClients(directive); err == nil {
+			return s, nil
+		}
+		return directive, nil
+	}
+	BlockWrappers["types"] = func(directive *Directive) (IDirective, error) {
+		if t, err := NewTypes(directive); err == nil {
+			return t, nil
+		}
+		return directive, nil
+	}
+	// a map that cannot be read keeps the plain directive, parser.WithArgumentValidation reports it
+	BlockWrappers["map"] = func(directive *Directive) (IDirective, error) {
+		if m, err := NewMap(directive); err == nil {
+			return m, nil
+		}
+		return directive, nil
+	}
 	BlockWrappers["location"] = func(directive *Directive) (IDirective, error) {
 		return NewLocation(directive)
 	}
@@ -112,6 +176,24 @@ func init() {
 	BlockWrappers["upstream"] = func(directive *Directive) (IDirective, error) {
 		return NewUpstream(directive)
 	}
+	BlockWrappers["events"] = func(directive *Directive) (IDirective, error) {
+		return NewEvents(directive)
+	}
+	BlockWrappers["stream"] = func(directive *Directive) (IDirective, error) {
+		return NewStream(directive)
+	}
+	SubsystemBlockWrappers["stream"]["server"] = func(directive *Directive) (IDirective, error) {
+		return NewStreamServer(directive)
+	}
+	SubsystemBlockWrappers["stream"]["upstream"] = func(directive *Directive) (IDirective, error) {
+		return NewStreamUpstream(directive)
+	}
+	BlockWrappers["mail"] = func(directive *Directive) (IDirective, error) {
+		return NewMail(directive)
+	}
+	SubsystemBlockWrappers["mail"]["server"] = func(directive *Directive) (IDirective, error) {
+		return NewMailServer(directive)
+	}
 
 	DirectiveWrappers["server"] = func(directive *Directive) (IDirective, error) {
 		return NewUpstreamServer(directive)
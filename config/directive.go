@@ -1,12 +1,20 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 // Directive represents any nginx directive.
 type Directive struct {
 	Block      IBlock
 	Name       string
-	Parameters []Parameter //TODO: Save parameters with their type
+	Parameters []Parameter
 	Comment    []string
 	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
 	Parent IDirective
 	Line   int
 }
@@ -55,3 +63,84 @@ func (d *Directive) GetBlock() IBlock {
 func (d *Directive) GetComment() []string {
 	return d.Comment
 }
+
+// AsBytes returns the first parameter as an nginx size in bytes, such as client_max_body_size 10m.
+func (d *Directive) AsBytes() (int64, error) {
+	p, err := d.firstParameter()
+	if err != nil {
+		return 0, err
+	}
+	return p.AsBytes()
+}
+
+// SetBytes sets the first parameter to a size in bytes.
+func (d *Directive) SetBytes(n int64) {
+	d.setFirstParameter(func(p *Parameter) { p.SetBytes(n) })
+}
+
+// AsOffset returns the first parameter as an nginx offset in bytes.
+func (d *Directive) AsOffset() (int64, error) {
+	p, err := d.firstParameter()
+	if err != nil {
+		return 0, err
+	}
+	return p.AsOffset()
+}
+
+// SetOffset sets the first parameter to an offset in bytes.
+func (d *Directive) SetOffset(n int64) {
+	d.setFirstParameter(func(p *Parameter) { p.SetOffset(n) })
+}
+
+// AsDuration returns the first parameter as an nginx time with millisecond precision, such as proxy_read_timeout 1h30s.
+func (d *Directive) AsDuration() (time.Duration, error) {
+	p, err := d.firstParameter()
+	if err != nil {
+		return 0, err
+	}
+	return p.AsDuration()
+}
+
+// AsSeconds returns the first parameter as an nginx time with second precision, such as expires 1M.
+func (d *Directive) AsSeconds() (time.Duration, error) {
+	p, err := d.firstParameter()
+	if err != nil {
+		return 0, err
+	}
+	return p.AsSeconds()
+}
+
+// SetDuration sets the first parameter to a time, the other parameters are kept, e.g. the header timeout
+// of keepalive_timeout.
+func (d *Directive) SetDuration(duration time.Duration) {
+	d.setFirstParameter(func(p *Parameter) { p.SetDuration(duration) })
+}
+
+// AsBool returns the first parameter as an nginx flag, such as sendfile on.
+func (d *Directive) AsBool() (bool, error) {
+	p, err := d.firstParameter()
+	if err != nil {
+		return false, err
+	}
+	return p.AsBool()
+}
+
+// SetBool sets the first parameter to on or off.
+func (d *Directive) SetBool(b bool) {
+	d.setFirstParameter(func(p *Parameter) { p.SetBool(b) })
+}
+
+func (d *Directive) firstParameter() (*Parameter, error) {
+	if len(d.Parameters) == 0 {
+		return nil, fmt.Errorf("directive %s has no parameter", d.Name)
+	}
+	return &d.Parameters[0], nil
+}
+
+// setFirstParameter sets the first parameter, adding it when the directive has none
+func (d *Directive) setFirstParameter(set func(p *Parameter)) {
+	if len(d.Parameters) == 0 {
+		d.Parameters = []Parameter{{}}
+	}
+	set(&d.Parameters[0])
+}
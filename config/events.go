@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// DefaultWorkerConnections is the worker_connections of nginx when it is not set
+const DefaultWorkerConnections = 512
+
+// EventMethods are the connection processing methods of the use directive
+var EventMethods = []string{"select", "poll", "kqueue", "epoll", "/dev/poll", "eventport"}
+
+// Events represents the events block of the main context.
+type Events struct {
+	*Directive
+	Parent IDirective
+	Line   int
+}
+
+// SetLine sets the line number.
+func (e *Events) SetLine(line int) {
+	e.Line = line
+}
+
+// GetLine returns the line number.
+func (e *Events) GetLine() int {
+	return e.Line
+}
+
+// SetParent sets the parent directive.
+func (e *Events) SetParent(parent IDirective) {
+	e.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (e *Events) GetParent() IDirective {
+	return e.Parent
+}
+
+// NewEvents initializes an Events from an events directive.
+func NewEvents(directive IDirective) (*Events, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("events directive type error")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("events directive must have a block")
+	}
+	if len(dir.Parameters) != 0 {
+		return nil, errors.New("events directive does not take parameters")
+	}
+	return &Events{Directive: dir}, nil
+}
+
+// WorkerConnections returns the maximum number of connections of a worker, DefaultWorkerConnections when it is not set.
+func (e *Events) WorkerConnections() (int, error) {
+	parameters, _ := directiveParameters(e.Block, "worker_connections")
+	if len(parameters) == 0 {
+		return DefaultWorkerConnections, nil
+	}
+	n, err := strconv.Atoi(parameters[0].Unquoted())
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid worker_connections %s", parameters[0].Value)
+	}
+	return n, nil
+}
+
+// SetWorkerConnections sets worker_connections, adding it when missing.
+func (e *Events) SetWorkerConnections(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid worker_connections %d", n)
+	}
+	e.Block = setDirective(e.Block, e, "worker_connections", Parameter{Value: strconv.Itoa(n)})
+	return nil
+}
+
+// Use returns the connection processing method, empty when nginx picks the most efficient one.
+func (e *Events) Use() string {
+	if parameters, _ := directiveParameters(e.Block, "use"); len(parameters) > 0 {
+		return parameters[0].Unquoted()
+	}
+	return ""
+}
+
+// SetUse sets the connection processing method, one of EventMethods, adding use when missing.
+// An empty method removes use
+func (e *Events) SetUse(method string) error {
+	if method == "" {
+		e.Block = replaceDirectives(e.Block, e, "use", nil)
+		return nil
+	}
+	for _, m := range EventMethods {
+		if m == method {
+			e.Block = setDirective(e.Block, e, "use", Parameter{Value: method})
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid event type \"%s\"", method)
+}
+
+// MultiAccept reports whether a worker accepts all new connections at a time, off by default.
+func (e *Events) MultiAccept() bool {
+	parameters, _ := directiveParameters(e.Block, "multi_accept")
+	if len(parameters) == 0 {
+		return false
+	}
+	on, err := parameters[0].AsBool()
+	return err == nil && on
+}
+
+// SetMultiAccept sets multi_accept, adding it when missing.
+func (e *Events) SetMultiAccept(on bool) {
+	e.Block = setFlag(e.Block, e, "multi_accept", on)
+}
+
+// FindDirectives finds directives by name.
+func (e *Events) FindDirectives(directiveName string) []IDirective {
+	return e.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the events block.
+func (e *Events) GetDirectives() []IDirective {
+	return e.GetBlock().GetDirectives()
+}
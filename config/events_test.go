@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	_, err := NewEvents(&Directive{Name: "events"})
+	assert.Error(t, err, "events directive must have a block")
+
+	events, err := NewEvents(&Directive{Name: "events", Block: &Block{Directives: []IDirective{
+		&Directive{Name: "accept_mutex", Parameters: []Parameter{{Value: "on"}}},
+		&Directive{Name: "use", Parameters: []Parameter{{Value: "epoll"}}},
+	}}})
+	assert.NilError(t, err)
+
+	connections, err := events.WorkerConnections()
+	assert.NilError(t, err)
+	assert.Equal(t, connections, DefaultWorkerConnections)
+	assert.Equal(t, events.Use(), "epoll")
+	assert.Assert(t, !events.MultiAccept())
+
+	assert.NilError(t, events.SetWorkerConnections(4096))
+	assert.Error(t, events.SetWorkerConnections(0), "invalid worker_connections 0")
+	assert.Error(t, events.SetUse("iocp"), "invalid event type \"iocp\"")
+	assert.NilError(t, events.SetUse("kqueue"))
+	events.SetMultiAccept(true)
+
+	connections, err = events.WorkerConnections()
+	assert.NilError(t, err)
+	assert.Equal(t, connections, 4096)
+	assert.Equal(t, events.Use(), "kqueue")
+	assert.Assert(t, events.MultiAccept())
+	directives := events.GetDirectives()
+	assert.Equal(t, directives[0].GetName(), "accept_mutex")
+	assert.Equal(t, directives[1].GetName(), "use")
+	assert.Equal(t, directives[3].GetName(), "multi_accept")
+	assert.Equal(t, directives[3].GetParent(), IDirective(events))
+
+	assert.NilError(t, events.SetUse(""))
+	assert.Equal(t, events.Use(), "")
+	assert.Equal(t, len(events.FindDirectives("use")), 0)
+}
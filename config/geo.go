@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// GeoEntry is a network of a geo block with its value, or a network it deletes
+type GeoEntry struct {
+	Network string // as written: an address, a CIDR network or, in ranges mode, a range such as 10.0.0.1-10.0.0.9
+	Value   string // empty for deleted networks
+	Delete  bool
+
+	Prefix     netip.Prefix // the network as written, a single address being a /32 or /128; unset in ranges mode
+	Start, End netip.Addr   // the first and last addresses of a range
+}
+
+// Geo represents a geo block, which sets a variable from the client address
+type Geo struct {
+	*Directive
+	Address        string // the variable holding the address, empty for the client address
+	Variable       string // the result variable, e.g. $country
+	Default        string // the result when no network matches, empty by default
+	Ranges         bool
+	Proxies        []netip.Prefix // the trusted proxies, whose X-Forwarded-For header gives the address
+	ProxyRecursive bool
+	Includes       []IDirective // the include directives of the block, their parsed entries are part of Entries
+	Entries        []GeoEntry   // the entries in config order
+	Parent         IDirective
+	Line           int
+}
+
+// SetLine sets the line number.
+func (g *Geo) SetLine(line int) {
+	g.Line = line
+}
+
+// GetLine returns the line number.
+func (g *Geo) GetLine() int {
+	return g.Line
+}
+
+// SetParent sets the parent directive.
+func (g *Geo) SetParent(parent IDirective) {
+	g.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (g *Geo) GetParent() IDirective {
+	return g.Parent
+}
+
+// NewGeo initializes a Geo from a geo directive, reading its entries and those of the files it includes.
+// Invalid addresses, networks and ranges return an error
+func NewGeo(directive IDirective) (*Geo, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("geo directive type error")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("geo directive must have a block")
+	}
+	g := &Geo{
+		Directive: dir,
+		Proxies:   []netip.Prefix{},
+		Includes:  []IDirective{},
+		Entries:   []GeoEntry{},
+	}
+	switch len(dir.Parameters) {
+	case 1:
+		g.Variable = dir.Parameters[0].Unquoted()
+	case 2:
+		g.Address = dir.Parameters[0].Unquoted()
+		g.Variable = dir.Parameters[1].Unquoted()
+	default:
+		return nil, errors.New("geo directive requires a variable, optionally preceded by an address")
+	}
+	if err := g.read(dir.Block.GetDirectives()); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// read adds the entries and the parameters of directives to the geo block
+func (g *Geo) read(directives []IDirective) error {
+	for _, d := range directives {
+		name := (&Parameter{Value: d.GetName()}).Unquoted()
+		params := d.GetParameters()
+		switch {
+		case name == "": // comments
+			continue
+		case (name == "ranges" || name == "proxy_recursive") && len(params) == 0:
+			g.Ranges = g.Ranges || name == "ranges"
+			g.ProxyRecursive = g.ProxyRecursive || name == "proxy_recursive"
+			continue
+		case name == "include" && len(params) == 1:
+			g.Includes = append(g.Includes, d)
+			if include, ok := d.(*Include); ok {
+				for _, c := range include.Configs {
+					if err := g.read(c.GetDirectives()); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		case len(params) != 1:
+			return fmt.Errorf("invalid number of the geo parameters for %s", name)
+		}
+
+		value := params[0].Unquoted()
+		switch name {
+		case "default":
+			g.Default = value
+		case "proxy":
+			proxy, err := parsePrefix(value)
+			if err != nil {
+				return err
+			}
+			g.Proxies = append(g.Proxies, proxy.Masked())
+		case "delete":
+			entry, err := g.entry(value)
+			if err != nil {
+				return err
+			}
+			entry.Delete = true
+			g.Entries = append(g.Entries, entry)
+		default:
+			entry, err := g.entry(name)
+			if err != nil {
+				return err
+			}
+			entry.Value = value
+			g.Entries = append(g.Entries, entry)
+		}
+	}
+	return nil
+}
+
+// entry parses a network as written in the geo block
+func (g *Geo) entry(network string) (GeoEntry, error) {
+	entry := GeoEntry{Network: network}
+	if !g.Ranges {
+		prefix, err := parsePrefix(network)
+		if err != nil {
+			return entry, err
+		}
+		entry.Prefix = prefix
+		return entry, nil
+	}
+
+	first, last, ok := strings.Cut(network, "-")
+	start, err := netip.ParseAddr(first)
+	if err != nil || !ok || !start.Is4() {
+		return entry, fmt.Errorf("invalid range %s", network)
+	}
+	end, err := netip.ParseAddr(last)
+	if err != nil || !end.Is4() || end.Less(start) {
+		return entry, fmt.Errorf("invalid range %s", network)
+	}
+	entry.Start, entry.End = start, end
+	return entry, nil
+}
+
+// parsePrefix parses a CIDR network or a single address, keeping the low address bits
+func parsePrefix(network string) (netip.Prefix, error) {
+	if strings.Contains(network, "/") {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid network %s", network)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix, nil
+	}
+	addr, err := netip.ParseAddr(network)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %s", network)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Validate reports what nginx warns about: networks whose low address bits are set, networks
+// defined twice, deleted networks that are not defined, and overlapping ranges
+func (g *Geo) Validate() error {
+	var errs []error
+	if !g.Ranges {
+		defined := make(map[netip.Prefix]string)
+		for _, entry := range g.Entries {
+			prefix := entry.Prefix.Masked()
+			if prefix != entry.Prefix {
+				errs = append(errs, fmt.Errorf("low address bits of %s are meaningless", entry.Network))
+			}
+			previous, ok := defined[prefix]
+			switch {
+			case entry.Delete && !ok:
+				errs = append(errs, fmt.Errorf("no network %s to delete", entry.Network))
+			case entry.Delete:
+				delete(defined, prefix)
+			case ok:
+				errs = append(errs, fmt.Errorf("duplicate network %s, value: %s, old value: %s", entry.Network, entry.Value, previous))
+				fallthrough
+			default:
+				defined[prefix] = entry.Value
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	for i, entry := range g.Entries {
+		if entry.Delete {
+			continue
+		}
+		for _, previous := range g.Entries[:i] {
+			if !previous.Delete && !entry.End.Less(previous.Start) && !previous.End.Less(entry.Start) {
+				errs = append(errs, fmt.Errorf("range %s overlaps %s", entry.Network, previous.Network))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Lookup returns the value of the geo block for the address ip, the client address or the value of
+// the Address variable. When ip is a trusted proxy, as nginx does whatever the variable, the address is
+// taken from the X-Forwarded-For header, its last address or, with proxy_recursive, the last one that
+// is not a trusted proxy. The most specific network
+// matches, in ranges mode the last range holding the address. Without a match, or for an invalid
+// address, it returns the default value and false
+func (g *Geo) Lookup(ip, xForwardedFor string) (value string, matched bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return g.Default, false
+	}
+	addr = addr.Unmap()
+	if xForwardedFor != "" {
+		addr = g.forwardedAddr(addr, xForwardedFor)
+	}
+
+	if g.Ranges {
+		for i := len(g.Entries) - 1; i >= 0; i-- {
+			entry := g.Entries[i]
+			if !addr.Less(entry.Start) && !entry.End.Less(addr) {
+				if entry.Delete {
+					break
+				}
+				return entry.Value, true
+			}
+		}
+		return g.Default, false
+	}
+
+	networks := make(map[netip.Prefix]string)
+	for _, entry := range g.Entries {
+		if entry.Delete {
+			delete(networks, entry.Prefix.Masked())
+		} else {
+			networks[entry.Prefix.Masked()] = entry.Value
+		}
+	}
+	best := -1
+	for prefix, v := range networks {
+		if prefix.Contains(addr) && prefix.Bits() > best {
+			best, value = prefix.Bits(), v
+		}
+	}
+	if best < 0 {
+		return g.Default, false
+	}
+	return value, true
+}
+
+// forwardedAddr returns the client address behind the trusted proxies
+func (g *Geo) forwardedAddr(addr netip.Addr, xForwardedFor string) netip.Addr {
+	if !g.isProxy(addr) {
+		return addr
+	}
+	forwarded := strings.Split(xForwardedFor, ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		next, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		addr = next.Unmap()
+		if !g.ProxyRecursive || !g.isProxy(addr) {
+			break
+		}
+	}
+	return addr
+}
+
+func (g *Geo) isProxy(addr netip.Addr) bool {
+	for _, proxy := range g.Proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindDirectives finds directives by name.
+func (g *Geo) FindDirectives(directiveName string) []IDirective {
+	return g.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the geo block.
+func (g *Geo) GetDirectives() []IDirective {
+	return g.GetBlock().GetDirectives()
+}
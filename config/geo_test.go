@@ -0,0 +1,153 @@
+package config
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func geoDirective(parameters []string, entries ...string) *Directive {
+	block := &Block{}
+	for _, e := range entries {
+		d := &Directive{}
+		for i, field := range strings.Fields(e) {
+			if i == 0 {
+				d.Name = field
+				continue
+			}
+			d.Parameters = append(d.Parameters, Parameter{Value: field})
+		}
+		block.Directives = append(block.Directives, d)
+	}
+	d := &Directive{Name: "geo", Block: block}
+	for _, p := range parameters {
+		d.Parameters = append(d.Parameters, Parameter{Value: p})
+	}
+	return d
+}
+
+func TestNewGeo(t *testing.T) {
+	t.Parallel()
+	g, err := NewGeo(geoDirective([]string{"$country"},
+		"default ZZ",
+		"proxy 192.168.0.0/16",
+		"proxy 10.0.0.1",
+		"proxy_recursive",
+		"127.0.0.1 LOCAL",
+		"10.0.0.0/8 PRIVATE",
+		"2001:db8::/32 DOC",
+		"delete 10.1.0.0/16",
+	))
+	assert.NilError(t, err)
+	assert.Equal(t, g.Address, "")
+	assert.Equal(t, g.Variable, "$country")
+	assert.Equal(t, g.Default, "ZZ")
+	assert.Assert(t, g.ProxyRecursive)
+	assert.Assert(t, !g.Ranges)
+	assert.Assert(t, slices.Equal(g.Proxies, []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16"), netip.MustParsePrefix("10.0.0.1/32")}))
+	assert.Equal(t, len(g.Entries), 4)
+	assert.Equal(t, g.Entries[0].Prefix, netip.MustParsePrefix("127.0.0.1/32"))
+	assert.Assert(t, g.Entries[3].Delete)
+
+	g, err = NewGeo(geoDirective([]string{"$arg_ip", "$country"}, "ranges", "10.0.0.0-10.0.0.255 A"))
+	assert.NilError(t, err)
+	assert.Equal(t, g.Address, "$arg_ip")
+	assert.Assert(t, g.Ranges)
+	assert.Equal(t, g.Entries[0].End, netip.MustParseAddr("10.0.0.255"))
+}
+
+func TestNewGeo_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		entry   string
+		wantErr string
+	}{
+		{"10.0.0.0/33 A", "invalid network 10.0.0.0/33"},
+		{"10.0.0.256 A", "invalid address 10.0.0.256"},
+		{"proxy nowhere", "invalid address nowhere"},
+		{"10.0.0.0/8", "invalid number of the geo parameters for 10.0.0.0/8"},
+	}
+	for _, tt := range tests {
+		_, err := NewGeo(geoDirective([]string{"$a"}, tt.entry))
+		assert.Error(t, err, tt.wantErr)
+	}
+	_, err := NewGeo(geoDirective([]string{"$a"}, "ranges", "10.0.0.9-10.0.0.1 A"))
+	assert.Error(t, err, "invalid range 10.0.0.9-10.0.0.1")
+	_, err = NewGeo(geoDirective([]string{"$a"}, "ranges", "10.0.0.0/8 A"))
+	assert.Error(t, err, "invalid range 10.0.0.0/8")
+}
+
+func TestGeo_Validate(t *testing.T) {
+	t.Parallel()
+	g, err := NewGeo(geoDirective([]string{"$a"}, "10.0.0.0/8 A", "10.0.0.0/16 B", "10.0.0.1/8 C", "delete 172.16.0.0/12"))
+	assert.NilError(t, err)
+	assert.Error(t, g.Validate(), "low address bits of 10.0.0.1/8 are meaningless\n"+
+		"duplicate network 10.0.0.1/8, value: C, old value: A\n"+
+		"no network 172.16.0.0/12 to delete")
+
+	g, err = NewGeo(geoDirective([]string{"$a"}, "ranges", "10.0.0.0-10.0.0.9 A", "10.0.0.10-10.0.0.19 B", "10.0.0.5-10.0.0.12 C"))
+	assert.NilError(t, err)
+	assert.Error(t, g.Validate(), "range 10.0.0.5-10.0.0.12 overlaps 10.0.0.0-10.0.0.9\n"+
+		"range 10.0.0.5-10.0.0.12 overlaps 10.0.0.10-10.0.0.19")
+
+	g, err = NewGeo(geoDirective([]string{"$a"}, "10.0.0.0/8 A", "10.0.0.0/16 B"))
+	assert.NilError(t, err)
+	assert.NilError(t, g.Validate())
+}
+
+func TestGeo_Lookup(t *testing.T) {
+	t.Parallel()
+	g, err := NewGeo(geoDirective([]string{"$country"},
+		"default ZZ",
+		"proxy 192.168.0.0/16",
+		"10.0.0.0/8 PRIVATE",
+		"10.1.0.0/16 OFFICE",
+		"10.1.2.0/24 LAB",
+		"delete 10.1.0.0/16",
+		"2001:db8::/32 DOC",
+	))
+	assert.NilError(t, err)
+
+	tests := []struct {
+		ip, xForwardedFor string
+		value             string
+		matched           bool
+	}{
+		{"10.9.9.9", "", "PRIVATE", true},
+		{"10.1.2.3", "", "LAB", true},
+		{"10.1.9.9", "", "PRIVATE", true},
+		{"::ffff:10.1.2.3", "", "LAB", true},
+		{"2001:db8::1", "", "DOC", true},
+		{"8.8.8.8", "", "ZZ", false},
+		{"not an ip", "", "ZZ", false},
+		// a trusted proxy is replaced by the last forwarded address
+		{"192.168.1.1", "10.1.2.3, 192.168.1.2", "ZZ", false},
+		{"192.168.1.1", "192.168.1.2, 10.1.2.3", "LAB", true},
+		// an untrusted address ignores the header
+		{"8.8.8.8", "10.1.2.3", "ZZ", false},
+	}
+	for _, tt := range tests {
+		value, matched := g.Lookup(tt.ip, tt.xForwardedFor)
+		assert.Equal(t, value, tt.value, tt.ip)
+		assert.Equal(t, matched, tt.matched, tt.ip)
+	}
+
+	// proxy_recursive skips the trusted proxies of the header
+	g.ProxyRecursive = true
+	value, _ := g.Lookup("192.168.1.1", "10.1.2.3, 192.168.1.2")
+	assert.Equal(t, value, "LAB")
+	// the header is read for the address of a variable too
+	g.Address = "$arg_ip"
+	value, _ = g.Lookup("192.168.1.1", "10.1.2.3")
+	assert.Equal(t, value, "LAB")
+
+	g, err = NewGeo(geoDirective([]string{"$a"}, "ranges", "default -", "10.0.0.0-10.0.0.255 A", "10.0.0.100-10.0.0.199 B", "delete 10.0.0.150-10.0.0.159"))
+	assert.NilError(t, err)
+	for ip, want := range map[string]string{"10.0.0.1": "A", "10.0.0.120": "B", "10.0.0.155": "-", "10.0.0.250": "A", "10.0.1.0": "-"} {
+		value, _ := g.Lookup(ip, "")
+		assert.Equal(t, value, want, ip)
+	}
+}
@@ -10,6 +10,10 @@ type HTTP struct {
 	Directives []IDirective
 	Comment    []string
 	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
+	DefaultBraces
 	Parent IDirective
 	Line   int
 }
@@ -61,6 +65,11 @@ func NewHTTP(directive IDirective) (*HTTP, error) {
 		}
 		http.Comment = directive.GetComment()
 		http.InlineComment = directive.GetInlineComment()
+		http.Span = directive.GetSpan()
+		http.CommentSpans = directive.GetCommentSpans()
+		http.Trivia = directive.GetTrivia()
+		http.Annotations, http.AnnotationSyntax = directive.GetAnnotations(), directive.GetAnnotationSyntax()
+		http.LBrace, http.RBrace = block.GetBraces()
 
 		return http, nil
 	}
@@ -80,11 +89,26 @@ func (h *HTTP) GetParameters() []Parameter {
 // GetDirectives returns all directives in the http block.
 func (h *HTTP) GetDirectives() []IDirective {
 	directives := make([]IDirective, 0)
-	directives = append(directives, h.Directives...)
+	others, trailing := splitTrailingComment(h.Directives)
+	directives = append(directives, others...)
 	for _, directive := range h.Servers {
 		directives = append(directives, directive)
 	}
-	return directives
+	return append(directives, trailing...)
+}
+
+// SetDirectives replaces the directives of the http block, the servers going to Servers.
+func (h *HTTP) SetDirectives(directives []IDirective) {
+	h.Servers = []*Server{}
+	h.Directives = []IDirective{}
+	for _, directive := range directives {
+		if server, ok := directive.(*Server); ok {
+			server.Parent = h
+			h.Servers = append(h.Servers, server)
+			continue
+		}
+		h.Directives = append(h.Directives, directive)
+	}
 }
 
 // FindDirectives finds directives in the http block.
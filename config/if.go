@@ -0,0 +1,330 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ConditionOperator is the operator of an if condition
+type ConditionOperator string
+
+// The operators of if conditions. A condition without operator tests
+// whether its variable is neither empty nor "0"
+const (
+	OperatorNone                ConditionOperator = ""
+	OperatorEqual               ConditionOperator = "="
+	OperatorNotEqual            ConditionOperator = "!="
+	OperatorMatch               ConditionOperator = "~"
+	OperatorMatchInsensitive    ConditionOperator = "~*"
+	OperatorNotMatch            ConditionOperator = "!~"
+	OperatorNotMatchInsensitive ConditionOperator = "!~*"
+	OperatorFile                ConditionOperator = "-f"
+	OperatorNotFile             ConditionOperator = "!-f"
+	OperatorDirectory           ConditionOperator = "-d"
+	OperatorNotDirectory        ConditionOperator = "!-d"
+	OperatorExists              ConditionOperator = "-e"
+	OperatorNotExists           ConditionOperator = "!-e"
+	OperatorExecutable          ConditionOperator = "-x"
+	OperatorNotExecutable       ConditionOperator = "!-x"
+)
+
+// IsFileTest reports whether the operator checks a file rather than a variable
+func (o ConditionOperator) IsFileTest() bool {
+	return strings.HasPrefix(string(o), "-") || strings.HasPrefix(string(o), "!-")
+}
+
+// IsRegex reports whether the operand of the operator is a regular expression
+func (o ConditionOperator) IsRegex() bool {
+	return strings.Contains(string(o), "~")
+}
+
+// Negated reports whether the operator is the negation of another one, e.g. != or !-f
+func (o ConditionOperator) Negated() bool {
+	return strings.HasPrefix(string(o), "!")
+}
+
+func (o ConditionOperator) valid() bool {
+	switch o {
+	case OperatorEqual, OperatorNotEqual, OperatorMatch, OperatorMatchInsensitive, OperatorNotMatch, OperatorNotMatchInsensitive,
+		OperatorFile, OperatorNotFile, OperatorDirectory, OperatorNotDirectory, OperatorExists, OperatorNotExists, OperatorExecutable, OperatorNotExecutable:
+		return true
+	}
+	return false
+}
+
+// Condition is the parsed condition of an if block, as nginx reads it:
+// ($variable), ($variable operator operand) or (file_operator operand)
+type Condition struct {
+	Variable string            // the tested or compared variable with its $, e.g. $http_x; empty for file tests
+	Operator ConditionOperator // OperatorNone when the variable is tested alone
+	Operand  Parameter         // the compared value, the regex or the path of file tests
+}
+
+// ParseCondition parses the parameters of an if directive, the parentheses
+// being separate parameters or part of the first and last ones
+func ParseCondition(parameters []Parameter) (Condition, error) {
+	args := append([]Parameter{}, parameters...)
+	if len(args) == 0 || !strings.HasPrefix(args[0].Value, "(") {
+		return Condition{}, errors.New("invalid if condition: missing (")
+	}
+	if args[0].Value == "(" {
+		args = args[1:]
+	} else {
+		args[0].Value = args[0].Value[1:]
+	}
+	last := len(args) - 1
+	if last < 0 || !strings.HasSuffix(args[last].Value, ")") {
+		return Condition{}, errors.New("invalid if condition: missing )")
+	}
+	if args[last].Value == ")" {
+		args = args[:last]
+	} else {
+		args[last].Value = args[last].Value[:len(args[last].Value)-1]
+	}
+	if len(args) == 0 {
+		return Condition{}, errors.New("invalid if condition: empty condition")
+	}
+
+	first := args[0].Value
+	switch {
+	case len(first) > 1 && first[0] == '$':
+		if !args[0].IsVariable() {
+			return Condition{}, fmt.Errorf("invalid if condition: invalid variable %s", first)
+		}
+		switch len(args) {
+		case 1:
+			return Condition{Variable: first}, nil
+		case 3:
+			operator := ConditionOperator(args[1].Value)
+			if !operator.valid() || operator.IsFileTest() {
+				return Condition{}, fmt.Errorf("invalid if condition: unexpected %s", args[1].Value)
+			}
+			operand := args[2]
+			operand.Regex = operator.IsRegex()
+			return Condition{Variable: first, Operator: operator, Operand: operand}, nil
+		}
+	case strings.HasPrefix(first, "-") || strings.HasPrefix(first, "!-"):
+		operator := ConditionOperator(first)
+		if !operator.valid() {
+			return Condition{}, fmt.Errorf("invalid if condition: unexpected %s", first)
+		}
+		if len(args) == 2 {
+			return Condition{Operator: operator, Operand: args[1]}, nil
+		}
+	}
+	return Condition{}, errors.New("invalid if condition")
+}
+
+// Parameters returns the parameters of an if directive with the condition
+func (c Condition) Parameters() []Parameter {
+	if c.Variable == "" && c.Operator == OperatorNone {
+		return nil
+	}
+	parameters := make([]Parameter, 0, 4)
+	if c.Variable != "" {
+		parameters = append(parameters, Parameter{Value: c.Variable})
+	}
+	if c.Operator != OperatorNone {
+		parameters = append(parameters, Parameter{Value: string(c.Operator)}, c.Operand)
+	}
+	parameters[0].Value = "(" + parameters[0].Value
+	// nginx reads ) after a quoted string as a separate parameter
+	if last := &parameters[len(parameters)-1]; last.Quote == QuoteNone {
+		last.Value += ")"
+	} else {
+		parameters = append(parameters, Parameter{Value: ")"})
+	}
+	for i := range parameters {
+		parameters[i].RelativeLineIndex = 0
+	}
+	return parameters
+}
+
+// String returns the condition as written in an if directive
+func (c Condition) String() string {
+	values := make([]string, 0, 4)
+	for _, p := range c.Parameters() {
+		values = append(values, p.Value)
+	}
+	return strings.Join(values, " ")
+}
+
+// Eval evaluates the condition with the values of vars, keyed by variable name without $, and
+// the files of fsys. Variables missing from vars are empty, as nginx reads headers and arguments
+// that are not sent. Regular expressions are read with the syntax of Go, which lacks some PCRE features
+func (c Condition) Eval(vars map[string]string, fsys fs.FS) (bool, error) {
+	if c.Operator.IsFileTest() {
+		return c.evalFile(vars, fsys)
+	}
+
+	value := vars[strings.Trim(strings.TrimPrefix(c.Variable, "$"), "{}")]
+	switch c.Operator {
+	case OperatorNone:
+		return value != "" && value != "0", nil
+	case OperatorEqual, OperatorNotEqual:
+		operand, err := expandVariables(c.Operand, vars)
+		if err != nil {
+			return false, err
+		}
+		return (value == operand) != c.Operator.Negated(), nil
+	case OperatorMatch, OperatorMatchInsensitive, OperatorNotMatch, OperatorNotMatchInsensitive:
+		expr := c.Operand.Unquoted()
+		if strings.HasSuffix(string(c.Operator), "*") {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return false, fmt.Errorf("if condition regex %s: %w", c.Operand.Value, err)
+		}
+		return re.MatchString(value) != c.Operator.Negated(), nil
+	}
+	return false, fmt.Errorf("unknown if condition operator %s", c.Operator)
+}
+
+func (c Condition) evalFile(vars map[string]string, fsys fs.FS) (bool, error) {
+	if fsys == nil {
+		return false, fmt.Errorf("%s needs a file system", c.Operator)
+	}
+	name, err := expandVariables(c.Operand, vars)
+	if err != nil {
+		return false, err
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	exists := err == nil
+	var result bool
+	switch strings.TrimPrefix(string(c.Operator), "!") {
+	case "-f":
+		result = exists && info.Mode().IsRegular()
+	case "-d":
+		result = exists && info.IsDir()
+	case "-e":
+		result = exists
+	case "-x":
+		result = exists && info.Mode()&0o111 != 0
+	}
+	return result != c.Operator.Negated(), nil
+}
+
+// expandVariables returns the value of p with its variables replaced by their values in vars
+func expandVariables(p Parameter, vars map[string]string) (string, error) {
+	v := p.Unquoted()
+	var buf strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '$' || i+1 >= len(v) {
+			buf.WriteByte(v[i])
+			continue
+		}
+		rest := v[i+1:]
+		name := ""
+		switch {
+		case rest[0] >= '1' && rest[0] <= '9':
+			name = rest[:1]
+			i++
+		case rest[0] == '{':
+			end := strings.IndexByte(rest, '}')
+			if end < 2 || !isVariableName(rest[1:end]) {
+				return "", fmt.Errorf("invalid variable in %s", p.Value)
+			}
+			name = rest[1:end]
+			i += end + 1
+		default:
+			n := 0
+			for n < len(rest) && isVariableChar(rest[n]) {
+				n++
+			}
+			if n == 0 {
+				buf.WriteByte('$')
+				continue
+			}
+			name = rest[:n]
+			i += n
+		}
+		buf.WriteString(vars[name])
+	}
+	return buf.String(), nil
+}
+
+// If represents an if block with its parsed condition.
+type If struct {
+	*Directive
+	Condition Condition
+	Parent    IDirective
+	Line      int
+}
+
+// SetLine sets the line number.
+func (i *If) SetLine(line int) {
+	i.Line = line
+}
+
+// GetLine returns the line number.
+func (i *If) GetLine() int {
+	return i.Line
+}
+
+// SetParent sets the parent directive.
+func (i *If) SetParent(parent IDirective) {
+	i.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (i *If) GetParent() IDirective {
+	return i.Parent
+}
+
+// SetCondition sets the condition and rewrites the parameters of the directive with it.
+func (i *If) SetCondition(condition Condition) {
+	i.Condition = condition
+	i.Parameters = condition.Parameters()
+}
+
+// Eval evaluates the condition of the if block, see Condition.Eval.
+func (i *If) Eval(vars map[string]string, fsys fs.FS) (bool, error) {
+	return i.Condition.Eval(vars, fsys)
+}
+
+// NewIf initializes an If from a directive, parsing its condition.
+func NewIf(directive IDirective) (*If, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("if directive type error")
+	}
+	condition, err := ParseCondition(dir.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	return &If{
+		Directive: dir,
+		Condition: condition,
+	}, nil
+}
+
+// FindDirectives finds directives by name.
+func (i *If) FindDirectives(directiveName string) []IDirective {
+	block := i.GetBlock()
+	if block == nil {
+		return []IDirective{}
+	}
+	return block.FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the if block.
+func (i *If) GetDirectives() []IDirective {
+	block := i.GetBlock()
+	if block == nil {
+		return []IDirective{}
+	}
+	return block.GetDirectives()
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"gotest.tools/v3/assert"
+)
+
+func params(values ...string) []Parameter {
+	parameters := make([]Parameter, 0, len(values))
+	for _, v := range values {
+		parameters = append(parameters, Parameter{Value: v, Quote: QuoteOf(v)})
+	}
+	return parameters
+}
+
+func TestParseCondition(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		parameters []Parameter
+		want       Condition
+		wantErr    string
+	}{
+		{"variable", params("($slow)"), Condition{Variable: "$slow"}, ""},
+		{"separate parentheses", params("(", "$a", "!=", "''", ")"), Condition{Variable: "$a", Operator: OperatorNotEqual, Operand: Parameter{Value: "''", Quote: QuoteSingle}}, ""},
+		{"quoted value", params("($http_x", "=", `"a b"`, ")"), Condition{Variable: "$http_x", Operator: OperatorEqual, Operand: Parameter{Value: `"a b"`, Quote: QuoteDouble}}, ""},
+		{"regex", params("($request_uri", "~*", `\.(gif|jpg)$)`), Condition{Variable: "$request_uri", Operator: OperatorMatchInsensitive, Operand: Parameter{Value: `\.(gif|jpg)$`, Regex: true}}, ""},
+		{"negated file test", params("(!-f", "$request_filename)"), Condition{Operator: OperatorNotFile, Operand: Parameter{Value: "$request_filename"}}, ""},
+		{"missing (", params("$a"), Condition{}, "invalid if condition: missing ("},
+		{"missing )", params("($a", "=", "b)#x"), Condition{}, "invalid if condition: missing )"},
+		{"empty", params("()"), Condition{}, "invalid if condition: empty condition"},
+		{"unknown operator", params("($a", "<", "b)"), Condition{}, "invalid if condition: unexpected <"},
+		{"file test on a variable", params("($a", "-f", "b)"), Condition{}, "invalid if condition: unexpected -f"},
+		{"unknown file test", params("(-z", "/a)"), Condition{}, "invalid if condition: unexpected -z"},
+		{"too many arguments", params("($a", "=", "b", "c)"), Condition{}, "invalid if condition"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseCondition(tt.parameters)
+			if tt.wantErr != "" {
+				assert.Error(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestCondition_String(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, Condition{Variable: "$slow"}.String(), "($slow)")
+	assert.Equal(t, Condition{Variable: "$a", Operator: OperatorEqual, Operand: NewParameter("a b")}.String(), `($a = "a b" )`)
+	assert.Equal(t, Condition{Operator: OperatorDirectory, Operand: NewParameter("/srv")}.String(), "(-d /srv)")
+	assert.Equal(t, Condition{}.String(), "")
+}
+
+func TestCondition_Eval(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"srv/index.html": {Data: []byte("hi")},
+		"srv/run.sh":     {Data: []byte("#!/bin/sh"), Mode: 0o755},
+	}
+	vars := map[string]string{"slow": "1", "off": "0", "http_x": "a b", "uri": "/img/A.GIF", "root": "/srv"}
+	tests := []struct {
+		condition Condition
+		want      bool
+	}{
+		{Condition{Variable: "$slow"}, true},
+		{Condition{Variable: "$off"}, false},
+		{Condition{Variable: "$unset"}, false},
+		{Condition{Variable: "${slow}"}, true},
+		{Condition{Variable: "$http_x", Operator: OperatorEqual, Operand: Parameter{Value: `"a b"`}}, true},
+		{Condition{Variable: "$http_x", Operator: OperatorNotEqual, Operand: Parameter{Value: `"a b"`}}, false},
+		{Condition{Variable: "$unset", Operator: OperatorEqual, Operand: Parameter{Value: `""`}}, true},
+		{Condition{Variable: "$uri", Operator: OperatorMatch, Operand: Parameter{Value: `\.gif$`}}, false},
+		{Condition{Variable: "$uri", Operator: OperatorMatchInsensitive, Operand: Parameter{Value: `\.gif$`}}, true},
+		{Condition{Variable: "$uri", Operator: OperatorNotMatch, Operand: Parameter{Value: `^/img/`}}, false},
+		{Condition{Variable: "$uri", Operator: OperatorNotMatchInsensitive, Operand: Parameter{Value: `^/IMG/`}}, false},
+		{Condition{Operator: OperatorFile, Operand: Parameter{Value: "$root/index.html"}}, true},
+		{Condition{Operator: OperatorFile, Operand: Parameter{Value: "$root"}}, false},
+		{Condition{Operator: OperatorNotFile, Operand: Parameter{Value: "${root}/missing"}}, true},
+		{Condition{Operator: OperatorDirectory, Operand: Parameter{Value: "/srv/"}}, true},
+		{Condition{Operator: OperatorNotDirectory, Operand: Parameter{Value: "/srv"}}, false},
+		{Condition{Operator: OperatorExists, Operand: Parameter{Value: "/srv/run.sh"}}, true},
+		{Condition{Operator: OperatorNotExists, Operand: Parameter{Value: "/srv/run.sh"}}, false},
+		{Condition{Operator: OperatorExecutable, Operand: Parameter{Value: "/srv/run.sh"}}, true},
+		{Condition{Operator: OperatorExecutable, Operand: Parameter{Value: "/srv/index.html"}}, false},
+		{Condition{Operator: OperatorNotExecutable, Operand: Parameter{Value: "/srv/missing"}}, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.condition.Eval(vars, fsys)
+		assert.NilError(t, err, tt.condition.String())
+		assert.Equal(t, got, tt.want, tt.condition.String())
+	}
+
+	_, err := Condition{Operator: OperatorFile, Operand: Parameter{Value: "/a"}}.Eval(vars, nil)
+	assert.Error(t, err, "-f needs a file system")
+	_, err = Condition{Variable: "$uri", Operator: OperatorMatch, Operand: Parameter{Value: `(?<=a)b`}}.Eval(vars, nil)
+	assert.ErrorContains(t, err, "if condition regex (?<=a)b")
+}
+
+func TestNewIf(t *testing.T) {
+	t.Parallel()
+	i, err := NewIf(&Directive{Name: "if", Parameters: params("($a", "=", "b)"), Block: &Block{}})
+	assert.NilError(t, err)
+	assert.Equal(t, i.Condition.Variable, "$a")
+	assert.Equal(t, i.Condition.Operand.Value, "b")
+
+	i.SetCondition(Condition{Operator: OperatorNotExists, Operand: NewParameter("/tmp/maintenance")})
+	assert.DeepEqual(t, i.GetParameters(), params("(!-e", "/tmp/maintenance)"))
+
+	_, err = NewIf(&Directive{Name: "if", Parameters: params("($a", "=")})
+	assert.Error(t, err, "invalid if condition: missing )")
+	_, err = NewIf(&Location{})
+	assert.Error(t, err, "if directive type error")
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Listen is a parsed listen directive, of a stream server or of an http server
+type Listen struct {
+	Address       string // as written: address:port, port, [::1]:port, a port range or unix:path
+	DefaultServer bool   // default_server, or default, of http servers
+	SSL           bool
+	UDP           bool // stream servers only
+	ProxyProtocol bool
+	ReusePort     bool
+	Bind          bool
+	Flags         []string          // the other flags, e.g. http2 or deferred
+	Options       map[string]string // the key=value parameters, e.g. backlog=511
+}
+
+// ParseListen parses the parameters of a listen directive
+func ParseListen(d IDirective) (Listen, error) {
+	params := d.GetParameters()
+	if d.GetName() != "listen" || len(params) == 0 {
+		return Listen{}, errors.New("listen directive requires an address")
+	}
+	l := Listen{Address: params[0].Unquoted(), Flags: []string{}, Options: map[string]string{}}
+	for _, p := range params[1:] {
+		v := p.Unquoted()
+		switch v {
+		case "default_server", "default":
+			l.DefaultServer = true
+		case "ssl":
+			l.SSL = true
+		case "udp":
+			l.UDP = true
+		case "proxy_protocol":
+			l.ProxyProtocol = true
+		case "reuseport":
+			l.ReusePort = true
+		case "bind":
+			l.Bind = true
+		default:
+			if key, value, ok := strings.Cut(v, "="); ok {
+				l.Options[key] = value
+				continue
+			}
+			l.Flags = append(l.Flags, v)
+		}
+	}
+	return l, nil
+}
+
+// Host returns the address without its port, empty when only a port is given.
+// A unix socket is returned as is
+func (l Listen) Host() string {
+	if strings.HasPrefix(l.Address, "unix:") {
+		return l.Address
+	}
+	if strings.HasPrefix(l.Address, "[") {
+		host, _, _ := strings.Cut(l.Address, "]")
+		return host + "]"
+	}
+	if host, _, ok := strings.Cut(l.Address, ":"); ok {
+		return host
+	}
+	if isPort(l.Address) {
+		return ""
+	}
+	return l.Address
+}
+
+// Port returns the port or the port range, empty when it is not written
+func (l Listen) Port() string {
+	if strings.HasPrefix(l.Address, "unix:") {
+		return ""
+	}
+	if i := strings.LastIndex(l.Address, ":"); i >= 0 && !strings.HasSuffix(l.Address, "]") {
+		return l.Address[i+1:]
+	}
+	if isPort(l.Address) {
+		return l.Address
+	}
+	return ""
+}
+
+func isPort(s string) bool {
+	first, last, _ := strings.Cut(s, "-")
+	return first != "" && isDigits(first) && isDigits(last)
+}
+
+// Parameters returns the parameters of the listen directive: the address, the flags in the
+// order nginx documents them, then the key=value parameters sorted by key
+func (l Listen) Parameters() []Parameter {
+	parameters := []Parameter{NewParameter(l.Address)}
+	flags := []struct {
+		set  bool
+		name string
+	}{
+		{l.DefaultServer, "default_server"},
+		{l.SSL, "ssl"},
+		{l.UDP, "udp"},
+		{l.ProxyProtocol, "proxy_protocol"},
+	}
+	for _, flag := range flags {
+		if flag.set {
+			parameters = append(parameters, Parameter{Value: flag.name})
+		}
+	}
+	for _, flag := range l.Flags {
+		parameters = append(parameters, NewParameter(flag))
+	}
+
+	keys := make([]string, 0, len(l.Options))
+	for key := range l.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parameters = append(parameters, NewParameter(key+"="+l.Options[key]))
+	}
+	if l.Bind {
+		parameters = append(parameters, Parameter{Value: "bind"})
+	}
+	if l.ReusePort {
+		parameters = append(parameters, Parameter{Value: "reuseport"})
+	}
+	return parameters
+}
+
+// Directive returns a listen directive with the parameters of l
+func (l Listen) Directive() *Directive {
+	return &Directive{Name: "listen", Parameters: l.Parameters()}
+}
+
+// listensOf returns the parsed listen directives of block
+func listensOf(block IBlock) ([]Listen, error) {
+	listens := make([]Listen, 0)
+	if block == nil {
+		return listens, nil
+	}
+	for _, d := range block.GetDirectives() {
+		if d.GetName() != "listen" {
+			continue
+		}
+		l, err := ParseListen(d)
+		if err != nil {
+			return nil, err
+		}
+		listens = append(listens, l)
+	}
+	return listens, nil
+}
+
+// replaceListens replaces the listen directives of block, see replaceDirectives
+func replaceListens(block IBlock, parent IDirective, listens []Listen) IBlock {
+	directives := make([]*Directive, 0, len(listens))
+	for _, l := range listens {
+		directives = append(directives, l.Directive())
+	}
+	return replaceDirectives(block, parent, "listen", directives)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseListen(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		params []string
+		want   Listen
+		host   string
+		port   string
+		dump   string
+	}{
+		{
+			params: []string{"53", "udp", "reuseport"},
+			want:   Listen{Address: "53", UDP: true, ReusePort: true, Flags: []string{}, Options: map[string]string{}},
+			host:   "",
+			port:   "53",
+			dump:   "53 udp reuseport",
+		},
+		{
+			params: []string{"[::1]:443", "reuseport", "proxy_protocol", "backlog=511", "ssl"},
+			want:   Listen{Address: "[::1]:443", SSL: true, ProxyProtocol: true, ReusePort: true, Flags: []string{}, Options: map[string]string{"backlog": "511"}},
+			host:   "[::1]",
+			port:   "443",
+			dump:   "[::1]:443 ssl proxy_protocol backlog=511 reuseport",
+		},
+		{
+			params: []string{"127.0.0.1:12345-12399", "default_server", "http2"},
+			want:   Listen{Address: "127.0.0.1:12345-12399", DefaultServer: true, Flags: []string{"http2"}, Options: map[string]string{}},
+			host:   "127.0.0.1",
+			port:   "12345-12399",
+			dump:   "127.0.0.1:12345-12399 default_server http2",
+		},
+		{
+			params: []string{"unix:/var/run/dns.sock"},
+			want:   Listen{Address: "unix:/var/run/dns.sock", Flags: []string{}, Options: map[string]string{}},
+			host:   "unix:/var/run/dns.sock",
+			port:   "",
+			dump:   "unix:/var/run/dns.sock",
+		},
+	}
+	for _, tt := range tests {
+		d := &Directive{Name: "listen"}
+		for _, p := range tt.params {
+			d.Parameters = append(d.Parameters, Parameter{Value: p})
+		}
+		l, err := ParseListen(d)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, l, tt.want)
+		assert.Equal(t, l.Host(), tt.host)
+		assert.Equal(t, l.Port(), tt.port)
+		values := ""
+		for i, p := range l.Parameters() {
+			if i > 0 {
+				values += " "
+			}
+			values += p.Value
+		}
+		assert.Equal(t, values, tt.dump)
+	}
+
+	_, err := ParseListen(&Directive{Name: "listen"})
+	assert.ErrorContains(t, err, "listen directive requires an address")
+}
@@ -10,6 +10,10 @@ type LuaBlock struct {
 	Name       string
 	Comment    []string
 	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
+	DefaultBraces
 	LuaCode    string
 	Parent     IDirective
 	Line       int
@@ -29,6 +33,11 @@ func NewLuaBlock(directive IDirective) (*LuaBlock, error) {
 		lb.Directives = append(lb.Directives, block.GetDirectives()...)
 		lb.Comment = directive.GetComment()
 		lb.InlineComment = directive.GetInlineComment()
+		lb.Span = directive.GetSpan()
+		lb.CommentSpans = directive.GetCommentSpans()
+		lb.Trivia = directive.GetTrivia()
+		lb.Annotations, lb.AnnotationSyntax = directive.GetAnnotations(), directive.GetAnnotationSyntax()
+		lb.LBrace, lb.RBrace = block.GetBraces()
 
 		return lb, nil
 	}
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LuaFile represents a *_by_lua_file directive whose code is kept in memory,
+// as created by ExtractLua. dumper.WriteConfig writes Code to FilePath along with the config
+type LuaFile struct {
+	*Directive
+	Path     string // the path of the Lua file, as written in the directive
+	FilePath string // where the file is read from and written to
+	Code     string
+}
+
+// NewLuaFile initializes a LuaFile from a *_by_lua_file directive, its code is not read
+func NewLuaFile(dir IDirective) (*LuaFile, error) {
+	directive, ok := dir.(*Directive)
+	if !ok {
+		return nil, errors.New("lua file directive type error")
+	}
+	if !strings.HasSuffix(directive.Name, "_by_lua_file") {
+		return nil, fmt.Errorf("%s is not a *_by_lua_file directive", directive.Name)
+	}
+
+	index := luaPathIndex(directive.Name)
+	if len(directive.Parameters) <= index {
+		return nil, fmt.Errorf("%s directive requires a file path", directive.Name)
+	}
+	return &LuaFile{
+		Directive: directive,
+		Path:      directive.Parameters[index].Unquoted(),
+	}, nil
+}
+
+// luaPathIndex returns the index of the file path in the parameters of a *_by_lua_file directive,
+// set_by_lua_file takes the variable to set first
+func luaPathIndex(name string) int {
+	if name == "set_by_lua_file" {
+		return 1
+	}
+	return 0
+}
+
+// LuaOption configures ExtractLua and InlineLua
+type LuaOption func(*luaOptions)
+
+type luaOptions struct {
+	dir   string
+	root  string
+	namer func(block *LuaBlock, index int) string
+	fsys  fs.FS
+}
+
+// WithLuaDir sets the directory of the extracted files as written in the directives, "lua" by default
+func WithLuaDir(dir string) LuaOption {
+	return func(o *luaOptions) {
+		o.dir = dir
+	}
+}
+
+// WithLuaRoot sets the directory relative Lua paths are resolved from when reading and writing files.
+// nginx resolves them from its prefix; the directory of the config file is used by default
+func WithLuaRoot(root string) LuaOption {
+	return func(o *luaOptions) {
+		o.root = root
+	}
+}
+
+// WithLuaNamer sets the name of the file ExtractLua moves a block to, relative to the Lua directory.
+// index counts the extracted blocks from 1, in config order. The default name is
+// the phase of the block followed by index, e.g. access_1.lua for access_by_lua_block
+func WithLuaNamer(namer func(block *LuaBlock, index int) string) LuaOption {
+	return func(o *luaOptions) {
+		o.namer = namer
+	}
+}
+
+// WithLuaFS makes InlineLua read Lua files from fsys instead of the operating system,
+// relative paths are resolved from the Lua root and absolute ones from the root of fsys
+func WithLuaFS(fsys fs.FS) LuaOption {
+	return func(o *luaOptions) {
+		o.fsys = fsys
+	}
+}
+
+func newLuaOptions(c *Config, opts []LuaOption) luaOptions {
+	o := luaOptions{
+		dir:  "lua",
+		root: filepath.Dir(c.FilePath),
+		namer: func(block *LuaBlock, index int) string {
+			return fmt.Sprintf("%s_%d.lua", strings.TrimSuffix(block.Name, "_by_lua_block"), index)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ExtractLua replaces every *_by_lua_block of the config and of its included configs by the
+// matching *_by_lua_file directive, e.g. access_by_lua_block { ... } by access_by_lua_file lua/access_1.lua;
+// The code is kept in the returned LuaFile directives until dumper.WriteConfig writes it
+func ExtractLua(c *Config, opts ...LuaOption) ([]*LuaFile, error) {
+	o := newLuaOptions(c, opts)
+	o.fsys = nil // the files are written by dumper.WriteConfig
+	files := make([]*LuaFile, 0)
+	paths := make(map[string]struct{})
+	err := rewriteDirectives(c.Block, func(d IDirective) (IDirective, error) {
+		block, ok := d.(*LuaBlock)
+		if !ok {
+			return d, nil
+		}
+		luaPath := path.Join(o.dir, o.namer(block, len(files)+1))
+		if _, ok := paths[luaPath]; ok {
+			return nil, fmt.Errorf("duplicate Lua file %s for %s", luaPath, block.Name)
+		}
+		paths[luaPath] = struct{}{}
+
+		directive := &Directive{
+			Name:                 strings.TrimSuffix(block.Name, "_block") + "_file",
+			Parameters:           append(append([]Parameter{}, block.Parameters...), NewParameter(luaPath)),
+			Comment:              block.Comment,
+			DefaultInlineComment: block.DefaultInlineComment,
+			DefaultAnnotations:   block.DefaultAnnotations,
+			Parent:               block.Parent,
+		}
+		file := &LuaFile{
+			Directive: directive,
+			Path:      luaPath,
+			FilePath:  o.resolve(luaPath),
+			Code:      extractedCode(block.LuaCode),
+		}
+		files = append(files, file)
+		return file, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// InlineLua replaces every *_by_lua_file directive of the config and of its included configs by
+// the matching *_by_lua_block holding the content of the file, for single file deployments.
+// Paths with variables and set_by_lua_file with arguments cannot be inlined and return an error
+func InlineLua(c *Config, opts ...LuaOption) error {
+	o := newLuaOptions(c, opts)
+	return rewriteDirectives(c.Block, func(d IDirective) (IDirective, error) {
+		if !strings.HasSuffix(d.GetName(), "_by_lua_file") {
+			return d, nil
+		}
+		file, ok := d.(*LuaFile)
+		if !ok {
+			var err error
+			if file, err = NewLuaFile(d); err != nil {
+				return nil, err
+			}
+		}
+
+		index := luaPathIndex(file.Name)
+		if len(file.Parameters) > index+1 {
+			return nil, fmt.Errorf("cannot inline %s with arguments", file.Name)
+		}
+		if parameter := file.Parameters[index]; parameter.IsVariable() || len(parameter.Variables()) > 0 {
+			return nil, fmt.Errorf("cannot inline %s with a variable path %s", file.Name, parameter.Value)
+		}
+		if !ok {
+			var err error
+			file.FilePath = o.resolve(file.Path)
+			if file.Code, err = o.read(file.FilePath); err != nil {
+				return nil, err
+			}
+		}
+
+		return &LuaBlock{
+			Directives:           []IDirective{},
+			Name:                 strings.TrimSuffix(file.Name, "_file") + "_block",
+			Comment:              file.Comment,
+			DefaultInlineComment: file.DefaultInlineComment,
+			DefaultAnnotations:   file.DefaultAnnotations,
+			LuaCode:              "\n" + strings.TrimRight(file.Code, "\r\n") + "\n",
+			Parent:               file.Parent,
+			Parameters:           append([]Parameter{}, file.Parameters[:index]...),
+		}, nil
+	})
+}
+
+// resolve returns the path of a Lua file on disk, or in the file system set by WithLuaFS
+func (o luaOptions) resolve(luaPath string) string {
+	if o.fsys != nil {
+		if path.IsAbs(luaPath) {
+			return strings.TrimPrefix(luaPath, "/")
+		}
+		return path.Join(filepath.ToSlash(o.root), luaPath)
+	}
+	if filepath.IsAbs(luaPath) {
+		return luaPath
+	}
+	return filepath.Join(o.root, filepath.FromSlash(luaPath))
+}
+
+func (o luaOptions) read(name string) (string, error) {
+	var code []byte
+	var err error
+	if o.fsys != nil {
+		code, err = fs.ReadFile(o.fsys, name)
+	} else {
+		code, err = os.ReadFile(name)
+	}
+	return string(code), err
+}
+
+// extractedCode returns the code of a block as the content of its own file: without the blank
+// lines around it and, unless it has long strings whose content would change, without its indentation
+func extractedCode(code string) string {
+	lines := strings.Split(strings.TrimRight(code, " \t\r\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	if !strings.Contains(code, "[[") && !strings.Contains(code, "[=") {
+		indent := ""
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			if i == 0 || len(lineIndent) < len(indent) {
+				indent = lineIndent
+			}
+		}
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(line, indent)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// rewriteDirectives replaces in place every directive of b, of its sub blocks and of the
+// configs it includes by the result of rewrite. A block whose directives change must be a DirectivesSetter
+func rewriteDirectives(b IBlock, rewrite func(IDirective) (IDirective, error)) error {
+	directives := b.GetDirectives()
+	rewritten := make([]IDirective, 0, len(directives))
+	changed := false
+	for _, directive := range directives {
+		r, err := rewrite(directive)
+		if err != nil {
+			return err
+		}
+		changed = changed || r != directive
+		rewritten = append(rewritten, r)
+	}
+	if changed {
+		setter, ok := b.(DirectivesSetter)
+		if !ok {
+			return fmt.Errorf("cannot replace the directives of a %T block", b)
+		}
+		setter.SetDirectives(rewritten)
+	}
+
+	for _, directive := range b.GetDirectives() {
+		if include, ok := directive.(*Include); ok {
+			for _, c := range include.Configs {
+				if err := rewriteDirectives(c.Block, rewrite); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if _, ok := directive.(*LuaBlock); !ok && directive.GetBlock() != nil {
+			if err := rewriteDirectives(directive.GetBlock(), rewrite); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
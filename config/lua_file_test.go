@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"gotest.tools/v3/assert"
+)
+
+func luaTestConfig() *Config {
+	access := &LuaBlock{Name: "access_by_lua_block", LuaCode: "\n            if ngx.var.arg_a then\n                ngx.exit(403)\n            end\n        ", Comment: []string{"# deny a"}}
+	set := &LuaBlock{Name: "set_by_lua_block", LuaCode: " return 1 ", Parameters: []Parameter{{Value: "$one"}}}
+	location := &Location{Directive: &Directive{Name: "location", Parameters: []Parameter{{Value: "/"}}, Block: &Block{Directives: []IDirective{access, set}}}}
+	server := &Server{Block: &Block{Directives: []IDirective{location}}}
+	initBlock := &LuaBlock{Name: "init_by_lua_block", LuaCode: "\n    require \"resty.core\"\n"}
+	http := &HTTP{Directives: []IDirective{initBlock}, Servers: []*Server{server}}
+	return &Config{FilePath: "/etc/nginx/nginx.conf", Block: &Block{Directives: []IDirective{http}}}
+}
+
+func TestExtractLua(t *testing.T) {
+	t.Parallel()
+	c := luaTestConfig()
+	files, err := ExtractLua(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 3)
+
+	assert.Equal(t, files[0].Name, "init_by_lua_file")
+	assert.Equal(t, files[0].Path, "lua/init_1.lua")
+	assert.Equal(t, files[0].FilePath, "/etc/nginx/lua/init_1.lua")
+	assert.Equal(t, files[0].Code, "require \"resty.core\"\n")
+
+	// the code is unindented and the comments are kept
+	assert.Equal(t, files[1].Name, "access_by_lua_file")
+	assert.Equal(t, files[1].Code, "if ngx.var.arg_a then\n    ngx.exit(403)\nend\n")
+	assert.DeepEqual(t, files[1].Comment, []string{"# deny a"})
+
+	// set_by_lua_file keeps its variable before the path
+	assert.Equal(t, files[2].Name, "set_by_lua_file")
+	assert.DeepEqual(t, files[2].Parameters, []Parameter{{Value: "$one"}, {Value: "lua/set_3.lua"}})
+	assert.Equal(t, files[2].Code, "return 1\n")
+
+	// the blocks are replaced in place
+	http := c.Directives[0].(*HTTP)
+	assert.Equal(t, http.Directives[0], IDirective(files[0]))
+	location := http.Servers[0].GetDirectives()[0]
+	assert.Equal(t, location.GetBlock().GetDirectives()[0], IDirective(files[1]))
+}
+
+func TestExtractLua_Options(t *testing.T) {
+	t.Parallel()
+	c := luaTestConfig()
+	files, err := ExtractLua(c, WithLuaDir("/srv/lua"), WithLuaRoot("/ignored"), WithLuaNamer(func(block *LuaBlock, index int) string {
+		return fmt.Sprintf("%d/%s.lua", index, block.Name)
+	}))
+	assert.NilError(t, err)
+	assert.Equal(t, files[1].Path, "/srv/lua/2/access_by_lua_block.lua")
+	assert.Equal(t, files[1].FilePath, "/srv/lua/2/access_by_lua_block.lua")
+
+	files, err = ExtractLua(luaTestConfig(), WithLuaRoot("/opt/openresty"))
+	assert.NilError(t, err)
+	assert.Equal(t, files[0].FilePath, "/opt/openresty/lua/init_1.lua")
+
+	_, err = ExtractLua(luaTestConfig(), WithLuaNamer(func(*LuaBlock, int) string { return "same.lua" }))
+	assert.Error(t, err, "duplicate Lua file lua/same.lua for access_by_lua_block")
+}
+
+func TestInlineLua(t *testing.T) {
+	t.Parallel()
+	c := luaTestConfig()
+	_, err := ExtractLua(c)
+	assert.NilError(t, err)
+
+	// the extracted code is inlined back from memory
+	assert.NilError(t, InlineLua(c))
+	http := c.Directives[0].(*HTTP)
+	init, ok := http.Directives[0].(*LuaBlock)
+	assert.Assert(t, ok)
+	assert.Equal(t, init.Name, "init_by_lua_block")
+	assert.Equal(t, init.LuaCode, "\nrequire \"resty.core\"\n")
+	set := http.Servers[0].GetDirectives()[0].GetBlock().GetDirectives()[1].(*LuaBlock)
+	assert.DeepEqual(t, set.Parameters, []Parameter{{Value: "$one"}})
+
+	// parsed directives are read from their file
+	fsys := fstest.MapFS{
+		"etc/nginx/lua/access.lua": {Data: []byte("ngx.exit(403)\n")},
+		"usr/lib/hello.lua":        {Data: []byte("ngx.say('hello')")},
+	}
+	c = &Config{FilePath: "/etc/nginx/nginx.conf", Block: &Block{Directives: []IDirective{
+		&Directive{Name: "access_by_lua_file", Parameters: []Parameter{{Value: "lua/access.lua"}}},
+		&Directive{Name: "content_by_lua_file", Parameters: []Parameter{{Value: "\"/usr/lib/hello.lua\"", Quote: QuoteDouble}}, Comment: []string{"# hello"}},
+	}}}
+	assert.NilError(t, InlineLua(c, WithLuaRoot("etc/nginx"), WithLuaFS(fsys)))
+	access := c.Directives[0].(*LuaBlock)
+	assert.Equal(t, access.Name, "access_by_lua_block")
+	assert.Equal(t, access.GetCodeBlock(), "\nngx.exit(403)\n")
+	content := c.Directives[1].(*LuaBlock)
+	assert.Equal(t, content.GetCodeBlock(), "\nngx.say('hello')\n")
+	assert.DeepEqual(t, content.Comment, []string{"# hello"})
+}
+
+func TestInlineLua_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		directive *Directive
+		wantErr   string
+	}{
+		{&Directive{Name: "content_by_lua_file", Parameters: []Parameter{{Value: "lua/$uri.lua"}}}, "cannot inline content_by_lua_file with a variable path lua/$uri.lua"},
+		{&Directive{Name: "set_by_lua_file", Parameters: []Parameter{{Value: "$a"}, {Value: "a.lua"}, {Value: "$arg_b"}}}, "cannot inline set_by_lua_file with arguments"},
+		{&Directive{Name: "content_by_lua_file"}, "content_by_lua_file directive requires a file path"},
+		{&Directive{Name: "content_by_lua_file", Parameters: []Parameter{{Value: "missing.lua"}}}, "open missing.lua: file does not exist"},
+	}
+	fsys := fstest.MapFS{"a.lua": {Data: []byte("return ngx.arg[1]")}}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.wantErr, func(t *testing.T) {
+			t.Parallel()
+			c := &Config{Block: &Block{Directives: []IDirective{tt.directive}}}
+			assert.Error(t, InlineLua(c, WithLuaFS(fsys)), tt.wantErr)
+		})
+	}
+}
+
+// fixedBlock is a block whose directives cannot be replaced
+type fixedBlock struct {
+	DefaultBraces
+	directives []IDirective
+}
+
+func (b *fixedBlock) GetDirectives() []IDirective        { return b.directives }
+func (b *fixedBlock) FindDirectives(string) []IDirective { return nil }
+func (b *fixedBlock) GetCodeBlock() string               { return "" }
+func (b *fixedBlock) SetParent(IDirective)               {}
+func (b *fixedBlock) GetParent() IDirective              { return nil }
+
+func TestExtractLua_Blocks(t *testing.T) {
+	t.Parallel()
+	content := &LuaBlock{Name: "content_by_lua_block", LuaCode: "ngx.say(1)"}
+	custom := &Directive{Name: "custom", Block: &fixedBlock{directives: []IDirective{content}}}
+	_, err := ExtractLua(&Config{Block: &Block{Directives: []IDirective{custom}}})
+	assert.Error(t, err, "cannot replace the directives of a *config.fixedBlock block")
+
+	// blocks without Lua are left alone
+	custom.Block = &fixedBlock{directives: []IDirective{&Directive{Name: "return", Parameters: []Parameter{{Value: "204"}}}}}
+	files, err := ExtractLua(&Config{Block: &Block{Directives: []IDirective{custom}}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 0)
+
+	// the servers of an upstream stay servers
+	balancer := &LuaBlock{Name: "balancer_by_lua_block", LuaCode: "ngx.exit(200)"}
+	upstream := &Upstream{UpstreamName: "backend", Directives: []IDirective{balancer}, UpstreamServers: []*UpstreamServer{{Address: "127.0.0.1"}}}
+	files, err = ExtractLua(&Config{Block: &Block{Directives: []IDirective{upstream}}})
+	assert.NilError(t, err)
+	assert.Equal(t, upstream.Directives[0], IDirective(files[0]))
+	assert.Equal(t, len(upstream.UpstreamServers), 1)
+	assert.Equal(t, upstream.UpstreamServers[0].GetParent(), IDirective(upstream))
+}
@@ -0,0 +1,400 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MailProtocol is the protocol of a mail server
+type MailProtocol string
+
+// The protocols of the mail proxy
+const (
+	MailProtocolIMAP MailProtocol = "imap"
+	MailProtocolPOP3 MailProtocol = "pop3"
+	MailProtocolSMTP MailProtocol = "smtp"
+)
+
+// StartTLS is the mode of the starttls directive
+type StartTLS string
+
+// The modes of starttls. StartTLSOnly requires clients to switch to TLS
+const (
+	StartTLSOff  StartTLS = "off"
+	StartTLSOn   StartTLS = "on"
+	StartTLSOnly StartTLS = "only"
+)
+
+// SMTPAuthMethod is a method of the smtp_auth directive
+type SMTPAuthMethod string
+
+// The SMTP authentication methods. SMTPAuthNone allows clients that do not authenticate
+const (
+	SMTPAuthLogin    SMTPAuthMethod = "login"
+	SMTPAuthPlain    SMTPAuthMethod = "plain"
+	SMTPAuthCRAMMD5  SMTPAuthMethod = "cram-md5"
+	SMTPAuthExternal SMTPAuthMethod = "external"
+	SMTPAuthNone     SMTPAuthMethod = "none"
+)
+
+// ParseMailProtocol parses the value of the protocol directive
+func ParseMailProtocol(s string) (MailProtocol, error) {
+	switch p := MailProtocol(s); p {
+	case MailProtocolIMAP, MailProtocolPOP3, MailProtocolSMTP:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown protocol \"%s\"", s)
+}
+
+// ParseStartTLS parses the value of the starttls directive
+func ParseStartTLS(s string) (StartTLS, error) {
+	switch mode := StartTLS(s); mode {
+	case StartTLSOff, StartTLSOn, StartTLSOnly:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid value \"%s\" in \"starttls\" directive, it must be \"on\", \"off\" or \"only\"", s)
+}
+
+// ParseSMTPAuth parses the methods of the smtp_auth directive
+func ParseSMTPAuth(parameters []Parameter) ([]SMTPAuthMethod, error) {
+	methods := make([]SMTPAuthMethod, 0, len(parameters))
+	for _, p := range parameters {
+		switch method := SMTPAuthMethod(strings.ToLower(p.Unquoted())); method {
+		case SMTPAuthLogin, SMTPAuthPlain, SMTPAuthCRAMMD5, SMTPAuthExternal, SMTPAuthNone:
+			methods = append(methods, method)
+		default:
+			return nil, fmt.Errorf("invalid value \"%s\" in \"smtp_auth\" directive", p.Unquoted())
+		}
+	}
+	return methods, nil
+}
+
+// Mail represents a mail block, the IMAP, POP3 and SMTP proxy subsystem.
+type Mail struct {
+	*Directive
+	Parent IDirective
+	Line   int
+}
+
+// SetLine sets the line number.
+func (m *Mail) SetLine(line int) {
+	m.Line = line
+}
+
+// GetLine returns the line number.
+func (m *Mail) GetLine() int {
+	return m.Line
+}
+
+// SetParent sets the parent directive.
+func (m *Mail) SetParent(parent IDirective) {
+	m.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (m *Mail) GetParent() IDirective {
+	return m.Parent
+}
+
+// NewMail initializes a Mail from a mail directive.
+func NewMail(directive IDirective) (*Mail, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("mail directive type error")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("mail directive must have a block")
+	}
+	if len(dir.Parameters) != 0 {
+		return nil, errors.New("mail directive does not take parameters")
+	}
+	return &Mail{Directive: dir}, nil
+}
+
+// Servers returns the servers of the mail block, those of the files it includes too.
+func (m *Mail) Servers() []*MailServer {
+	servers := make([]*MailServer, 0)
+	for _, d := range m.FindDirectives("server") {
+		if server, ok := d.(*MailServer); ok {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// AddServer appends a server to the mail block.
+func (m *Mail) AddServer(server *MailServer) {
+	if server == nil {
+		return
+	}
+	server.SetParent(m)
+	m.Block = appendDirective(m.Block, server)
+}
+
+// AuthHTTP returns the URL of the authentication server, empty without auth_http.
+func (m *Mail) AuthHTTP() string {
+	return mailAuthHTTP(m.Block)
+}
+
+// SetAuthHTTP sets the URL of the authentication server, adding auth_http when missing.
+func (m *Mail) SetAuthHTTP(url string) {
+	m.Block = setDirective(m.Block, m, "auth_http", NewParameter(url))
+}
+
+// ProxyPassErrorMessage reports whether the errors of the backends are passed to the clients, off by default.
+func (m *Mail) ProxyPassErrorMessage() bool {
+	on, _ := mailProxyPassErrorMessage(m.Block)
+	return on
+}
+
+// SetProxyPassErrorMessage sets proxy_pass_error_message, adding it when missing.
+func (m *Mail) SetProxyPassErrorMessage(on bool) {
+	m.Block = setFlag(m.Block, m, "proxy_pass_error_message", on)
+}
+
+// StartTLS returns the starttls mode, StartTLSOff by default.
+func (m *Mail) StartTLS() StartTLS {
+	mode, _ := mailStartTLS(m.Block)
+	return mode
+}
+
+// SetStartTLS sets the starttls mode, adding the directive when missing.
+func (m *Mail) SetStartTLS(mode StartTLS) error {
+	if _, err := ParseStartTLS(string(mode)); err != nil {
+		return err
+	}
+	m.Block = setDirective(m.Block, m, "starttls", Parameter{Value: string(mode)})
+	return nil
+}
+
+// SMTPAuth returns the SMTP authentication methods, login and plain by default.
+func (m *Mail) SMTPAuth() []SMTPAuthMethod {
+	methods, _ := mailSMTPAuth(m.Block)
+	return methods
+}
+
+// SetSMTPAuth sets the SMTP authentication methods, adding smtp_auth when missing.
+func (m *Mail) SetSMTPAuth(methods ...SMTPAuthMethod) error {
+	parameters, err := smtpAuthParameters(methods)
+	if err != nil {
+		return err
+	}
+	m.Block = setDirective(m.Block, m, "smtp_auth", parameters...)
+	return nil
+}
+
+// FindDirectives finds directives by name.
+func (m *Mail) FindDirectives(directiveName string) []IDirective {
+	return m.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the mail block.
+func (m *Mail) GetDirectives() []IDirective {
+	return m.GetBlock().GetDirectives()
+}
+
+// MailServer represents a server block of the mail subsystem. The directives that can be set in the
+// mail block are read from it when the server does not set them, as nginx inherits them
+type MailServer struct {
+	*Directive
+	Parent IDirective
+	Line   int
+}
+
+// SetLine sets the line number.
+func (s *MailServer) SetLine(line int) {
+	s.Line = line
+}
+
+// GetLine returns the line number.
+func (s *MailServer) GetLine() int {
+	return s.Line
+}
+
+// SetParent sets the parent directive.
+func (s *MailServer) SetParent(parent IDirective) {
+	s.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (s *MailServer) GetParent() IDirective {
+	return s.Parent
+}
+
+// NewMailServer initializes a MailServer from a server directive of a mail block.
+func NewMailServer(directive IDirective) (*MailServer, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("mail server directive type error")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("server directive must have a block")
+	}
+	return &MailServer{Directive: dir}, nil
+}
+
+// mail returns the block of the mail block of the server, nil when the server is not in one
+func (s *MailServer) mail() IBlock {
+	if m, ok := s.Parent.(*Mail); ok {
+		return m.Block
+	}
+	return nil
+}
+
+// Protocol returns the protocol of the server, empty when it is not set: nginx then
+// guesses it from the well-known ports of the listen directives.
+func (s *MailServer) Protocol() MailProtocol {
+	parameters, _ := directiveParameters(s.Block, "protocol")
+	if len(parameters) == 0 {
+		return ""
+	}
+	protocol, _ := ParseMailProtocol(parameters[0].Unquoted())
+	return protocol
+}
+
+// SetProtocol sets the protocol of the server, adding the directive when missing.
+func (s *MailServer) SetProtocol(protocol MailProtocol) error {
+	if _, err := ParseMailProtocol(string(protocol)); err != nil {
+		return err
+	}
+	s.Block = setDirective(s.Block, s, "protocol", Parameter{Value: string(protocol)})
+	return nil
+}
+
+// Listens returns the parsed listen directives of the server.
+func (s *MailServer) Listens() ([]Listen, error) {
+	return listensOf(s.Block)
+}
+
+// SetListens replaces the listen directives of the server, the new ones taking the place of the first one
+// and the comments of the replaced ones by position.
+func (s *MailServer) SetListens(listens ...Listen) {
+	s.Block = replaceListens(s.Block, s, listens)
+}
+
+// AuthHTTP returns the URL of the authentication server of the server or of its mail block.
+func (s *MailServer) AuthHTTP() string {
+	if url := mailAuthHTTP(s.Block); url != "" {
+		return url
+	}
+	return mailAuthHTTP(s.mail())
+}
+
+// SetAuthHTTP sets the URL of the authentication server in the server, adding auth_http when missing.
+func (s *MailServer) SetAuthHTTP(url string) {
+	s.Block = setDirective(s.Block, s, "auth_http", NewParameter(url))
+}
+
+// ProxyPassErrorMessage reports whether the errors of the backends are passed to the clients.
+func (s *MailServer) ProxyPassErrorMessage() bool {
+	if on, ok := mailProxyPassErrorMessage(s.Block); ok {
+		return on
+	}
+	on, _ := mailProxyPassErrorMessage(s.mail())
+	return on
+}
+
+// SetProxyPassErrorMessage sets proxy_pass_error_message in the server, adding it when missing.
+func (s *MailServer) SetProxyPassErrorMessage(on bool) {
+	s.Block = setFlag(s.Block, s, "proxy_pass_error_message", on)
+}
+
+// StartTLS returns the starttls mode of the server or of its mail block, StartTLSOff by default.
+func (s *MailServer) StartTLS() StartTLS {
+	if mode, ok := mailStartTLS(s.Block); ok {
+		return mode
+	}
+	mode, _ := mailStartTLS(s.mail())
+	return mode
+}
+
+// SetStartTLS sets the starttls mode in the server, adding the directive when missing.
+func (s *MailServer) SetStartTLS(mode StartTLS) error {
+	if _, err := ParseStartTLS(string(mode)); err != nil {
+		return err
+	}
+	s.Block = setDirective(s.Block, s, "starttls", Parameter{Value: string(mode)})
+	return nil
+}
+
+// SMTPAuth returns the SMTP authentication methods of the server or of its mail block, login and plain by default.
+func (s *MailServer) SMTPAuth() []SMTPAuthMethod {
+	if methods, ok := mailSMTPAuth(s.Block); ok {
+		return methods
+	}
+	methods, _ := mailSMTPAuth(s.mail())
+	return methods
+}
+
+// SetSMTPAuth sets the SMTP authentication methods in the server, adding smtp_auth when missing.
+func (s *MailServer) SetSMTPAuth(methods ...SMTPAuthMethod) error {
+	parameters, err := smtpAuthParameters(methods)
+	if err != nil {
+		return err
+	}
+	s.Block = setDirective(s.Block, s, "smtp_auth", parameters...)
+	return nil
+}
+
+// FindDirectives finds directives by name.
+func (s *MailServer) FindDirectives(directiveName string) []IDirective {
+	return s.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the server block.
+func (s *MailServer) GetDirectives() []IDirective {
+	return s.GetBlock().GetDirectives()
+}
+
+func mailAuthHTTP(block IBlock) string {
+	if parameters, _ := directiveParameters(block, "auth_http"); len(parameters) > 0 {
+		return parameters[0].Unquoted()
+	}
+	return ""
+}
+
+func mailProxyPassErrorMessage(block IBlock) (on bool, ok bool) {
+	parameters, ok := directiveParameters(block, "proxy_pass_error_message")
+	if !ok || len(parameters) == 0 {
+		return false, false
+	}
+	on, err := parameters[0].AsBool()
+	return on, err == nil
+}
+
+func mailStartTLS(block IBlock) (StartTLS, bool) {
+	parameters, ok := directiveParameters(block, "starttls")
+	if !ok || len(parameters) == 0 {
+		return StartTLSOff, false
+	}
+	mode, err := ParseStartTLS(parameters[0].Unquoted())
+	if err != nil {
+		return StartTLSOff, false
+	}
+	return mode, true
+}
+
+func mailSMTPAuth(block IBlock) ([]SMTPAuthMethod, bool) {
+	parameters, ok := directiveParameters(block, "smtp_auth")
+	if ok {
+		if methods, err := ParseSMTPAuth(parameters); err == nil && len(methods) > 0 {
+			return methods, true
+		}
+	}
+	return []SMTPAuthMethod{SMTPAuthLogin, SMTPAuthPlain}, false
+}
+
+func smtpAuthParameters(methods []SMTPAuthMethod) ([]Parameter, error) {
+	if len(methods) == 0 {
+		return nil, errors.New("smtp_auth requires a method")
+	}
+	parameters := make([]Parameter, 0, len(methods))
+	for _, method := range methods {
+		parameters = append(parameters, Parameter{Value: string(method)})
+	}
+	if _, err := ParseSMTPAuth(parameters); err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMailServer(t *testing.T) {
+	t.Parallel()
+	server, err := NewMailServer(&Directive{Name: "server", Block: &Block{Directives: []IDirective{
+		&Directive{Name: "listen", Parameters: []Parameter{{Value: "25"}}},
+		&Directive{Name: "protocol", Parameters: []Parameter{{Value: "smtp"}}},
+		&Directive{Name: "smtp_auth", Parameters: []Parameter{{Value: "LOGIN"}, {Value: "cram-md5"}}},
+	}}})
+	assert.NilError(t, err)
+	mail, err := NewMail(&Directive{Name: "mail", Block: &Block{Directives: []IDirective{
+		&Directive{Name: "auth_http", Parameters: []Parameter{{Value: "localhost:9000/auth"}}},
+		&Directive{Name: "starttls", Parameters: []Parameter{{Value: "only"}}},
+	}}})
+	assert.NilError(t, err)
+	mail.AddServer(server)
+
+	assert.Equal(t, len(mail.Servers()), 1)
+	assert.Equal(t, server.Protocol(), MailProtocolSMTP)
+	assert.DeepEqual(t, server.SMTPAuth(), []SMTPAuthMethod{SMTPAuthLogin, SMTPAuthCRAMMD5})
+	assert.DeepEqual(t, mail.SMTPAuth(), []SMTPAuthMethod{SMTPAuthLogin, SMTPAuthPlain})
+	// inherited from the mail block
+	assert.Equal(t, server.AuthHTTP(), "localhost:9000/auth")
+	assert.Equal(t, server.StartTLS(), StartTLSOnly)
+	assert.Assert(t, !server.ProxyPassErrorMessage())
+
+	assert.NilError(t, server.SetStartTLS(StartTLSOn))
+	assert.Equal(t, server.StartTLS(), StartTLSOn)
+	assert.Equal(t, mail.StartTLS(), StartTLSOnly)
+	assert.ErrorContains(t, server.SetStartTLS("always"), `invalid value "always" in "starttls" directive`)
+	assert.ErrorContains(t, server.SetProtocol("http"), `unknown protocol "http"`)
+	assert.ErrorContains(t, server.SetSMTPAuth("xoauth"), `invalid value "xoauth" in "smtp_auth" directive`)
+	assert.NilError(t, server.SetSMTPAuth(SMTPAuthNone))
+	server.SetAuthHTTP("auth.internal:9000")
+	mail.SetProxyPassErrorMessage(true)
+	assert.Assert(t, server.ProxyPassErrorMessage())
+
+	server.SetListens(Listen{Address: "587", ProxyProtocol: true})
+	listens, err := server.Listens()
+	assert.NilError(t, err)
+	assert.Equal(t, listens[0].Port(), "587")
+	assert.Assert(t, listens[0].ProxyProtocol)
+
+	names := make([]string, 0)
+	for _, d := range server.GetDirectives() {
+		names = append(names, d.GetName())
+	}
+	assert.DeepEqual(t, names, []string{"listen", "protocol", "smtp_auth", "starttls", "auth_http"})
+	assert.Equal(t, server.FindDirectives("smtp_auth")[0].GetParameters()[0].Value, "none")
+}
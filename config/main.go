@@ -0,0 +1,318 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WorkerCount is a number of worker processes, WorkerCountAuto being one per CPU core
+type WorkerCount int
+
+// WorkerCountAuto is the auto value of worker_processes
+const WorkerCountAuto WorkerCount = -1
+
+// ParseWorkerCount parses a number of worker processes or auto
+func ParseWorkerCount(s string) (WorkerCount, error) {
+	if s == "auto" {
+		return WorkerCountAuto, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of worker processes %s", s)
+	}
+	return WorkerCount(n), nil
+}
+
+// String returns the count as written in worker_processes
+func (w WorkerCount) String() string {
+	if w == WorkerCountAuto {
+		return "auto"
+	}
+	return strconv.Itoa(int(w))
+}
+
+// ErrorLog is an error_log directive: the file, or a syslog: or memory: target, and the minimal level
+type ErrorLog struct {
+	File  string
+	Level string // empty for the default level, error
+}
+
+// Main is the main context of a config, its top level directives. The typed accessors only change
+// the directives they are about, the other directives keep their order
+type Main struct {
+	*Config
+}
+
+// NewMain returns the main context of c.
+func NewMain(c *Config) *Main {
+	if c.Block == nil {
+		c.Block = &Block{}
+	}
+	return &Main{Config: c}
+}
+
+// User returns the user and group of the worker processes, empty when user is not set.
+func (m *Main) User() (user, group string) {
+	parameters, _ := directiveParameters(m.Block, "user")
+	if len(parameters) > 0 {
+		user = parameters[0].Unquoted()
+	}
+	if len(parameters) > 1 {
+		group = parameters[1].Unquoted()
+	}
+	return user, group
+}
+
+// SetUser sets the user and group of the worker processes, an empty group being the group named as the user.
+func (m *Main) SetUser(user, group string) {
+	parameters := []Parameter{NewParameter(user)}
+	if group != "" {
+		parameters = append(parameters, NewParameter(group))
+	}
+	m.set("user", parameters...)
+}
+
+// WorkerProcesses returns the number of worker processes, 1 when worker_processes is not set.
+func (m *Main) WorkerProcesses() (WorkerCount, error) {
+	parameters, _ := directiveParameters(m.Block, "worker_processes")
+	if len(parameters) == 0 {
+		return 1, nil
+	}
+	return ParseWorkerCount(parameters[0].Unquoted())
+}
+
+// SetWorkerProcesses sets the number of worker processes, WorkerCountAuto for auto.
+func (m *Main) SetWorkerProcesses(count WorkerCount) error {
+	if count != WorkerCountAuto && count <= 0 {
+		return fmt.Errorf("invalid number of worker processes %d", count)
+	}
+	m.set("worker_processes", Parameter{Value: count.String()})
+	return nil
+}
+
+// WorkerRlimitNofile returns the limit of open files of the worker processes, 0 when it is not set.
+func (m *Main) WorkerRlimitNofile() (int, error) {
+	parameters, _ := directiveParameters(m.Block, "worker_rlimit_nofile")
+	if len(parameters) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(parameters[0].Unquoted())
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid worker_rlimit_nofile %s", parameters[0].Value)
+	}
+	return n, nil
+}
+
+// SetWorkerRlimitNofile sets the limit of open files of the worker processes.
+func (m *Main) SetWorkerRlimitNofile(n int) error {
+	if n < 0 {
+		return fmt.Errorf("invalid worker_rlimit_nofile %d", n)
+	}
+	m.set("worker_rlimit_nofile", Parameter{Value: strconv.Itoa(n)})
+	return nil
+}
+
+// PID returns the file of the process ID of the master process, empty when pid is not set.
+func (m *Main) PID() string {
+	if parameters, _ := directiveParameters(m.Block, "pid"); len(parameters) > 0 {
+		return parameters[0].Unquoted()
+	}
+	return ""
+}
+
+// SetPID sets the file of the process ID of the master process.
+func (m *Main) SetPID(file string) {
+	m.set("pid", NewParameter(file))
+}
+
+// ErrorLogs returns the error_log directives of the main context.
+func (m *Main) ErrorLogs() []ErrorLog {
+	logs := make([]ErrorLog, 0)
+	for _, d := range m.GetDirectives() {
+		if d.GetName() != "error_log" || len(d.GetParameters()) == 0 {
+			continue
+		}
+		log := ErrorLog{File: d.GetParameters()[0].Unquoted()}
+		if len(d.GetParameters()) > 1 {
+			log.Level = d.GetParameters()[1].Unquoted()
+		}
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+// SetErrorLogs replaces the error_log directives of the main context, the new ones taking the place
+// of the first one and the comments of the replaced ones by position.
+func (m *Main) SetErrorLogs(logs ...ErrorLog) {
+	directives := make([]*Directive, 0, len(logs))
+	for _, log := range logs {
+		d := &Directive{Name: "error_log", Parameters: []Parameter{NewParameter(log.File)}}
+		if log.Level != "" {
+			d.Parameters = append(d.Parameters, Parameter{Value: log.Level})
+		}
+		directives = append(directives, d)
+	}
+	m.Block = replaceDirectives(m.Block, nil, "error_log", directives).(*Block)
+}
+
+// LoadModules returns the paths of the dynamic modules loaded by load_module.
+func (m *Main) LoadModules() []string {
+	return m.values("load_module")
+}
+
+// AddLoadModule loads a dynamic module, after the other load_module directives or at the top
+// of the config, as modules must be loaded before their directives. It does nothing when the module is loaded
+func (m *Main) AddLoadModule(path string) {
+	m.add("load_module", path, 0)
+}
+
+// RemoveLoadModule removes the load_module directives of path, it returns false when there is none.
+func (m *Main) RemoveLoadModule(path string) bool {
+	return m.remove("load_module", func(value string) bool { return value == path })
+}
+
+// Env returns the environment variables kept or set by env, as written: NAME or NAME=value.
+func (m *Main) Env() []string {
+	return m.values("env")
+}
+
+// SetEnv keeps the environment variable name for the worker processes, setting it to value when
+// it is not empty. An existing env directive of the variable is updated, a new one follows the other env directives
+func (m *Main) SetEnv(name, value string) {
+	v := name
+	if value != "" {
+		v += "=" + value
+	}
+	for _, d := range m.GetDirectives() {
+		if dir, ok := d.(*Directive); ok && dir.Name == "env" && len(dir.Parameters) > 0 && envName(dir.Parameters[0].Unquoted()) == name {
+			dir.Parameters = []Parameter{NewParameter(v)}
+			return
+		}
+	}
+	m.add("env", v, m.firstBlock())
+}
+
+// RemoveEnv removes the env directives of the variable name, it returns false when there is none.
+func (m *Main) RemoveEnv(name string) bool {
+	return m.remove("env", func(value string) bool { return envName(value) == name })
+}
+
+// Includes returns the include directives of the main context.
+func (m *Main) Includes() []*Include {
+	includes := make([]*Include, 0)
+	for _, d := range m.GetDirectives() {
+		if include, ok := d.(*Include); ok {
+			includes = append(includes, include)
+		}
+	}
+	return includes
+}
+
+// AddInclude appends an include directive to the main context, its files are not parsed.
+func (m *Main) AddInclude(path string) *Include {
+	include := &Include{
+		Directive:   &Directive{Name: "include", Parameters: []Parameter{NewParameter(path)}},
+		IncludePath: path,
+	}
+	m.Block = appendDirective(m.Block, include).(*Block)
+	return include
+}
+
+// Events returns the events block, nil when there is none.
+func (m *Main) Events() *Events {
+	for _, d := range m.GetDirectives() {
+		if events, ok := d.(*Events); ok {
+			return events
+		}
+	}
+	return nil
+}
+
+// SetEvents replaces the events block, or adds it before the other blocks.
+func (m *Main) SetEvents(events *Events) {
+	events.SetParent(nil)
+	directives := append([]IDirective{}, m.GetDirectives()...)
+	for i, d := range directives {
+		if d.GetName() == "events" {
+			directives[i] = events
+			m.Directives = directives
+			return
+		}
+	}
+	at := m.firstBlock()
+	m.Directives = append(directives[:at], append([]IDirective{events}, directives[at:]...)...)
+}
+
+// firstBlock returns the index of the first directive with a block, or of the comment ending the config
+func (m *Main) firstBlock() int {
+	directives := m.GetDirectives()
+	for i, d := range directives {
+		if d.GetBlock() != nil {
+			return i
+		}
+	}
+	others, _ := splitTrailingComment(directives)
+	return len(others)
+}
+
+// set sets the parameters of the first directive named name, adding it before the first block when missing
+func (m *Main) set(name string, parameters ...Parameter) {
+	if _, ok := directiveParameters(m.Block, name); ok {
+		m.Block = setDirective(m.Block, nil, name, parameters...).(*Block)
+		return
+	}
+	m.insert(m.firstBlock(), &Directive{Name: name, Parameters: parameters})
+}
+
+// add adds a directive with the parameter value after the last one named name, or at index at, unless one has it already
+func (m *Main) add(name, value string, at int) {
+	for i, d := range m.GetDirectives() {
+		if d.GetName() != name {
+			continue
+		}
+		if len(d.GetParameters()) > 0 && d.GetParameters()[0].Unquoted() == value {
+			return
+		}
+		at = i + 1
+	}
+	m.insert(at, &Directive{Name: name, Parameters: []Parameter{NewParameter(value)}})
+}
+
+func (m *Main) insert(at int, directive IDirective) {
+	directives := m.GetDirectives()
+	updated := make([]IDirective, 0, len(directives)+1)
+	updated = append(updated, directives[:at]...)
+	updated = append(updated, directive)
+	m.Directives = append(updated, directives[at:]...)
+}
+
+// values returns the first parameter of the directives named name
+func (m *Main) values(name string) []string {
+	values := make([]string, 0)
+	for _, d := range m.GetDirectives() {
+		if d.GetName() == name && len(d.GetParameters()) > 0 {
+			values = append(values, d.GetParameters()[0].Unquoted())
+		}
+	}
+	return values
+}
+
+// remove removes the directives named name whose first parameter matches
+func (m *Main) remove(name string, match func(value string) bool) bool {
+	directives := make([]IDirective, 0, len(m.GetDirectives()))
+	for _, d := range m.GetDirectives() {
+		if d.GetName() == name && len(d.GetParameters()) > 0 && match(d.GetParameters()[0].Unquoted()) {
+			continue
+		}
+		directives = append(directives, d)
+	}
+	removed := len(directives) != len(m.GetDirectives())
+	m.Directives = directives
+	return removed
+}
+
+func envName(value string) string {
+	name, _, _ := strings.Cut(value, "=")
+	return name
+}
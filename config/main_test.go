@@ -0,0 +1,122 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseWorkerCount(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value string
+		want  WorkerCount
+		err   string
+	}{
+		{value: "auto", want: WorkerCountAuto},
+		{value: "4", want: 4},
+		{value: "0", err: "invalid number of worker processes 0"},
+		{value: "many", err: "invalid number of worker processes many"},
+	}
+	for _, tt := range tests {
+		got, err := ParseWorkerCount(tt.value)
+		if tt.err != "" {
+			assert.Error(t, err, tt.err)
+			continue
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, got, tt.want)
+		assert.Equal(t, got.String(), tt.value)
+	}
+}
+
+func TestMain_Directives(t *testing.T) {
+	t.Parallel()
+	events, err := NewEvents(&Directive{Name: "events", Block: &Block{}})
+	assert.NilError(t, err)
+	m := NewMain(&Config{Block: &Block{Directives: []IDirective{
+		&Directive{Name: "load_module", Parameters: []Parameter{{Value: "modules/ngx_stream_module.so"}}},
+		&Directive{Name: "user", Parameters: []Parameter{{Value: "www-data"}}},
+		&Directive{Name: "daemon", Parameters: []Parameter{{Value: "off"}}},
+		&Directive{Name: "error_log", Parameters: []Parameter{{Value: "/var/log/nginx/error.log"}, {Value: "warn"}}, Comment: []string{"# logs"}},
+		&Directive{Name: "env", Parameters: []Parameter{{Value: "TZ"}}},
+		events,
+		&Directive{Name: "http", Block: &Block{}},
+		&Comment{Comment: []string{"# end"}},
+	}}})
+
+	user, group := m.User()
+	assert.Equal(t, user, "www-data")
+	assert.Equal(t, group, "")
+	workers, err := m.WorkerProcesses()
+	assert.NilError(t, err)
+	assert.Equal(t, workers, WorkerCount(1))
+	nofile, err := m.WorkerRlimitNofile()
+	assert.NilError(t, err)
+	assert.Equal(t, nofile, 0)
+	assert.Equal(t, m.PID(), "")
+	assert.DeepEqual(t, m.ErrorLogs(), []ErrorLog{{File: "/var/log/nginx/error.log", Level: "warn"}})
+	assert.DeepEqual(t, m.LoadModules(), []string{"modules/ngx_stream_module.so"})
+	assert.DeepEqual(t, m.Env(), []string{"TZ"})
+	assert.Equal(t, m.Events(), events)
+
+	m.SetUser("nginx", "nginx")
+	assert.NilError(t, m.SetWorkerProcesses(WorkerCountAuto))
+	assert.Error(t, m.SetWorkerProcesses(0), "invalid number of worker processes 0")
+	assert.NilError(t, m.SetWorkerRlimitNofile(65535))
+	m.SetPID("/run/nginx.pid")
+	m.SetErrorLogs(ErrorLog{File: "stderr"}, ErrorLog{File: "syslog:server=unix:/dev/log", Level: "error"})
+	m.AddLoadModule("modules/ngx_mail_module.so")
+	m.AddLoadModule("modules/ngx_stream_module.so")
+	m.SetEnv("TZ", "UTC")
+	m.SetEnv("PERL5LIB", "")
+
+	names := make([]string, 0)
+	for _, d := range m.GetDirectives() {
+		names = append(names, d.GetName())
+	}
+	assert.DeepEqual(t, names, []string{
+		"load_module", "load_module", "user", "daemon", "error_log", "error_log", "env", "env",
+		"worker_processes", "worker_rlimit_nofile", "pid", "events", "http", "",
+	})
+
+	user, group = m.User()
+	assert.Equal(t, user+":"+group, "nginx:nginx")
+	workers, err = m.WorkerProcesses()
+	assert.NilError(t, err)
+	assert.Equal(t, workers, WorkerCountAuto)
+	nofile, err = m.WorkerRlimitNofile()
+	assert.NilError(t, err)
+	assert.Equal(t, nofile, 65535)
+	assert.Equal(t, m.PID(), "/run/nginx.pid")
+	assert.Equal(t, len(m.ErrorLogs()), 2)
+	assert.DeepEqual(t, m.FindDirectives("error_log")[0].GetComment(), []string{"# logs"})
+	assert.DeepEqual(t, m.LoadModules(), []string{"modules/ngx_stream_module.so", "modules/ngx_mail_module.so"})
+	assert.DeepEqual(t, m.Env(), []string{"TZ=UTC", "PERL5LIB"})
+
+	assert.Assert(t, m.RemoveLoadModule("modules/ngx_stream_module.so"))
+	assert.Assert(t, !m.RemoveLoadModule("modules/ngx_stream_module.so"))
+	assert.Assert(t, m.RemoveEnv("TZ"))
+	assert.DeepEqual(t, m.Env(), []string{"PERL5LIB"})
+}
+
+func TestMain_SetEvents(t *testing.T) {
+	t.Parallel()
+	m := NewMain(&Config{})
+	assert.Assert(t, m.Events() == nil)
+
+	m.SetPID("nginx.pid")
+	m.AddInclude("modules.d/*.conf")
+	events, err := NewEvents(&Directive{Name: "events", Block: &Block{}})
+	assert.NilError(t, err)
+	m.SetEvents(events)
+	assert.Equal(t, m.Events(), events)
+	assert.Equal(t, len(m.Includes()), 1)
+	assert.Equal(t, m.Includes()[0].IncludePath, "modules.d/*.conf")
+
+	replaced, err := NewEvents(&Directive{Name: "events", Block: &Block{}})
+	assert.NilError(t, err)
+	m.SetEvents(replaced)
+	assert.Equal(t, m.Events(), replaced)
+	assert.Equal(t, len(m.FindDirectives("events")), 1)
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MapKeyKind is the kind of a map key, which tells how it matches the source value
+type MapKeyKind int
+
+// The kinds of map keys. Wildcards are only read in maps with hostnames
+const (
+	MapKeyExact            MapKeyKind = iota // matches the value, ignoring case
+	MapKeyWildcardPrefix                     // *.example.com or .example.com, the latter also matching example.com
+	MapKeyWildcardSuffix                     // www.example.*
+	MapKeyRegex                              // ~ followed by a case sensitive regex
+	MapKeyRegexInsensitive                   // ~* followed by a case insensitive regex
+)
+
+// MapEntry is a key of a map block with its result
+type MapEntry struct {
+	Key   string // the key as nginx reads it, unquoted and without the backslash escaping a special key
+	Value string // the result, which may reference the captures of a regex key
+	Kind  MapKeyKind
+
+	regex *regexp.Regexp
+}
+
+// Map represents a map block, which sets a variable from a source value
+type Map struct {
+	*Directive
+	Source    string // the source expression, e.g. $uri
+	Variable  string // the result variable, e.g. $new_uri
+	Default   string // the result when no key matches, empty by default
+	Hostnames bool
+	Volatile  bool
+	Includes  []IDirective // the include directives of the block, their parsed entries are part of Entries
+	Entries   []MapEntry   // the entries in config order
+	Parent    IDirective
+	Line      int
+
+	keys map[string]int // index of the entries by lowercase key, wildcards starting with a dot
+}
+
+// SetLine sets the line number.
+func (m *Map) SetLine(line int) {
+	m.Line = line
+}
+
+// GetLine returns the line number.
+func (m *Map) GetLine() int {
+	return m.Line
+}
+
+// SetParent sets the parent directive.
+func (m *Map) SetParent(parent IDirective) {
+	m.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (m *Map) GetParent() IDirective {
+	return m.Parent
+}
+
+// NewMap initializes a Map from a map directive, reading its entries and those of the files it includes.
+// Regex keys are compiled with the syntax of Go, which lacks some PCRE features
+func NewMap(directive IDirective) (*Map, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("map directive type error")
+	}
+	if len(dir.Parameters) != 2 {
+		return nil, errors.New("map directive requires a source and a variable")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("map directive must have a block")
+	}
+	m := &Map{
+		Directive: dir,
+		Source:    dir.Parameters[0].Unquoted(),
+		Variable:  dir.Parameters[1].Unquoted(),
+		Includes:  []IDirective{},
+		Entries:   []MapEntry{},
+		keys:      map[string]int{},
+	}
+	if err := m.read(dir.Block.GetDirectives()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// read adds the entries and the parameters of directives to the map
+func (m *Map) read(directives []IDirective) error {
+	for _, d := range directives {
+		if d.GetName() == "" { // comments
+			continue
+		}
+		params := d.GetParameters()
+		switch d.GetName() {
+		case "hostnames", "volatile":
+			if len(params) == 0 {
+				m.Hostnames = m.Hostnames || d.GetName() == "hostnames"
+				m.Volatile = m.Volatile || d.GetName() == "volatile"
+				continue
+			}
+		case "include":
+			if len(params) == 1 {
+				m.Includes = append(m.Includes, d)
+				if include, ok := d.(*Include); ok {
+					for _, c := range include.Configs {
+						if err := m.read(c.GetDirectives()); err != nil {
+							return err
+						}
+					}
+				}
+				continue
+			}
+		}
+		if len(params) != 1 {
+			return fmt.Errorf("invalid number of the map parameters for %s", d.GetName())
+		}
+
+		key := (&Parameter{Value: d.GetName()}).Unquoted()
+		value := params[0].Unquoted()
+		if d.GetName() == "default" {
+			m.Default = value
+			continue
+		}
+		if err := m.add(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds an entry to the map
+func (m *Map) add(key, value string) error {
+	entry := MapEntry{Key: key, Value: value, Kind: MapKeyExact}
+	switch {
+	case strings.HasPrefix(key, "\\"):
+		entry.Key = key[1:]
+	case strings.HasPrefix(key, "~*"), strings.HasPrefix(key, "~"):
+		entry.Kind = MapKeyRegex
+		expr := key[1:]
+		if strings.HasPrefix(key, "~*") {
+			entry.Kind = MapKeyRegexInsensitive
+			expr = "(?i)" + key[2:]
+		}
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("map regex %s: %w", key, err)
+		}
+		entry.regex = regex
+	case m.Hostnames && (strings.HasPrefix(key, "*.") || strings.HasPrefix(key, ".")):
+		entry.Kind = MapKeyWildcardPrefix
+	case m.Hostnames && strings.HasSuffix(key, ".*"):
+		entry.Kind = MapKeyWildcardSuffix
+	}
+
+	if entry.Kind != MapKeyRegex && entry.Kind != MapKeyRegexInsensitive {
+		lower := strings.ToLower(entry.Key)
+		if entry.Kind == MapKeyWildcardPrefix {
+			lower = "." + strings.TrimPrefix(strings.TrimPrefix(lower, "*"), ".")
+		}
+		if _, ok := m.keys[lower]; ok {
+			return fmt.Errorf("conflicting map parameter %s", key)
+		}
+		m.keys[lower] = len(m.Entries)
+	}
+	m.Entries = append(m.Entries, entry)
+	return nil
+}
+
+// Lookup returns the result of the map for value, following the precedence of nginx:
+// the exact key, the longest wildcard starting with *, the longest wildcard ending with *,
+// then the first matching regex, whose captures ($1, $name) are replaced in the result.
+// Without a match, it returns the default result and false
+func (m *Map) Lookup(value string) (result string, matched bool) {
+	if m.Hostnames {
+		value = strings.TrimSuffix(value, ".")
+	}
+	lower := strings.ToLower(value)
+	if i, ok := m.keys[lower]; ok && m.Entries[i].Kind == MapKeyExact {
+		return m.Entries[i].Value, true
+	}
+
+	var prefix, suffix *MapEntry
+	for i := range m.Entries {
+		entry := &m.Entries[i]
+		key := strings.ToLower(entry.Key)
+		switch entry.Kind {
+		case MapKeyExact:
+			// maps built without NewMap have no index
+			if m.keys == nil && key == lower {
+				return entry.Value, true
+			}
+		case MapKeyWildcardPrefix:
+			domain := strings.TrimPrefix(key, "*")
+			if strings.HasSuffix(lower, domain) && len(lower) > len(domain) ||
+				strings.HasPrefix(key, ".") && lower == key[1:] {
+				if prefix == nil || len(domain) > len(strings.TrimPrefix(prefix.Key, "*")) {
+					prefix = entry
+				}
+			}
+		case MapKeyWildcardSuffix:
+			start := strings.TrimSuffix(key, "*")
+			if strings.HasPrefix(lower, start) && len(lower) > len(start) {
+				if suffix == nil || len(start) > len(suffix.Key)-1 {
+					suffix = entry
+				}
+			}
+		}
+	}
+	if prefix != nil {
+		return prefix.Value, true
+	}
+	if suffix != nil {
+		return suffix.Value, true
+	}
+
+	for _, entry := range m.Entries {
+		if entry.regex == nil {
+			continue
+		}
+		if match := entry.regex.FindStringSubmatchIndex(value); match != nil {
+			return expandCaptures(entry.Value, entry.regex, value, match), true
+		}
+	}
+	return m.Default, false
+}
+
+// expandCaptures replaces $1 to $9 and the named captures of regex in result by what they matched
+func expandCaptures(result string, regex *regexp.Regexp, value string, match []int) string {
+	group := func(i int) string {
+		if 2*i+1 >= len(match) || match[2*i] < 0 {
+			return ""
+		}
+		return value[match[2*i]:match[2*i+1]]
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(result); i++ {
+		if result[i] != '$' || i+1 >= len(result) {
+			buf.WriteByte(result[i])
+			continue
+		}
+		rest := result[i+1:]
+		name, length := "", 0
+		switch {
+		case rest[0] >= '1' && rest[0] <= '9':
+			buf.WriteString(group(int(rest[0] - '0')))
+			i++
+			continue
+		case rest[0] == '{':
+			if end := strings.IndexByte(rest, '}'); end > 1 {
+				name, length = rest[1:end], end+1
+			}
+		default:
+			for length < len(rest) && isVariableChar(rest[length]) {
+				length++
+			}
+			name = rest[:length]
+		}
+		if index := regex.SubexpIndex(name); name != "" && index > 0 {
+			buf.WriteString(group(index))
+			i += length
+			continue
+		}
+		// other variables are kept for nginx to expand
+		buf.WriteByte('$')
+	}
+	return buf.String()
+}
+
+// FindDirectives finds directives by name.
+func (m *Map) FindDirectives(directiveName string) []IDirective {
+	return m.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the map block.
+func (m *Map) GetDirectives() []IDirective {
+	return m.GetBlock().GetDirectives()
+}
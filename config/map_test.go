@@ -0,0 +1,108 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func mapDirective(entries ...[2]string) *Directive {
+	block := &Block{}
+	for _, e := range entries {
+		d := &Directive{Name: e[0]}
+		if e[1] != "" {
+			d.Parameters = []Parameter{{Value: e[1]}}
+		}
+		block.Directives = append(block.Directives, d)
+	}
+	return &Directive{Name: "map", Parameters: []Parameter{{Value: "$host"}, {Value: "$name"}}, Block: block}
+}
+
+func TestNewMap(t *testing.T) {
+	t.Parallel()
+	m, err := NewMap(mapDirective(
+		[2]string{"hostnames", ""},
+		[2]string{"volatile", ""},
+		[2]string{"default", "0"},
+		[2]string{"example.com", "1"},
+		[2]string{"*.example.com", "2"},
+		[2]string{"www.example.*", "3"},
+		[2]string{`"~^(?<user>[a-z]+)\.example\.org$"`, "$user"},
+		[2]string{`\default`, "4"},
+	))
+	assert.NilError(t, err)
+	assert.Equal(t, m.Source, "$host")
+	assert.Equal(t, m.Variable, "$name")
+	assert.Equal(t, m.Default, "0")
+	assert.Assert(t, m.Hostnames)
+	assert.Assert(t, m.Volatile)
+
+	kinds := []MapKeyKind{}
+	keys := []string{}
+	for _, e := range m.Entries {
+		kinds = append(kinds, e.Kind)
+		keys = append(keys, e.Key)
+	}
+	assert.DeepEqual(t, kinds, []MapKeyKind{MapKeyExact, MapKeyWildcardPrefix, MapKeyWildcardSuffix, MapKeyRegex, MapKeyExact})
+	assert.DeepEqual(t, keys, []string{"example.com", "*.example.com", "www.example.*", `~^(?<user>[a-z]+)\.example\.org$`, "default"})
+}
+
+func TestNewMap_Errors(t *testing.T) {
+	t.Parallel()
+	_, err := NewMap(mapDirective([2]string{"/a", "1"}, [2]string{"/A", "2"}))
+	assert.Error(t, err, "conflicting map parameter /A")
+	_, err = NewMap(mapDirective([2]string{"/a", ""}))
+	assert.Error(t, err, "invalid number of the map parameters for /a")
+	_, err = NewMap(mapDirective([2]string{"~(?<=a)b", "1"}))
+	assert.ErrorContains(t, err, "map regex ~(?<=a)b")
+	_, err = NewMap(&Directive{Name: "map", Parameters: []Parameter{{Value: "$a"}}, Block: &Block{}})
+	assert.Error(t, err, "map directive requires a source and a variable")
+}
+
+func TestMap_Lookup(t *testing.T) {
+	t.Parallel()
+	m, err := NewMap(mapDirective(
+		[2]string{"hostnames", ""},
+		[2]string{"default", "none"},
+		[2]string{"~^www\\.", "regex"},
+		[2]string{"www.example.com", "exact"},
+		[2]string{".example.com", "dot"},
+		[2]string{"*.api.example.com", "api"},
+		[2]string{"www.*", "www"},
+		[2]string{"www.example.*", "www-example"},
+		[2]string{"~*^(?<sub>[a-z]+)\\.(test)$", "$sub-$2-${sub}-$host"},
+	))
+	assert.NilError(t, err)
+
+	tests := []struct {
+		value   string
+		result  string
+		matched bool
+	}{
+		{"www.example.com", "exact", true},
+		{"WWW.Example.COM.", "exact", true},
+		{"example.com", "dot", true},
+		{"a.example.com", "dot", true},
+		{"v1.api.example.com", "api", true},
+		{"www.example.org", "www-example", true},
+		{"www.other.org", "www", true},
+		{"Shop.TEST", "Shop-TEST-Shop-$host", true},
+		{"other.org", "none", false},
+	}
+	for _, tt := range tests {
+		result, matched := m.Lookup(tt.value)
+		assert.Equal(t, result, tt.result, tt.value)
+		assert.Equal(t, matched, tt.matched, tt.value)
+	}
+
+	// without hostnames, wildcards are exact keys and regexes come in order
+	m, err = NewMap(mapDirective([2]string{"*.example.com", "star"}, [2]string{"~^/a", "a"}, [2]string{"~^/", "root"}))
+	assert.NilError(t, err)
+	result, matched := m.Lookup("*.example.com")
+	assert.Equal(t, result, "star")
+	assert.Assert(t, matched)
+	result, _ = m.Lookup("www.example.com")
+	assert.Equal(t, result, "")
+	result, _ = m.Lookup("/ab")
+	assert.Equal(t, result, "a")
+}
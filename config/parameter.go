@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tufanbarisyildirim/gonginx/config/value"
+)
+
+// NewParameter creates a parameter holding a logical value: the value is written bare when it
+// reads back as is, otherwise it is double quoted with its quotes, backslashes and control
+// characters escaped, so that nginx reads back exactly v. Variables ($name) are still expanded by nginx
+func NewParameter(v string) Parameter {
+	if v != "" && !strings.ContainsAny(v, " \t\r\n;{}#\"'`\\") {
+		return Parameter{Value: v}
+	}
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for i := 0; i < len(v); i++ {
+		switch ch := v[i]; ch {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(ch)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	buf.WriteByte('"')
+	return Parameter{Value: buf.String(), Quote: QuoteDouble}
+}
+
+// SetUnquoted sets the logical value of the parameter, quoting and escaping it as NewParameter does
+func (p *Parameter) SetUnquoted(v string) {
+	np := NewParameter(v)
+	p.Value, p.Quote, p.Regex = np.Value, np.Quote, false
+}
+
+// Unquoted returns the logical value of the parameter as nginx reads it: without its quotes,
+// with \", \', \\ unescaped and \t, \r, \n turned into tab, carriage return and line feed.
+// Other escapes, such as \. in a regex, are kept as is
+func (p *Parameter) Unquoted() string {
+	v := p.Value
+	quote := QuoteOf(v)
+	if quote != QuoteNone {
+		v = v[1 : len(v)-1]
+	}
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			switch v[i+1] {
+			case '"', '\'', '\\':
+				i++
+			case '`':
+				if quote == QuoteBacktick {
+					i++
+				}
+			case 't':
+				buf.WriteByte('\t')
+				i++
+				continue
+			case 'r':
+				buf.WriteByte('\r')
+				i++
+				continue
+			case 'n':
+				buf.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		buf.WriteByte(v[i])
+	}
+	return buf.String()
+}
+
+// Validate checks that the raw value of the parameter is read back as this single parameter,
+// a value such as `a; evil on` or `"a" b` would otherwise change the config when dumped
+func (p *Parameter) Validate() error {
+	v := p.Value
+	if v == "" {
+		return errors.New("empty parameter must be quoted")
+	}
+
+	switch v[0] {
+	case '"', '\'', '`':
+		for i := 1; i < len(v); i++ {
+			if v[i] == '\\' {
+				i++
+			} else if v[i] == v[0] {
+				if i != len(v)-1 {
+					return fmt.Errorf("parameter %s continues after its closing quote", v)
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("parameter %s has no closing quote", v)
+	case '#':
+		return fmt.Errorf("parameter %s would start a comment", v)
+	case ';', '{', '}', ' ', '\t', '\r', '\n':
+		return fmt.Errorf("parameter %q must be quoted", v)
+	}
+
+	// a bare parameter ends at a space, a semicolon, a line end or a brace
+	// that is not part of a ${variable}
+	prev := v[0]
+	inVarRef := false
+	for i := 1; i < len(v); i++ {
+		switch ch := v[i]; {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' || ch == ';':
+			return fmt.Errorf("parameter %q must be quoted", v)
+		case ch == '{':
+			if prev == '$' {
+				inVarRef = true
+			} else if !inVarRef {
+				return fmt.Errorf("parameter %q must be quoted", v)
+			}
+		case ch == '}':
+			if !inVarRef {
+				return fmt.Errorf("parameter %q must be quoted", v)
+			}
+			inVarRef = false
+		default:
+			prev = ch
+		}
+	}
+
+	// nginx reads a backslash as escaping the next character, the ; ending the directive included
+	trailing := len(v) - len(strings.TrimRight(v, `\`))
+	if trailing%2 == 1 {
+		return fmt.Errorf("parameter %q ends with an unescaped backslash", v)
+	}
+	return nil
+}
+
+// AsBytes returns the parameter as an nginx size in bytes, such as 10m, see value.ParseSize
+func (p *Parameter) AsBytes() (int64, error) {
+	return value.ParseSize(p.Unquoted())
+}
+
+// SetBytes sets the parameter to a size in bytes, with the largest exact k, m or g suffix
+func (p *Parameter) SetBytes(n int64) {
+	p.SetUnquoted(value.FormatSize(n))
+}
+
+// AsOffset returns the parameter as an nginx offset in bytes, see value.ParseOffset
+func (p *Parameter) AsOffset() (int64, error) {
+	return value.ParseOffset(p.Unquoted())
+}
+
+// SetOffset sets the parameter to an offset in bytes
+func (p *Parameter) SetOffset(n int64) {
+	p.SetUnquoted(value.FormatOffset(n))
+}
+
+// AsDuration returns the parameter as an nginx time with millisecond precision, such as 1h30s
+// for proxy_read_timeout, see value.ParseDuration
+func (p *Parameter) AsDuration() (time.Duration, error) {
+	return value.ParseDuration(p.Unquoted())
+}
+
+// AsSeconds returns the parameter as an nginx time with second precision, such as 1M
+// for expires, see value.ParseSeconds
+func (p *Parameter) AsSeconds() (time.Duration, error) {
+	return value.ParseSeconds(p.Unquoted())
+}
+
+// SetDuration sets the parameter to a time, such as 1h30s, truncated to milliseconds
+func (p *Parameter) SetDuration(d time.Duration) {
+	p.SetUnquoted(value.FormatDuration(d))
+}
+
+// AsBool returns the parameter as an nginx flag, on or off
+func (p *Parameter) AsBool() (bool, error) {
+	return value.ParseFlag(p.Unquoted())
+}
+
+// SetBool sets the parameter to on or off
+func (p *Parameter) SetBool(b bool) {
+	p.SetUnquoted(value.FormatFlag(b))
+}
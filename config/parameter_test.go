@@ -0,0 +1,145 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNewParameter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "example.com", want: "example.com"},
+		{value: "$host", want: "$host"},
+		{value: "", want: `""`},
+		{value: "a b", want: `"a b"`},
+		{value: "a; evil on", want: `"a; evil on"`},
+		{value: `say "hi"`, want: `"say \"hi\""`},
+		{value: `C:\`, want: `"C:\\"`},
+		{value: "it's", want: `"it's"`},
+		{value: "a\tb\n", want: `"a\tb\n"`},
+		{value: "#x", want: `"#x"`},
+		{value: "}", want: `"}"`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+			p := NewParameter(tt.value)
+			assert.Equal(t, p.Value, tt.want)
+			assert.Equal(t, p.Quote, QuoteOf(tt.want))
+			assert.NilError(t, p.Validate())
+			assert.Equal(t, p.Unquoted(), tt.value)
+		})
+	}
+}
+
+func TestParameter_Unquoted(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, (&Parameter{Value: `'a\'b'`}).Unquoted(), `a'b`)
+	assert.Equal(t, (&Parameter{Value: "`a\\`b`"}).Unquoted(), "a`b")
+	assert.Equal(t, (&Parameter{Value: "\"a\\`b\""}).Unquoted(), "a\\`b")
+	assert.Equal(t, (&Parameter{Value: `^/a\.b$`}).Unquoted(), `^/a\.b$`)
+	assert.Equal(t, (&Parameter{Value: `a\\b`}).Unquoted(), `a\b`)
+}
+
+func TestParameter_SetUnquoted(t *testing.T) {
+	t.Parallel()
+	p := Parameter{Value: "~^/a", RelativeLineIndex: 1, Regex: true}
+	p.SetUnquoted("a b")
+	assert.DeepEqual(t, p, Parameter{Value: `"a b"`, RelativeLineIndex: 1, Quote: QuoteDouble})
+}
+
+func TestParameter_Validate(t *testing.T) {
+	t.Parallel()
+	valid := []string{"a", `"a b"`, `'a"b'`, `"a\"b"`, `"a\\"`, "${host}x", "${a", "a$", `a\\`, `^/a\.b$`}
+	for _, v := range valid {
+		assert.NilError(t, (&Parameter{Value: v}).Validate(), v)
+	}
+	invalid := []string{"", "a b", "a;", "a{", "a}", "#a", `"a`, `"a"b`, `"a\"`, "{", "a\nb", `a\`, `\`, `a\\\`}
+	for _, v := range invalid {
+		assert.Assert(t, (&Parameter{Value: v}).Validate() != nil, v)
+	}
+}
+
+func TestParameter_TypedValues(t *testing.T) {
+	t.Parallel()
+	size := Parameter{Value: `"10m"`, Quote: QuoteDouble}
+	n, err := size.AsBytes()
+	assert.NilError(t, err)
+	assert.Equal(t, n, int64(10<<20))
+	size.SetBytes(512 << 10)
+	assert.Equal(t, size.Value, "512k")
+	assert.Equal(t, size.Quote, QuoteNone)
+
+	offset := Parameter{Value: "1g"}
+	n, err = offset.AsOffset()
+	assert.NilError(t, err)
+	assert.Equal(t, n, int64(1<<30))
+	offset.SetOffset(0)
+	assert.Equal(t, offset.Value, "0")
+
+	timeout := Parameter{Value: "1h30s"}
+	d, err := timeout.AsDuration()
+	assert.NilError(t, err)
+	assert.Equal(t, d, time.Hour+30*time.Second)
+	timeout.SetDuration(90 * time.Second)
+	assert.Equal(t, timeout.Value, "1m30s")
+
+	expires := Parameter{Value: "1M"}
+	d, err = expires.AsSeconds()
+	assert.NilError(t, err)
+	assert.Equal(t, d, 30*24*time.Hour)
+	_, err = expires.AsDuration()
+	assert.ErrorContains(t, err, `invalid value: time "1M"`)
+
+	flag := Parameter{Value: "on"}
+	on, err := flag.AsBool()
+	assert.NilError(t, err)
+	assert.Assert(t, on)
+	flag.SetBool(false)
+	assert.Equal(t, flag.Value, "off")
+}
+
+func TestDirective_TypedValues(t *testing.T) {
+	t.Parallel()
+	size := &Directive{Name: "client_max_body_size", Parameters: []Parameter{{Value: "10m"}}}
+	n, err := size.AsBytes()
+	assert.NilError(t, err)
+	assert.Equal(t, n, int64(10<<20))
+	size.SetBytes(1 << 30)
+	assert.Equal(t, size.Parameters[0].Value, "1g")
+
+	offset := &Directive{Name: "mp4_max_buffer_size"}
+	_, err = offset.AsOffset()
+	assert.Error(t, err, "directive mp4_max_buffer_size has no parameter")
+	offset.SetOffset(1024)
+	n, err = offset.AsOffset()
+	assert.NilError(t, err)
+	assert.Equal(t, n, int64(1024))
+
+	// the other parameters are kept
+	keepalive := &Directive{Name: "keepalive_timeout", Parameters: []Parameter{{Value: "65"}, {Value: "60"}}}
+	d, err := keepalive.AsDuration()
+	assert.NilError(t, err)
+	assert.Equal(t, d, 65*time.Second)
+	keepalive.SetDuration(90 * time.Second)
+	assert.DeepEqual(t, keepalive.Parameters, []Parameter{{Value: "1m30s"}, {Value: "60"}})
+
+	expires := &Directive{Name: "expires", Parameters: []Parameter{{Value: "1M"}}}
+	d, err = expires.AsSeconds()
+	assert.NilError(t, err)
+	assert.Equal(t, d, 30*24*time.Hour)
+
+	sendfile := &Directive{Name: "sendfile", Parameters: []Parameter{{Value: "yes"}}}
+	_, err = sendfile.AsBool()
+	assert.Assert(t, err != nil)
+	sendfile.SetBool(true)
+	on, err := sendfile.AsBool()
+	assert.NilError(t, err)
+	assert.Assert(t, on)
+}
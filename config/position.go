@@ -0,0 +1,73 @@
+package config
+
+import "github.com/tufanbarisyildirim/gonginx/parser/token"
+
+// Span represents a source range, from Start up to (but not including) End.
+type Span struct {
+	Start token.Position
+	End   token.Position
+}
+
+// IsValid reports whether the span has been set by the parser.
+func (s Span) IsValid() bool {
+	return s.Start.IsValid()
+}
+
+// Positioner represents a node that knows where it was read from.
+type Positioner interface {
+	GetSpan() Span
+	SetSpan(span Span)
+	GetCommentSpans() []Span
+	SetCommentSpans(spans []Span)
+}
+
+// DefaultPosition represents the default source position holder.
+// Span covers the directive from its name up to its terminating ';' or '}',
+// CommentSpans holds one span per outline comment, in order.
+type DefaultPosition struct {
+	Span         Span
+	CommentSpans []Span
+}
+
+// GetSpan returns the source range of the directive.
+func (d *DefaultPosition) GetSpan() Span {
+	return d.Span
+}
+
+// SetSpan sets the source range of the directive.
+func (d *DefaultPosition) SetSpan(span Span) {
+	d.Span = span
+}
+
+// GetCommentSpans returns the source ranges of the outline comments.
+func (d *DefaultPosition) GetCommentSpans() []Span {
+	return d.CommentSpans
+}
+
+// SetCommentSpans sets the source ranges of the outline comments.
+func (d *DefaultPosition) SetCommentSpans(spans []Span) {
+	d.CommentSpans = spans
+}
+
+// BracePositioner represents a block that knows where its braces are.
+type BracePositioner interface {
+	GetBraces() (lbrace, rbrace Span)
+	SetBraces(lbrace, rbrace Span)
+}
+
+// DefaultBraces represents the default brace position holder.
+type DefaultBraces struct {
+	LBrace Span
+	RBrace Span
+}
+
+// GetBraces returns the source ranges of the opening and closing braces.
+func (b *DefaultBraces) GetBraces() (lbrace, rbrace Span) {
+	return b.LBrace, b.RBrace
+}
+
+// SetBraces sets the source ranges of the opening and closing braces.
+func (b *DefaultBraces) SetBraces(lbrace, rbrace Span) {
+	b.LBrace = lbrace
+	b.RBrace = rbrace
+}
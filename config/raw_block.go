@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+)
+
+// RawBlock represents a block directive whose body is not nginx syntax, such as inline njs code,
+// registered with parser.WithRawBlocks. Code holds the body verbatim
+type RawBlock struct {
+	Name     string
+	Language string // the language of Code, e.g. "js"
+	Code     string
+	Comment  []string
+	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
+	DefaultBraces
+	Parent     IDirective
+	Line       int
+	Parameters []Parameter
+}
+
+// NewRawBlock creates a raw-content block holding the code of the directive block, written in language
+func NewRawBlock(directive IDirective, language string) (*RawBlock, error) {
+	block := directive.GetBlock()
+	if block == nil {
+		return nil, fmt.Errorf("%s must have a block", directive.GetName())
+	}
+	rb := &RawBlock{
+		Name:       directive.GetName(),
+		Language:   language,
+		Code:       block.GetCodeBlock(),
+		Comment:    directive.GetComment(),
+		Parameters: directive.GetParameters(),
+	}
+	rb.InlineComment = directive.GetInlineComment()
+	rb.Span = directive.GetSpan()
+	rb.CommentSpans = directive.GetCommentSpans()
+	rb.Trivia = directive.GetTrivia()
+	rb.Annotations, rb.AnnotationSyntax = directive.GetAnnotations(), directive.GetAnnotationSyntax()
+	rb.LBrace, rb.RBrace = block.GetBraces()
+	return rb, nil
+}
+
+// SetLine sets the line number.
+func (rb *RawBlock) SetLine(line int) {
+	rb.Line = line
+}
+
+// GetLine returns the line number.
+func (rb *RawBlock) GetLine() int {
+	return rb.Line
+}
+
+// SetParent sets the parent directive.
+func (rb *RawBlock) SetParent(parent IDirective) {
+	rb.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (rb *RawBlock) GetParent() IDirective {
+	return rb.Parent
+}
+
+// GetName returns the directive name.
+func (rb *RawBlock) GetName() string {
+	return rb.Name
+}
+
+// GetParameters returns the directive parameters.
+func (rb *RawBlock) GetParameters() []Parameter {
+	return rb.Parameters
+}
+
+// GetDirectives returns no directives, the body is code.
+func (rb *RawBlock) GetDirectives() []IDirective {
+	return []IDirective{}
+}
+
+// FindDirectives returns no directives, the body is code.
+func (rb *RawBlock) FindDirectives(directiveName string) []IDirective {
+	return []IDirective{}
+}
+
+// GetCodeBlock returns the body verbatim.
+func (rb *RawBlock) GetCodeBlock() string {
+	return rb.Code
+}
+
+// GetBlock returns the raw block itself.
+func (rb *RawBlock) GetBlock() IBlock {
+	return rb
+}
+
+// GetComment returns the directive comment.
+func (rb *RawBlock) GetComment() []string {
+	return rb.Comment
+}
+
+// SetComment sets the directive comment.
+func (rb *RawBlock) SetComment(comment []string) {
+	rb.Comment = comment
+}
@@ -9,6 +9,9 @@ type Server struct {
 	Block   IBlock
 	Comment []string
 	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
 	Parent IDirective
 	Line   int
 }
@@ -50,6 +53,15 @@ func NewServer(directive IDirective) (*Server, error) {
 			Block:                block,
 			Comment:              directive.GetComment(),
 			DefaultInlineComment: DefaultInlineComment{InlineComment: directive.GetInlineComment()},
+			DefaultPosition: DefaultPosition{
+				Span:         directive.GetSpan(),
+				CommentSpans: directive.GetCommentSpans(),
+			},
+			DefaultTrivia: DefaultTrivia{Trivia: directive.GetTrivia()},
+			DefaultAnnotations: DefaultAnnotations{
+				Annotations:      directive.GetAnnotations(),
+				AnnotationSyntax: directive.GetAnnotationSyntax(),
+			},
 		}, nil
 	}
 	return nil, errors.New("server directive must have a block")
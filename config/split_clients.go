@@ -0,0 +1,323 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SplitClientsBucket is a bucket of a split_clients block
+type SplitClientsBucket struct {
+	Percent int    // the share of the bucket in hundredths of a percent, e.g. 50 for 0.5%; 0 for the * bucket
+	Value   string // the value of the variable for the keys of the bucket
+}
+
+// SplitClients represents a split_clients block, which splits keys into buckets by their hash
+type SplitClients struct {
+	*Directive
+	Key      string // the key expression, e.g. ${remote_addr}AAA
+	Variable string // the result variable, e.g. $variant
+	Buckets  []SplitClientsBucket
+	Parent   IDirective
+	Line     int
+}
+
+// SetLine sets the line number.
+func (s *SplitClients) SetLine(line int) {
+	s.Line = line
+}
+
+// GetLine returns the line number.
+func (s *SplitClients) GetLine() int {
+	return s.Line
+}
+
+// SetParent sets the parent directive.
+func (s *SplitClients) SetParent(parent IDirective) {
+	s.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (s *SplitClients) GetParent() IDirective {
+	return s.Parent
+}
+
+// NewSplitClients initializes a SplitClients from a split_clients directive.
+// Invalid percentages and a total above 100% return an error
+func NewSplitClients(directive IDirective) (*SplitClients, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("split_clients directive type error")
+	}
+	if len(dir.Parameters) != 2 {
+		return nil, errors.New("split_clients directive requires a key and a variable")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("split_clients directive must have a block")
+	}
+	s := &SplitClients{
+		Directive: dir,
+		Key:       dir.Parameters[0].Unquoted(),
+		Variable:  dir.Parameters[1].Unquoted(),
+		Buckets:   []SplitClientsBucket{},
+	}
+	for _, d := range dir.Block.GetDirectives() {
+		if d.GetName() == "" { // comments
+			continue
+		}
+		if len(d.GetParameters()) != 1 {
+			return nil, fmt.Errorf("invalid number of the split_clients parameters for %s", d.GetName())
+		}
+		percent, err := ParsePercent(d.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.Buckets = append(s.Buckets, SplitClientsBucket{Percent: percent, Value: d.GetParameters()[0].Unquoted()})
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParsePercent parses the percentage of a split_clients bucket, such as 0.5%, in hundredths
+// of a percent as nginx reads it. It returns 0 for the * bucket
+func ParsePercent(s string) (int, error) {
+	if s == "*" {
+		return 0, nil
+	}
+	number, ok := strings.CutSuffix(s, "%")
+	whole, fraction, _ := strings.Cut(number, ".")
+	if !ok || whole+fraction == "" || len(fraction) > 2 || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid percent value %s", s)
+	}
+	n, err := strconv.Atoi(whole + (fraction + "00")[:2])
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid percent value %s", s)
+	}
+	return n, nil
+}
+
+// FormatPercent formats a percentage in hundredths of a percent as written in a split_clients block,
+// 0 being the * bucket
+func FormatPercent(n int) string {
+	switch {
+	case n == 0:
+		return "*"
+	case n%100 == 0:
+		return fmt.Sprintf("%d%%", n/100)
+	case n%10 == 0:
+		return fmt.Sprintf("%d.%d%%", n/100, n%100/10)
+	}
+	return fmt.Sprintf("%d.%02d%%", n/100, n%100)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the percentages of the buckets do not exceed 100% and that a * bucket,
+// which takes what remains, is the last one
+func (s *SplitClients) Validate() error {
+	sum := 0
+	for i, bucket := range s.Buckets {
+		if bucket.Percent < 0 {
+			return fmt.Errorf("invalid percent value for %s", bucket.Value)
+		}
+		if bucket.Percent == 0 && i < len(s.Buckets)-1 {
+			return fmt.Errorf("the * bucket of %s must be the last one", bucket.Value)
+		}
+		sum += bucket.Percent
+	}
+	if sum > 10000 {
+		return errors.New("percent total is greater than 100%")
+	}
+	return nil
+}
+
+// Pick returns the value of the bucket of key, the value of the key expression, as nginx picks it
+// with the MurmurHash2 of the key. It returns an empty value when the key falls in no bucket
+func (s *SplitClients) Pick(key string) string {
+	hash := MurmurHash2([]byte(key))
+	var last uint32
+	for _, bucket := range s.Buckets {
+		if bucket.Percent == 0 {
+			return bucket.Value
+		}
+		// nginx keeps the bounds in 32 bits
+		last += uint32(uint64(bucket.Percent) * math.MaxUint32 / 10000)
+		if hash < last {
+			return bucket.Value
+		}
+	}
+	return ""
+}
+
+// SetPercent sets the percentage of the bucket of value, adding the bucket before the * bucket when
+// it is missing and removing it for 0. The percent is rounded to hundredths. When value is the one of
+// the * bucket, a bucket with a percentage is added in front of it. The directives of the block are
+// updated, a total above 100% returns an error and keeps the buckets
+func (s *SplitClients) SetPercent(value string, percent float64) error {
+	return s.SetBuckets(withPercent(s.Buckets, value, int(math.Round(percent*100))))
+}
+
+// Shift moves percent from the bucket of from to the bucket of to, e.g. Shift("v1", "v2", 5) during
+// a rollout. The bucket of to is added when missing and the bucket of from is removed when it has
+// nothing left. Shifting from or to the value of the * bucket only changes the other bucket, the *
+// bucket taking what remains
+func (s *SplitClients) Shift(from, to string, percent float64) error {
+	hundredths := int(math.Round(percent * 100))
+	if hundredths < 0 {
+		return fmt.Errorf("invalid percent value %g", percent)
+	}
+	buckets := s.Buckets
+	if share, ok := s.percent(from); ok {
+		if share < hundredths {
+			return fmt.Errorf("cannot shift %s from %s, which has %s", FormatPercent(hundredths), from, FormatPercent(share))
+		}
+		buckets = withPercent(buckets, from, share-hundredths)
+	}
+	if share, ok := s.percent(to); ok || !s.isDefault(to) {
+		buckets = withPercent(buckets, to, share+hundredths)
+	}
+	return s.SetBuckets(buckets)
+}
+
+// percent returns the percentage of the bucket of value, ok is false when it has none
+func (s *SplitClients) percent(value string) (percent int, ok bool) {
+	for _, bucket := range s.Buckets {
+		if bucket.Value == value && bucket.Percent != 0 {
+			return bucket.Percent, true
+		}
+	}
+	return 0, false
+}
+
+// isDefault reports whether value is the one of the * bucket
+func (s *SplitClients) isDefault(value string) bool {
+	for _, bucket := range s.Buckets {
+		if bucket.Value == value && bucket.Percent == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withPercent returns a copy of buckets where the bucket of value has percent
+func withPercent(buckets []SplitClientsBucket, value string, percent int) []SplitClientsBucket {
+	updated := make([]SplitClientsBucket, 0, len(buckets)+1)
+	found := percent == 0
+	for _, bucket := range buckets {
+		if bucket.Value == value && bucket.Percent != 0 {
+			if found {
+				continue
+			}
+			bucket.Percent, found = percent, true
+		}
+		if bucket.Percent == 0 && !found {
+			updated = append(updated, SplitClientsBucket{Percent: percent, Value: value})
+			found = true
+		}
+		updated = append(updated, bucket)
+	}
+	if !found {
+		updated = append(updated, SplitClientsBucket{Percent: percent, Value: value})
+	}
+	return updated
+}
+
+// SetBuckets validates and sets the buckets, then rewrites the directives of the block with them.
+// The directives of the buckets that are kept keep their comments, comments of the block stay at the end
+func (s *SplitClients) SetBuckets(buckets []SplitClientsBucket) error {
+	previous := s.Buckets
+	s.Buckets = buckets
+	if err := s.Validate(); err != nil {
+		s.Buckets = previous
+		return err
+	}
+
+	type bucketKey struct {
+		value      string
+		hasPercent bool
+	}
+	existing := make(map[bucketKey]*Directive)
+	var comments []IDirective
+	for _, d := range s.GetDirectives() {
+		if d.GetName() == "" {
+			comments = append(comments, d)
+			continue
+		}
+		if dir, ok := d.(*Directive); ok && len(dir.Parameters) == 1 {
+			existing[bucketKey{dir.Parameters[0].Unquoted(), dir.Name != "*"}] = dir
+		}
+	}
+
+	directives := make([]IDirective, 0, len(buckets)+len(comments))
+	for _, bucket := range buckets {
+		name := FormatPercent(bucket.Percent)
+		key := bucketKey{bucket.Value, bucket.Percent != 0}
+		if d, ok := existing[key]; ok {
+			delete(existing, key)
+			d.Name = name
+			directives = append(directives, d)
+			continue
+		}
+		directives = append(directives, &Directive{Name: name, Parameters: []Parameter{NewParameter(bucket.Value)}, Parent: s})
+	}
+	directives = append(directives, comments...)
+
+	block, ok := s.Block.(*Block)
+	if !ok {
+		block = &Block{Parent: s}
+		s.Block = block
+	}
+	block.Directives = directives
+	return nil
+}
+
+// MurmurHash2 returns the MurmurHash2 of data as nginx computes it for split_clients
+func MurmurHash2(data []byte) uint32 {
+	const m = 0x5bd1e995
+	h := uint32(len(data))
+	for len(data) >= 4 {
+		k := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+		data = data[4:]
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// FindDirectives finds directives by name.
+func (s *SplitClients) FindDirectives(directiveName string) []IDirective {
+	return s.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the split_clients block.
+func (s *SplitClients) GetDirectives() []IDirective {
+	return s.GetBlock().GetDirectives()
+}
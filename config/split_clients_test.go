@@ -0,0 +1,128 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func splitClientsDirective(buckets ...string) *Directive {
+	d := geoDirective([]string{`"${remote_addr}AAA"`, "$variant"}, buckets...)
+	d.Name = "split_clients"
+	return d
+}
+
+func TestMurmurHash2(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		data string
+		want uint32
+	}{
+		{"", 0},
+		{"a", 0x92685f5e},
+		{"ab", 446775395},
+		{"abc", 324500635},
+		{"abcd", 646393889},
+		{"hello world", 1151865881},
+		{"10.0.0.1AAA", 326221919},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, MurmurHash2([]byte(tt.data)), tt.want, tt.data)
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "*", want: 0},
+		{value: "0.5%", want: 50},
+		{value: "50%", want: 5000},
+		{value: "33.33%", want: 3333},
+		{value: ".5%", want: 50},
+		{value: "0%", wantErr: true},
+		{value: "50", wantErr: true},
+		{value: "0.125%", wantErr: true},
+		{value: "a%", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePercent(tt.value)
+		if tt.wantErr {
+			assert.ErrorContains(t, err, "invalid percent value "+tt.value)
+			continue
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, got, tt.want, tt.value)
+		if tt.value != ".5%" {
+			assert.Equal(t, FormatPercent(got), tt.value)
+		}
+	}
+}
+
+func TestNewSplitClients(t *testing.T) {
+	t.Parallel()
+	s, err := NewSplitClients(splitClientsDirective("0.5% v2", "* v1"))
+	assert.NilError(t, err)
+	assert.Equal(t, s.Key, "${remote_addr}AAA")
+	assert.Equal(t, s.Variable, "$variant")
+	assert.DeepEqual(t, s.Buckets, []SplitClientsBucket{{Percent: 50, Value: "v2"}, {Percent: 0, Value: "v1"}})
+
+	_, err = NewSplitClients(splitClientsDirective("60% a", "50% b"))
+	assert.ErrorContains(t, err, "percent total is greater than 100%")
+	_, err = NewSplitClients(splitClientsDirective("* x", "10% y"))
+	assert.ErrorContains(t, err, "the * bucket of x must be the last one")
+	_, err = NewSplitClients(splitClientsDirective("10% y", "* x", "* z"))
+	assert.ErrorContains(t, err, "the * bucket of x must be the last one")
+	_, err = NewSplitClients(splitClientsDirective("10% a b"))
+	assert.ErrorContains(t, err, "invalid number of the split_clients parameters for 10%")
+}
+
+func TestSplitClients_Pick(t *testing.T) {
+	t.Parallel()
+	s, err := NewSplitClients(splitClientsDirective("0.5% v2", "* v1"))
+	assert.NilError(t, err)
+	// 10.0.3.113AAA hashes to 17572072, below 0.5% of 2^32
+	assert.Equal(t, s.Pick("10.0.3.113AAA"), "v2")
+	assert.Equal(t, s.Pick("10.0.0.1AAA"), "v1")
+
+	s, err = NewSplitClients(splitClientsDirective("10% v2", "5% v3"))
+	assert.NilError(t, err)
+	assert.Equal(t, s.Pick("10.0.0.1AAA"), "v2")
+	// "a" hashes above 15%
+	assert.Equal(t, s.Pick("a"), "")
+}
+
+func TestSplitClients_Shift(t *testing.T) {
+	t.Parallel()
+	d := splitClientsDirective("0.5% v2", "* v1")
+	d.Block.GetDirectives()[0].SetComment([]string{"# canary"})
+	s, err := NewSplitClients(d)
+	assert.NilError(t, err)
+
+	assert.NilError(t, s.Shift("v1", "v2", 4.5))
+	assert.DeepEqual(t, s.Buckets, []SplitClientsBucket{{Percent: 500, Value: "v2"}, {Percent: 0, Value: "v1"}})
+	assert.Equal(t, s.GetDirectives()[0].GetName(), "5%")
+	assert.DeepEqual(t, s.GetDirectives()[0].GetComment(), []string{"# canary"})
+	assert.NilError(t, s.SetPercent("v3", 33.33))
+	assert.NilError(t, s.Shift("v2", "v3", 5))
+
+	directives := s.GetDirectives()
+	assert.Equal(t, len(directives), 2)
+	assert.Equal(t, directives[0].GetName(), "38.33%")
+	assert.Equal(t, directives[0].GetParameters()[0].Value, "v3")
+	assert.Equal(t, directives[1].GetName(), "*")
+
+	assert.NilError(t, s.Shift("v3", "v2", 0.33))
+	directives = s.GetDirectives()
+	assert.Equal(t, directives[0].GetName(), "38%")
+	assert.Equal(t, directives[1].GetName(), "0.33%")
+
+	assert.ErrorContains(t, s.Shift("v2", "v3", 1), "cannot shift 1% from v2, which has 0.33%")
+	assert.ErrorContains(t, s.SetPercent("v4", 70), "percent total is greater than 100%")
+	assert.Equal(t, len(s.Buckets), 3)
+	assert.ErrorContains(t, s.SetBuckets([]SplitClientsBucket{{Value: "v1"}, {Percent: 100, Value: "v2"}}), "the * bucket of v1 must be the last one")
+	assert.Equal(t, len(s.Buckets), 3)
+}
@@ -1,5 +1,7 @@
 package config
 
+import "strings"
+
 // IBlock represents any directive block
 type IBlock interface {
 	GetDirectives() []IDirective
@@ -7,6 +9,12 @@ type IBlock interface {
 	GetCodeBlock() string
 	SetParent(IDirective)
 	GetParent() IDirective
+	BracePositioner
+}
+
+// DirectivesSetter represents a block whose directives can be replaced, such as *Block
+type DirectivesSetter interface {
+	SetDirectives(directives []IDirective)
 }
 
 // IDirective represents any directive
@@ -21,6 +29,9 @@ type IDirective interface {
 	GetLine() int
 	SetLine(int)
 	InlineCommenter
+	Positioner
+	TriviaHolder
+	Annotator
 }
 
 // InlineCommenter represents the inline comment holder
@@ -54,10 +65,43 @@ type IncludeDirective interface {
 	FileDirective
 }
 
+// Quote is the way a parameter is quoted in the source
+type Quote int
+
+const (
+	// QuoteNone a bare parameter
+	QuoteNone Quote = iota
+	// QuoteSingle a 'single quoted' parameter
+	QuoteSingle
+	// QuoteDouble a "double quoted" parameter
+	QuoteDouble
+	// QuoteBacktick a `backtick quoted` parameter
+	QuoteBacktick
+)
+
+// QuoteOf returns the quoting of a parameter value as written in the source
+func QuoteOf(value string) Quote {
+	if len(value) < 2 || value[len(value)-1] != value[0] {
+		return QuoteNone
+	}
+	switch value[0] {
+	case '\'':
+		return QuoteSingle
+	case '"':
+		return QuoteDouble
+	case '`':
+		return QuoteBacktick
+	}
+	return QuoteNone
+}
+
 // Parameter represents a parameter in a directive
 type Parameter struct {
 	Value             string
-	RelativeLineIndex int // relative line index to the directive
+	RelativeLineIndex int   // relative line index to the directive
+	Span              Span  // source range of the parameter
+	Quote             Quote // quoting of Value, the quotes are kept in Value
+	Regex             bool  // the parameter is a regular expression (location ~, if, rewrite, server_name ~)
 }
 
 // String returns the value of the parameter
@@ -85,5 +129,65 @@ func (p *Parameter) GetRelativeLineIndex() int {
 	return p.RelativeLineIndex
 }
 
+// GetSpan returns the source range of the parameter
+func (p *Parameter) GetSpan() Span {
+	return p.Span
+}
+
+// IsVariable reports whether the parameter is a single bare variable, such as $host or ${host}
+func (p *Parameter) IsVariable() bool {
+	vars := p.Variables()
+	return p.Quote == QuoteNone && len(vars) == 1 && (p.Value == "$"+vars[0] || p.Value == "${"+vars[0]+"}")
+}
+
+// Variables returns the names of the variables referenced by the parameter, without the $,
+// in order of appearance. Regex captures ($1 to $9) are returned by number.
+// Regular expressions reference no variables, a trailing $ is an anchor there
+func (p *Parameter) Variables() []string {
+	if p.Regex {
+		return nil
+	}
+	var vars []string
+	v := p.Value
+	for i := 0; i < len(v); i++ {
+		if v[i] != '$' || i+1 >= len(v) {
+			continue
+		}
+		rest := v[i+1:]
+		switch {
+		case rest[0] >= '1' && rest[0] <= '9':
+			vars = append(vars, rest[:1])
+		case rest[0] == '{':
+			if end := strings.IndexByte(rest, '}'); end > 1 && isVariableName(rest[1:end]) {
+				vars = append(vars, rest[1:end])
+				i += end + 1
+			}
+		default:
+			n := 0
+			for n < len(rest) && isVariableChar(rest[n]) {
+				n++
+			}
+			if n > 0 {
+				vars = append(vars, rest[:n])
+				i += n
+			}
+		}
+	}
+	return vars
+}
+
+func isVariableName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isVariableChar(name[i]) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func isVariableChar(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
 // InlineComment represents an inline comment
 type InlineComment Parameter
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParameter_Variables(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		param Parameter
+		want  []string
+	}{
+		{name: "no variable", param: Parameter{Value: "/var/www"}},
+		{name: "single variable", param: Parameter{Value: "$host"}, want: []string{"host"}},
+		{name: "braced variable", param: Parameter{Value: "${host}_x"}, want: []string{"host"}},
+		{name: "mixed", param: Parameter{Value: `"$scheme://$host$request_uri"`, Quote: QuoteDouble}, want: []string{"scheme", "host", "request_uri"}},
+		{name: "captures", param: Parameter{Value: "/a/$1/$20"}, want: []string{"1", "2"}},
+		{name: "lone dollar", param: Parameter{Value: "a$ $"}},
+		{name: "regex", param: Parameter{Value: "^/a$", Regex: true}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.DeepEqual(t, tt.param.Variables(), tt.want)
+		})
+	}
+}
+
+func TestParameter_IsVariable(t *testing.T) {
+	t.Parallel()
+	assert.Assert(t, (&Parameter{Value: "$host"}).IsVariable())
+	assert.Assert(t, (&Parameter{Value: "${host}"}).IsVariable())
+	assert.Assert(t, !(&Parameter{Value: "$host$uri"}).IsVariable())
+	assert.Assert(t, !(&Parameter{Value: `"$host"`, Quote: QuoteDouble}).IsVariable())
+}
+
+func TestQuoteOf(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, QuoteOf("a"), QuoteNone)
+	assert.Equal(t, QuoteOf(`'a'`), QuoteSingle)
+	assert.Equal(t, QuoteOf(`"a"`), QuoteDouble)
+	assert.Equal(t, QuoteOf("`a`"), QuoteBacktick)
+	assert.Equal(t, QuoteOf(`"`), QuoteNone)
+	assert.Equal(t, QuoteOf(`"a'`), QuoteNone)
+}
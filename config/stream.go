@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+)
+
+// Stream represents a stream block, the TCP and UDP proxy subsystem.
+type Stream struct {
+	*Directive
+	Parent IDirective
+	Line   int
+}
+
+// SetLine sets the line number.
+func (s *Stream) SetLine(line int) {
+	s.Line = line
+}
+
+// GetLine returns the line number.
+func (s *Stream) GetLine() int {
+	return s.Line
+}
+
+// SetParent sets the parent directive.
+func (s *Stream) SetParent(parent IDirective) {
+	s.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (s *Stream) GetParent() IDirective {
+	return s.Parent
+}
+
+// NewStream initializes a Stream from a stream directive.
+func NewStream(directive IDirective) (*Stream, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("stream directive type error")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("stream directive must have a block")
+	}
+	if len(dir.Parameters) != 0 {
+		return nil, errors.New("stream directive does not take parameters")
+	}
+	return &Stream{Directive: dir}, nil
+}
+
+// Servers returns the servers of the stream block, those of the files it includes too.
+func (s *Stream) Servers() []*StreamServer {
+	servers := make([]*StreamServer, 0)
+	for _, d := range s.FindDirectives("server") {
+		if server, ok := d.(*StreamServer); ok {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// Upstreams returns the upstreams of the stream block, those of the files it includes too.
+func (s *Stream) Upstreams() []*StreamUpstream {
+	upstreams := make([]*StreamUpstream, 0)
+	for _, d := range s.FindDirectives("upstream") {
+		if upstream, ok := d.(*StreamUpstream); ok {
+			upstreams = append(upstreams, upstream)
+		}
+	}
+	return upstreams
+}
+
+// FindUpstream returns the upstream of the stream block named name, nil when there is none.
+func (s *Stream) FindUpstream(name string) *StreamUpstream {
+	for _, upstream := range s.Upstreams() {
+		if upstream.UpstreamName == name {
+			return upstream
+		}
+	}
+	return nil
+}
+
+// AddServer appends a server to the stream block.
+func (s *Stream) AddServer(server *StreamServer) {
+	if server == nil {
+		return
+	}
+	server.SetParent(s)
+	s.Block = appendDirective(s.Block, server)
+}
+
+// FindDirectives finds directives by name.
+func (s *Stream) FindDirectives(directiveName string) []IDirective {
+	return s.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the stream block.
+func (s *Stream) GetDirectives() []IDirective {
+	return s.GetBlock().GetDirectives()
+}
+
+// StreamServer represents a server block of the stream subsystem, which proxies TCP or UDP connections.
+type StreamServer struct {
+	*Directive
+	Parent IDirective
+	Line   int
+}
+
+// SetLine sets the line number.
+func (s *StreamServer) SetLine(line int) {
+	s.Line = line
+}
+
+// GetLine returns the line number.
+func (s *StreamServer) GetLine() int {
+	return s.Line
+}
+
+// SetParent sets the parent directive.
+func (s *StreamServer) SetParent(parent IDirective) {
+	s.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (s *StreamServer) GetParent() IDirective {
+	return s.Parent
+}
+
+// NewStreamServer initializes a StreamServer from a server directive of a stream block.
+func NewStreamServer(directive IDirective) (*StreamServer, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("stream server directive type error")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("server directive must have a block")
+	}
+	return &StreamServer{Directive: dir}, nil
+}
+
+// Listens returns the parsed listen directives of the server.
+func (s *StreamServer) Listens() ([]Listen, error) {
+	return listensOf(s.Block)
+}
+
+// SetListens replaces the listen directives of the server, the new ones taking the place of the first one
+// and the comments of the replaced ones by position.
+func (s *StreamServer) SetListens(listens ...Listen) {
+	s.Block = replaceListens(s.Block, s, listens)
+}
+
+// ProxyPass returns the address or upstream name connections are proxied to, empty without proxy_pass.
+func (s *StreamServer) ProxyPass() string {
+	if parameters, _ := directiveParameters(s.Block, "proxy_pass"); len(parameters) > 0 {
+		return parameters[0].Unquoted()
+	}
+	return ""
+}
+
+// SetProxyPass sets the address or upstream name connections are proxied to, adding proxy_pass when missing.
+func (s *StreamServer) SetProxyPass(target string) {
+	s.Block = setDirective(s.Block, s, "proxy_pass", NewParameter(target))
+}
+
+// Upstream returns the upstream of the stream block the server proxies to, nil when proxy_pass is not an upstream.
+func (s *StreamServer) Upstream() *StreamUpstream {
+	stream, ok := s.Parent.(*Stream)
+	if !ok || s.ProxyPass() == "" {
+		return nil
+	}
+	return stream.FindUpstream(s.ProxyPass())
+}
+
+// SSLPreread reports whether ssl_preread is on, which reads the server name of TLS connections without terminating them.
+func (s *StreamServer) SSLPreread() bool {
+	parameters, _ := directiveParameters(s.Block, "ssl_preread")
+	if len(parameters) == 0 {
+		return false
+	}
+	on, err := parameters[0].AsBool()
+	return err == nil && on
+}
+
+// SetSSLPreread turns ssl_preread on or off, adding the directive when missing.
+func (s *StreamServer) SetSSLPreread(on bool) {
+	s.Block = setFlag(s.Block, s, "ssl_preread", on)
+}
+
+// FindDirectives finds directives by name.
+func (s *StreamServer) FindDirectives(directiveName string) []IDirective {
+	return s.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the server block.
+func (s *StreamServer) GetDirectives() []IDirective {
+	return s.GetBlock().GetDirectives()
+}
+
+// StreamUpstream represents an upstream block of the stream subsystem, a group of TCP or UDP servers.
+// It has the servers and directives of an http *Upstream, which it embeds
+type StreamUpstream struct {
+	*Upstream
+}
+
+// NewStreamUpstream initializes a StreamUpstream from an upstream directive of a stream block.
+func NewStreamUpstream(directive IDirective) (*StreamUpstream, error) {
+	upstream, err := NewUpstream(directive)
+	if err != nil {
+		return nil, err
+	}
+	s := &StreamUpstream{Upstream: upstream}
+	for _, server := range upstream.UpstreamServers {
+		server.SetParent(s)
+	}
+	return s, nil
+}
+
+// AddServer appends a server to the upstream.
+func (s *StreamUpstream) AddServer(server *UpstreamServer) {
+	server.SetParent(s)
+	s.Upstream.AddServer(server)
+}
+
+// SetDirectives replaces the directives of the upstream, the servers going to UpstreamServers.
+func (s *StreamUpstream) SetDirectives(directives []IDirective) {
+	s.Upstream.SetDirectives(directives)
+	for _, server := range s.UpstreamServers {
+		server.SetParent(s)
+	}
+}
+
+// GetBlock returns the upstream itself.
+func (s *StreamUpstream) GetBlock() IBlock {
+	return s
+}
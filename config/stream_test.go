@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStreamServer(t *testing.T) {
+	t.Parallel()
+	listen := &Directive{Name: "listen", Parameters: []Parameter{{Value: "53"}, {Value: "udp"}}, Comment: []string{"# dns"}}
+	server, err := NewStreamServer(&Directive{Name: "server", Block: &Block{Directives: []IDirective{
+		listen,
+		&Directive{Name: "proxy_pass", Parameters: []Parameter{{Value: "dns"}}},
+	}}})
+	assert.NilError(t, err)
+	upstream, err := NewStreamUpstream(&Directive{Name: "upstream", Parameters: []Parameter{{Value: "dns"}}, Block: &Block{}})
+	assert.NilError(t, err)
+	stream, err := NewStream(&Directive{Name: "stream", Block: &Block{Directives: []IDirective{upstream}}})
+	assert.NilError(t, err)
+	stream.AddServer(server)
+
+	assert.Equal(t, len(stream.Servers()), 1)
+	assert.Equal(t, server.GetParent(), IDirective(stream))
+	assert.Equal(t, server.ProxyPass(), "dns")
+	assert.Equal(t, server.Upstream(), upstream)
+	assert.Assert(t, !server.SSLPreread())
+
+	listens, err := server.Listens()
+	assert.NilError(t, err)
+	assert.Equal(t, len(listens), 1)
+	assert.Assert(t, listens[0].UDP)
+
+	listens[0].ReusePort = true
+	server.SetListens(listens[0], Listen{Address: "[::]:53", UDP: true})
+	server.SetProxyPass("127.0.0.1:5353")
+	server.SetSSLPreread(true)
+
+	directives := server.GetDirectives()
+	assert.Equal(t, len(directives), 4)
+	assert.Equal(t, directives[0].GetParameters()[2].Value, "reuseport")
+	assert.DeepEqual(t, directives[0].GetComment(), []string{"# dns"})
+	assert.Equal(t, directives[1].GetParameters()[0].Value, "[::]:53")
+	assert.Equal(t, directives[2].GetParameters()[0].Value, "127.0.0.1:5353")
+	assert.Equal(t, directives[3].GetName(), "ssl_preread")
+	assert.Assert(t, server.SSLPreread())
+	assert.Assert(t, server.Upstream() == nil)
+}
+
+func TestStreamUpstream(t *testing.T) {
+	t.Parallel()
+	upstream, err := NewStreamUpstream(&Directive{Name: "upstream", Parameters: []Parameter{{Value: "dns"}}, Block: &Block{Directives: []IDirective{
+		&Directive{Name: "hash", Parameters: []Parameter{{Value: "$remote_addr"}, {Value: "consistent"}}},
+		&Directive{Name: "server", Parameters: []Parameter{{Value: "10.0.0.1:53"}}},
+	}}})
+	assert.NilError(t, err)
+	assert.Equal(t, upstream.UpstreamName, "dns")
+	assert.Equal(t, upstream.GetBlock(), IBlock(upstream))
+	assert.Equal(t, upstream.UpstreamServers[0].GetParent(), IDirective(upstream))
+
+	upstream.AddServer(&UpstreamServer{Address: "10.0.0.2:53"})
+	assert.Equal(t, upstream.UpstreamServers[1].GetParent(), IDirective(upstream))
+	upstream.SetDirectives(upstream.GetDirectives())
+	assert.Equal(t, len(upstream.UpstreamServers), 2)
+	assert.Equal(t, upstream.UpstreamServers[0].GetParent(), IDirective(upstream))
+
+	// both kinds of upstreams are found in a config
+	stream, err := NewStream(&Directive{Name: "stream", Block: &Block{Directives: []IDirective{upstream}}})
+	assert.NilError(t, err)
+	http, err := NewUpstream(&Directive{Name: "upstream", Parameters: []Parameter{{Value: "web"}}, Block: &Block{}})
+	assert.NilError(t, err)
+	c := &Config{Block: &Block{Directives: []IDirective{stream, http}}}
+	assert.DeepEqual(t, c.FindUpstreams(), []*Upstream{upstream.Upstream, http})
+	strict, err := c.FindUpstreamsStrict()
+	assert.NilError(t, err)
+	assert.Equal(t, len(strict), 2)
+}
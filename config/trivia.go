@@ -0,0 +1,95 @@
+package config
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Trivia holds the verbatim source text around a directive, kept when the
+// parser runs with trivia preservation enabled.
+type Trivia struct {
+	// Leading is the whitespace (blank lines, indentation, line endings) before the directive.
+	Leading string
+	// Head is the source from the first outline comment of the directive up to its
+	// terminating ';' and inline comment, or up to its opening '{' for blocks.
+	// Directives holding a code block (e.g. *_by_lua_block) keep everything up to the closing '}'.
+	Head string
+	// Closing is the source after the last sub directive up to and including the closing '}'.
+	Closing string
+
+	fingerprint string
+}
+
+// NewTrivia creates the trivia of a directive and records its current state,
+// so later changes to the directive can be detected with Modified.
+func NewTrivia(d IDirective, leading, head, closing string) *Trivia {
+	return &Trivia{
+		Leading:     leading,
+		Head:        head,
+		Closing:     closing,
+		fingerprint: fingerprint(d),
+	}
+}
+
+// Modified reports whether the name, parameters, comments, annotations or code of the directive
+// changed since its trivia was recorded. Sub directives are not taken into account.
+func (t *Trivia) Modified(d IDirective) bool {
+	return t.fingerprint != fingerprint(d)
+}
+
+// TriviaHolder represents a directive that can carry its source trivia.
+type TriviaHolder interface {
+	GetTrivia() *Trivia
+	SetTrivia(trivia *Trivia)
+}
+
+// DefaultTrivia represents the default trivia holder.
+type DefaultTrivia struct {
+	Trivia *Trivia
+}
+
+// GetTrivia returns the source trivia, nil if the directive was not parsed with trivia.
+func (d *DefaultTrivia) GetTrivia() *Trivia {
+	return d.Trivia
+}
+
+// SetTrivia sets the source trivia.
+func (d *DefaultTrivia) SetTrivia(trivia *Trivia) {
+	d.Trivia = trivia
+}
+
+func fingerprint(d IDirective) string {
+	var b strings.Builder
+	b.WriteString(d.GetName())
+	for _, p := range d.GetParameters() {
+		b.WriteString("\x00p")
+		b.WriteString(strconv.Itoa(p.RelativeLineIndex))
+		b.WriteString(" ")
+		b.WriteString(p.Value)
+	}
+	for _, c := range d.GetComment() {
+		b.WriteString("\x00c")
+		b.WriteString(c)
+	}
+	for _, c := range d.GetInlineComment() {
+		b.WriteString("\x00i")
+		b.WriteString(strconv.Itoa(c.RelativeLineIndex))
+		b.WriteString(" ")
+		b.WriteString(c.Value)
+	}
+	annotations := make([]string, 0, len(d.GetAnnotations()))
+	for key, value := range d.GetAnnotations() {
+		annotations = append(annotations, key+"\x00"+value)
+	}
+	sort.Strings(annotations)
+	for _, a := range annotations {
+		b.WriteString("\x00a")
+		b.WriteString(a)
+	}
+	if block := d.GetBlock(); block != nil {
+		b.WriteString("\x00{")
+		b.WriteString(block.GetCodeBlock())
+	}
+	return b.String()
+}
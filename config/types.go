@@ -0,0 +1,274 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TypesEntry is a MIME type of a types block with its file extensions
+type TypesEntry struct {
+	MIME       string
+	Extensions []string
+	Directive  IDirective // the directive of the entry, named by the MIME type
+}
+
+// DuplicateExtension is an extension mapped to a MIME type after being mapped to another one,
+// nginx warns about it and keeps the last MIME type
+type DuplicateExtension struct {
+	Extension    string
+	MIME         string
+	PreviousMIME string
+	Directive    IDirective // the directive of the last MIME type
+	Previous     IDirective // the directive of the previous MIME type
+}
+
+func (d DuplicateExtension) Error() string {
+	return fmt.Sprintf("duplicate extension \"%s\", content type: \"%s\", previous content type: \"%s\"", d.Extension, d.MIME, d.PreviousMIME)
+}
+
+// Types represents a types block, which maps file extensions to MIME types.
+// Its entries are read from the block directives, so changes to them are seen by Lookup
+type Types struct {
+	*Directive
+	Parent IDirective
+	Line   int
+}
+
+// SetLine sets the line number.
+func (t *Types) SetLine(line int) {
+	t.Line = line
+}
+
+// GetLine returns the line number.
+func (t *Types) GetLine() int {
+	return t.Line
+}
+
+// SetParent sets the parent directive.
+func (t *Types) SetParent(parent IDirective) {
+	t.Parent = parent
+}
+
+// GetParent returns the parent directive.
+func (t *Types) GetParent() IDirective {
+	return t.Parent
+}
+
+// NewTypes initializes a Types from a types directive.
+func NewTypes(directive IDirective) (*Types, error) {
+	dir, ok := directive.(*Directive)
+	if !ok {
+		return nil, errors.New("types directive type error")
+	}
+	if len(dir.Parameters) != 0 {
+		return nil, errors.New("types directive does not take parameters")
+	}
+	if dir.Block == nil {
+		return nil, errors.New("types directive must have a block")
+	}
+	return &Types{Directive: dir}, nil
+}
+
+// Entries returns the MIME types of the block in config order. Extensions are lowercase, as nginx reads them
+func (t *Types) Entries() []TypesEntry {
+	entries := make([]TypesEntry, 0)
+	for _, d := range t.GetDirectives() {
+		if d.GetName() == "" { // comments
+			continue
+		}
+		entry := TypesEntry{MIME: (&Parameter{Value: d.GetName()}).Unquoted(), Extensions: []string{}, Directive: d}
+		for _, p := range d.GetParameters() {
+			entry.Extensions = append(entry.Extensions, strings.ToLower(p.Unquoted()))
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Lookup returns the MIME type of a file extension, without its dot and ignoring case.
+// The last MIME type listing the extension wins, as in nginx
+func (t *Types) Lookup(ext string) (mime string, ok bool) {
+	return LookupType(ext, t)
+}
+
+// Extensions returns the extensions of a MIME type, those taken by a later MIME type excluded
+func (t *Types) Extensions(mime string) []string {
+	extensions := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, entry := range t.Entries() {
+		if entry.MIME != mime {
+			continue
+		}
+		for _, ext := range entry.Extensions {
+			if _, ok := seen[ext]; ok {
+				continue
+			}
+			seen[ext] = struct{}{}
+			if found, _ := t.Lookup(ext); found == mime {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+	return extensions
+}
+
+// Add maps extensions to a MIME type. The extensions are removed from the other MIME types, and
+// those left without extensions are removed. They are added to the first directive of the MIME type,
+// or to a new directive at the end of the block
+func (t *Types) Add(mime string, extensions ...string) {
+	for _, ext := range extensions {
+		t.removeExtension(strings.ToLower(ext), mime)
+	}
+
+	for _, d := range t.GetDirectives() {
+		if (&Parameter{Value: d.GetName()}).Unquoted() != mime {
+			continue
+		}
+		dir, ok := d.(*Directive)
+		if !ok {
+			continue
+		}
+		for _, ext := range extensions {
+			if !containsExtension(dir.Parameters, ext) {
+				dir.Parameters = append(dir.Parameters, NewParameter(ext))
+			}
+		}
+		return
+	}
+
+	if len(extensions) == 0 {
+		return
+	}
+	d := &Directive{Name: mime, Parameters: []Parameter{}, Parent: t}
+	for _, ext := range extensions {
+		if !containsExtension(d.Parameters, ext) {
+			d.Parameters = append(d.Parameters, NewParameter(ext))
+		}
+	}
+	t.setDirectives(append(t.GetDirectives(), d))
+}
+
+// Remove removes a MIME type with all its extensions, it returns false when the block does not have it
+func (t *Types) Remove(mime string) bool {
+	directives := make([]IDirective, 0, len(t.GetDirectives()))
+	for _, d := range t.GetDirectives() {
+		if d.GetName() == "" || (&Parameter{Value: d.GetName()}).Unquoted() != mime {
+			directives = append(directives, d)
+		}
+	}
+	removed := len(directives) != len(t.GetDirectives())
+	t.setDirectives(directives)
+	return removed
+}
+
+// RemoveExtension removes an extension from every MIME type, removing the ones left without extensions.
+// It returns false when no MIME type has the extension
+func (t *Types) RemoveExtension(ext string) bool {
+	_, ok := t.Lookup(ext)
+	t.removeExtension(strings.ToLower(ext), "")
+	return ok
+}
+
+// removeExtension removes ext from the MIME types other than keep
+func (t *Types) removeExtension(ext string, keep string) {
+	directives := make([]IDirective, 0, len(t.GetDirectives()))
+	for _, d := range t.GetDirectives() {
+		dir, ok := d.(*Directive)
+		if !ok || d.GetName() == "" || (&Parameter{Value: d.GetName()}).Unquoted() == keep || !containsExtension(dir.Parameters, ext) {
+			directives = append(directives, d)
+			continue
+		}
+		parameters := make([]Parameter, 0, len(dir.Parameters))
+		for _, p := range dir.Parameters {
+			if !strings.EqualFold(p.Unquoted(), ext) {
+				parameters = append(parameters, p)
+			}
+		}
+		dir.Parameters = parameters
+		if len(parameters) > 0 {
+			directives = append(directives, d)
+		}
+	}
+	t.setDirectives(directives)
+}
+
+// Merge adds the MIME types of other, which win over those of the block for the extensions both map
+func (t *Types) Merge(other *Types) {
+	for _, entry := range other.Entries() {
+		t.Add(entry.MIME, entry.Extensions...)
+	}
+}
+
+// Duplicates returns the extensions mapped to more than one MIME type in the block
+func (t *Types) Duplicates() []DuplicateExtension {
+	return DuplicateExtensions(t)
+}
+
+func (t *Types) setDirectives(directives []IDirective) {
+	block, ok := t.Block.(*Block)
+	if !ok {
+		block = &Block{Parent: t}
+		t.Block = block
+	}
+	block.Directives = directives
+}
+
+func containsExtension(parameters []Parameter, ext string) bool {
+	for _, p := range parameters {
+		if strings.EqualFold(p.Unquoted(), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupType returns the MIME type of a file extension in types blocks that nginx reads in turn,
+// such as the ones of the files included by a context. The last MIME type listing the extension wins
+func LookupType(ext string, types ...*Types) (mime string, ok bool) {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	for _, t := range types {
+		for _, entry := range t.Entries() {
+			for _, e := range entry.Extensions {
+				if e == ext {
+					mime, ok = entry.MIME, true
+				}
+			}
+		}
+	}
+	return mime, ok
+}
+
+// DuplicateExtensions returns the extensions mapped to more than one MIME type in types blocks that
+// nginx reads in turn, such as the ones of the files included by a context
+func DuplicateExtensions(types ...*Types) []DuplicateExtension {
+	duplicates := make([]DuplicateExtension, 0)
+	seen := make(map[string]TypesEntry)
+	for _, t := range types {
+		for _, entry := range t.Entries() {
+			for _, ext := range entry.Extensions {
+				if previous, ok := seen[ext]; ok && previous.Directive != entry.Directive {
+					duplicates = append(duplicates, DuplicateExtension{
+						Extension:    ext,
+						MIME:         entry.MIME,
+						PreviousMIME: previous.MIME,
+						Directive:    entry.Directive,
+						Previous:     previous.Directive,
+					})
+				}
+				seen[ext] = entry
+			}
+		}
+	}
+	return duplicates
+}
+
+// FindDirectives finds directives by name.
+func (t *Types) FindDirectives(directiveName string) []IDirective {
+	return t.GetBlock().FindDirectives(directiveName)
+}
+
+// GetDirectives returns all directives in the types block.
+func (t *Types) GetDirectives() []IDirective {
+	return t.GetBlock().GetDirectives()
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func typesDirective(entries ...string) *Types {
+	d := geoDirective(nil, entries...)
+	d.Name = "types"
+	t, _ := NewTypes(d)
+	return t
+}
+
+func TestTypes_Lookup(t *testing.T) {
+	t.Parallel()
+	types := typesDirective("text/html html htm", "application/javascript js", "text/plain txt JS")
+
+	mime, ok := types.Lookup("html")
+	assert.Assert(t, ok)
+	assert.Equal(t, mime, "text/html")
+	mime, _ = types.Lookup(".HTM")
+	assert.Equal(t, mime, "text/html")
+	// the last MIME type wins, as in nginx
+	mime, _ = types.Lookup("js")
+	assert.Equal(t, mime, "text/plain")
+	_, ok = types.Lookup("wasm")
+	assert.Assert(t, !ok)
+
+	assert.DeepEqual(t, types.Extensions("text/html"), []string{"html", "htm"})
+	assert.DeepEqual(t, types.Extensions("application/javascript"), []string{})
+
+	duplicates := types.Duplicates()
+	assert.Equal(t, len(duplicates), 1)
+	assert.Error(t, duplicates[0], `duplicate extension "js", content type: "text/plain", previous content type: "application/javascript"`)
+}
+
+func TestTypes_Add(t *testing.T) {
+	t.Parallel()
+	types := typesDirective("text/html html htm", "application/javascript js", "text/plain txt")
+
+	types.Add("text/javascript", "js", "mjs")
+	mime, _ := types.Lookup("mjs")
+	assert.Equal(t, mime, "text/javascript")
+	assert.Equal(t, len(types.Duplicates()), 0)
+	assert.Equal(t, len(types.FindDirectives("application/javascript")), 0)
+	assert.Equal(t, types.GetDirectives()[2].GetParent(), IDirective(types))
+
+	types.Add("text/html", "shtml", "HTM")
+	assert.DeepEqual(t, types.Extensions("text/html"), []string{"html", "htm", "shtml"})
+
+	assert.Assert(t, types.RemoveExtension("txt"))
+	assert.Assert(t, !types.RemoveExtension("txt"))
+	assert.Equal(t, len(types.FindDirectives("text/plain")), 0)
+	assert.Assert(t, types.Remove("text/html"))
+	assert.Assert(t, !types.Remove("text/html"))
+	assert.Equal(t, len(types.GetDirectives()), 1)
+}
+
+func TestTypes_Merge(t *testing.T) {
+	t.Parallel()
+	types := typesDirective("text/html html", "application/octet-stream bin wasm")
+	extra := typesDirective("application/wasm wasm", "image/avif avif")
+
+	assert.Equal(t, len(DuplicateExtensions(types, extra)), 1)
+	mime, _ := LookupType("wasm", types, extra)
+	assert.Equal(t, mime, "application/wasm")
+
+	types.Merge(extra)
+	mime, _ = types.Lookup("wasm")
+	assert.Equal(t, mime, "application/wasm")
+	assert.DeepEqual(t, types.Extensions("application/octet-stream"), []string{"bin"})
+	assert.DeepEqual(t, types.Extensions("image/avif"), []string{"avif"})
+	assert.Equal(t, len(types.Duplicates()), 0)
+}
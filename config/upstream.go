@@ -12,6 +12,10 @@ type Upstream struct {
 	Directives []IDirective
 	Comment    []string
 	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
+	DefaultBraces
 	Parent IDirective
 	Line   int
 }
@@ -64,12 +68,27 @@ func (us *Upstream) GetComment() []string {
 // GetDirectives returns sub directives of the upstream.
 func (us *Upstream) GetDirectives() []IDirective {
 	directives := make([]IDirective, 0)
-	directives = append(directives, us.Directives...)
+	others, trailing := splitTrailingComment(us.Directives)
+	directives = append(directives, others...)
 	for _, uss := range us.UpstreamServers {
 		directives = append(directives, uss)
 	}
 
-	return directives
+	return append(directives, trailing...)
+}
+
+// SetDirectives replaces the directives of the upstream, the servers going to UpstreamServers.
+func (us *Upstream) SetDirectives(directives []IDirective) {
+	us.UpstreamServers = []*UpstreamServer{}
+	us.Directives = []IDirective{}
+	for _, directive := range directives {
+		if uss, ok := directive.(*UpstreamServer); ok {
+			uss.SetParent(us)
+			us.UpstreamServers = append(us.UpstreamServers, uss)
+			continue
+		}
+		us.Directives = append(us.Directives, directive)
+	}
 }
 
 // NewUpstream creates a new Upstream from a directive.
@@ -105,6 +124,11 @@ func NewUpstream(directive IDirective) (*Upstream, error) {
 
 	us.Comment = directive.GetComment()
 	us.InlineComment = directive.GetInlineComment()
+	us.Span = directive.GetSpan()
+	us.CommentSpans = directive.GetCommentSpans()
+	us.Trivia = directive.GetTrivia()
+	us.Annotations, us.AnnotationSyntax = directive.GetAnnotations(), directive.GetAnnotationSyntax()
+	us.LBrace, us.RBrace = directive.GetBlock().GetBraces()
 
 	return us, nil
 }
@@ -13,6 +13,9 @@ type UpstreamServer struct {
 	Parameters map[string]string
 	Comment    []string
 	DefaultInlineComment
+	DefaultPosition
+	DefaultTrivia
+	DefaultAnnotations
 	Parent IDirective
 	Line   int
 }
@@ -121,6 +124,10 @@ func NewUpstreamServer(directive IDirective) (*UpstreamServer, error) {
 
 	uss.Comment = directive.GetComment()
 	uss.InlineComment = directive.GetInlineComment()
+	uss.Span = directive.GetSpan()
+	uss.CommentSpans = directive.GetCommentSpans()
+	uss.Trivia = directive.GetTrivia()
+	uss.Annotations, uss.AnnotationSyntax = directive.GetAnnotations(), directive.GetAnnotationSyntax()
 
 	return uss, nil
 }
@@ -0,0 +1,2 @@
+// Package value parses and formats nginx sizes, offsets, times and flags.
+package value
@@ -0,0 +1,235 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is wrapped by the errors of the Parse functions
+var ErrInvalid = errors.New("invalid value")
+
+const (
+	kilobyte = 1 << 10
+	megabyte = 1 << 20
+	gigabyte = 1 << 30
+)
+
+// ParseSize parses an nginx size, a number of bytes with an optional k, m or g suffix
+// (case insensitive), as in client_max_body_size 10m
+func ParseSize(s string) (int64, error) {
+	scale := int64(1)
+	digits := s
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			scale = kilobyte
+		case 'm', 'M':
+			scale = megabyte
+		case 'g', 'G':
+			scale = gigabyte
+		}
+		if scale != 1 {
+			digits = s[:len(s)-1]
+		}
+	}
+
+	n, ok := parseDigits(digits)
+	if !ok || n > math.MaxInt64/scale {
+		return 0, fmt.Errorf("%w: size %q", ErrInvalid, s)
+	}
+	return n * scale, nil
+}
+
+// FormatSize formats a number of bytes as an nginx size, with the largest
+// of the g, m and k suffixes that represents it exactly
+func FormatSize(n int64) string {
+	switch {
+	case n == 0:
+		return "0"
+	case n%gigabyte == 0:
+		return fmt.Sprintf("%dg", n/gigabyte)
+	case n%megabyte == 0:
+		return fmt.Sprintf("%dm", n/megabyte)
+	case n%kilobyte == 0:
+		return fmt.Sprintf("%dk", n/kilobyte)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// ParseOffset parses an nginx offset, as in mp4_max_buffer_size or limit_rate_after,
+// it has the grammar of ParseSize
+func ParseOffset(s string) (int64, error) {
+	n, err := ParseSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: offset %q", ErrInvalid, s)
+	}
+	return n, nil
+}
+
+// FormatOffset formats an offset as FormatSize does
+func FormatOffset(n int64) string {
+	return FormatSize(n)
+}
+
+// time steps, each part of a time must use a smaller unit than the previous one
+const (
+	stepStart = iota
+	stepYear
+	stepMonth
+	stepWeek
+	stepDay
+	stepHour
+	stepMinute
+	stepSecond
+	stepMillisecond
+)
+
+const (
+	day   = 24 * time.Hour
+	week  = 7 * day
+	month = 30 * day
+	year  = 365 * day
+)
+
+// ParseDuration parses an nginx time with millisecond precision, as in proxy_read_timeout 1h30s.
+// A time is a sequence of numbers with the units w, d, h, m, s and ms, from the largest unit
+// to the smallest, optionally separated by spaces; a number without unit is in seconds
+func ParseDuration(s string) (time.Duration, error) {
+	return parseTime(s, false)
+}
+
+// ParseSeconds parses an nginx time with second precision, as in expires 1M or ssl_session_timeout 1d.
+// It has the grammar of ParseDuration, with the M (30 days) and y (365 days) units but without ms
+func ParseSeconds(s string) (time.Duration, error) {
+	return parseTime(s, true)
+}
+
+func parseTime(s string, isSec bool) (time.Duration, error) {
+	invalid := fmt.Errorf("%w: time %q", ErrInvalid, s)
+	step := stepMonth
+	if isSec {
+		step = stepStart
+	}
+
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		n := 0
+		for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		number, ok := parseDigits(rest[:n])
+		if !ok {
+			return 0, invalid
+		}
+		rest = rest[n:]
+
+		next, scale := stepSecond, time.Second
+		if rest != "" {
+			switch {
+			case rest[0] == 'y' && step < stepYear:
+				next, scale = stepYear, year
+			case rest[0] == 'M' && step < stepMonth:
+				next, scale = stepMonth, month
+			case rest[0] == 'w' && step < stepWeek:
+				next, scale = stepWeek, week
+			case rest[0] == 'd' && step < stepDay:
+				next, scale = stepDay, day
+			case rest[0] == 'h' && step < stepHour:
+				next, scale = stepHour, time.Hour
+			case strings.HasPrefix(rest, "ms") && !isSec && step < stepMillisecond:
+				next, scale = stepMillisecond, time.Millisecond
+				rest = rest[1:]
+			case rest[0] == 'm' && step < stepMinute:
+				next, scale = stepMinute, time.Minute
+			case rest[0] == 's' && step < stepSecond:
+			case rest[0] == ' ' && step < stepSecond:
+				// a number without unit, no part is allowed after it
+				next = stepMillisecond + 1
+			default:
+				return 0, invalid
+			}
+			rest = strings.TrimLeft(rest[1:], " ")
+		} else if step >= stepSecond {
+			return 0, invalid
+		}
+
+		if number > int64((math.MaxInt64-total)/scale) {
+			return 0, invalid
+		}
+		total += time.Duration(number) * scale
+		step = next
+	}
+
+	if s == "" {
+		return 0, invalid
+	}
+	return total, nil
+}
+
+// FormatDuration formats a duration as an nginx time with the d, h, m, s and ms units,
+// such as 1h30s. Durations are truncated to milliseconds, negative durations format as 0s
+func FormatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return "0s"
+	}
+
+	var buf strings.Builder
+	for _, unit := range []struct {
+		suffix string
+		scale  time.Duration
+	}{
+		{"d", day},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+		{"ms", time.Millisecond},
+	} {
+		if n := d / unit.scale; n > 0 {
+			fmt.Fprintf(&buf, "%d%s", n, unit.suffix)
+			d -= n * unit.scale
+		}
+	}
+	return buf.String()
+}
+
+// ParseFlag parses an nginx flag, on or off (case insensitive), as in sendfile on
+func ParseFlag(s string) (bool, error) {
+	switch {
+	case strings.EqualFold(s, "on"):
+		return true, nil
+	case strings.EqualFold(s, "off"):
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: flag %q, it must be \"on\" or \"off\"", ErrInvalid, s)
+}
+
+// FormatFlag formats a flag as on or off
+func FormatFlag(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// parseDigits parses a non-empty sequence of decimal digits
+func parseDigits(s string) (int64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	var n int64
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		digit := int64(s[i] - '0')
+		if n > (math.MaxInt64-digit)/10 {
+			return 0, false
+		}
+		n = n*10 + digit
+	}
+	return n, true
+}
@@ -0,0 +1,146 @@
+package value
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "512", want: 512},
+		{in: "8k", want: 8 << 10},
+		{in: "10m", want: 10 << 20},
+		{in: "10M", want: 10 << 20},
+		{in: "2g", want: 2 << 30},
+		{in: "", wantErr: true},
+		{in: "k", wantErr: true},
+		{in: "1.5m", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "10mb", wantErr: true},
+		{in: "9999999999999g", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseSize(tt.in)
+			if tt.wantErr {
+				assert.Assert(t, errors.Is(err, ErrInvalid))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+
+			offset, err := ParseOffset(tt.in)
+			assert.NilError(t, err)
+			assert.Equal(t, offset, tt.want)
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, FormatSize(0), "0")
+	assert.Equal(t, FormatSize(1000), "1000")
+	assert.Equal(t, FormatSize(8<<10), "8k")
+	assert.Equal(t, FormatSize(1536<<10), "1536k")
+	assert.Equal(t, FormatSize(10<<20), "10m")
+	assert.Equal(t, FormatSize(3<<30), "3g")
+	assert.Equal(t, FormatOffset(1<<20), "1m")
+}
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "65", want: 65 * time.Second},
+		{in: "500ms", want: 500 * time.Millisecond},
+		{in: "1h30s", want: time.Hour + 30*time.Second},
+		{in: "1h 30m", want: 90 * time.Minute},
+		{in: "1w2d", want: 9 * 24 * time.Hour},
+		{in: "1m500ms", want: time.Minute + 500*time.Millisecond},
+		{in: "1h30", want: time.Hour + 30*time.Second},
+		{in: "", wantErr: true},
+		{in: "s", wantErr: true},
+		{in: "30s1h", wantErr: true},
+		{in: "1h1h", wantErr: true},
+		{in: "1s30", wantErr: true},
+		{in: "30 5", wantErr: true},
+		{in: "1M", wantErr: true},
+		{in: "1y", wantErr: true},
+		{in: "1x", wantErr: true},
+		{in: "999999999999y", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseDuration(tt.in)
+			if tt.wantErr {
+				assert.Assert(t, errors.Is(err, ErrInvalid))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseSeconds(t *testing.T) {
+	t.Parallel()
+	got, err := ParseSeconds("1y1M")
+	assert.NilError(t, err)
+	assert.Equal(t, got, 395*24*time.Hour)
+
+	got, err = ParseSeconds("1d")
+	assert.NilError(t, err)
+	assert.Equal(t, got, 24*time.Hour)
+
+	_, err = ParseSeconds("500ms")
+	assert.Assert(t, errors.Is(err, ErrInvalid))
+	_, err = ParseSeconds("1M1y")
+	assert.Assert(t, errors.Is(err, ErrInvalid))
+}
+
+func TestFormatDuration(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, FormatDuration(0), "0s")
+	assert.Equal(t, FormatDuration(-time.Second), "0s")
+	assert.Equal(t, FormatDuration(65*time.Second), "1m5s")
+	assert.Equal(t, FormatDuration(time.Hour+30*time.Second), "1h30s")
+	assert.Equal(t, FormatDuration(50*time.Hour), "2d2h")
+	assert.Equal(t, FormatDuration(1500*time.Millisecond+time.Microsecond), "1s500ms")
+
+	for _, d := range []time.Duration{time.Millisecond, 90 * time.Minute, 400 * 24 * time.Hour} {
+		got, err := ParseDuration(FormatDuration(d))
+		assert.NilError(t, err)
+		assert.Equal(t, got, d)
+	}
+}
+
+func TestParseFlag(t *testing.T) {
+	t.Parallel()
+	on, err := ParseFlag("on")
+	assert.NilError(t, err)
+	assert.Assert(t, on)
+	on, err = ParseFlag("OFF")
+	assert.NilError(t, err)
+	assert.Assert(t, !on)
+	_, err = ParseFlag("yes")
+	assert.Error(t, err, `invalid value: flag "yes", it must be "on" or "off"`)
+	assert.Equal(t, FormatFlag(true), "on")
+	assert.Equal(t, FormatFlag(false), "off")
+}
@@ -0,0 +1,98 @@
+package dumper
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+)
+
+// UnsafeParameterError is returned for a parameter that would not be read back as is
+// once dumped, e.g. a value set from user input that contains a ';' or an unbalanced quote
+type UnsafeParameterError struct {
+	Directive config.IDirective
+	Index     int // index of the parameter in the directive parameters
+	Err       error
+}
+
+func (e *UnsafeParameterError) Error() string {
+	return fmt.Sprintf("unsafe parameter in \"%s\" directive: %v", e.Directive.GetName(), e.Err)
+}
+
+func (e *UnsafeParameterError) Unwrap() error {
+	return e.Err
+}
+
+// UnsafeCommentError is returned for a comment or an annotation that would not be read back as a comment
+// once dumped, e.g. an annotation value holding a line break followed by a directive
+type UnsafeCommentError struct {
+	Directive config.IDirective
+	Err       error
+}
+
+func (e *UnsafeCommentError) Error() string {
+	return fmt.Sprintf("unsafe comment in \"%s\" directive: %v", e.Directive.GetName(), e.Err)
+}
+
+func (e *UnsafeCommentError) Unwrap() error {
+	return e.Err
+}
+
+// CheckDirective checks that every parameter of the directive and of its block
+// would re-tokenise as the same parameter once dumped, see config.Parameter.Validate,
+// and that its comments and annotations stay comments, see config.ValidateComment
+func CheckDirective(d config.IDirective) error {
+	if err := checkComments(d); err != nil {
+		return &UnsafeCommentError{Directive: d, Err: err}
+	}
+	for i, parameter := range d.GetParameters() {
+		if err := parameter.Validate(); err != nil {
+			return &UnsafeParameterError{Directive: d, Index: i, Err: err}
+		}
+	}
+	if d.GetBlock() == nil {
+		return nil
+	}
+	return CheckBlock(d.GetBlock())
+}
+
+func checkComments(d config.IDirective) error {
+	for _, comment := range d.GetComment() {
+		if err := config.ValidateComment(comment); err != nil {
+			return err
+		}
+	}
+	for _, comment := range d.GetInlineComment() {
+		if err := config.ValidateComment(comment.Value); err != nil {
+			return err
+		}
+	}
+	annotations := d.GetAnnotations()
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := d.GetAnnotationSyntax().Validate(key, annotations[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckBlock checks the parameters of every directive of the block, see CheckDirective
+func CheckBlock(b config.IBlock) error {
+	for _, directive := range b.GetDirectives() {
+		if err := CheckDirective(directive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckConfig checks the parameters, comments and annotations of every directive of the config, see CheckDirective.
+// WriteConfig refuses to write a config that does not pass it
+func CheckConfig(c *config.Config) error {
+	return CheckBlock(c.Block)
+}
@@ -0,0 +1,73 @@
+package dumper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckConfig(t *testing.T) {
+	t.Parallel()
+	headers := &config.Directive{Name: "add_header", Parameters: []config.Parameter{{Value: "X-User"}, {Value: "a; evil on"}}}
+	c := &config.Config{
+		Block: &config.Block{Directives: []config.IDirective{
+			&config.Directive{Name: "server", Block: &config.Block{Directives: []config.IDirective{headers}}},
+		}},
+		FilePath: filepath.Join(t.TempDir(), "nginx.conf"),
+	}
+
+	err := CheckConfig(c)
+	var unsafeErr *UnsafeParameterError
+	assert.Assert(t, errors.As(err, &unsafeErr))
+	assert.Equal(t, unsafeErr.Directive, config.IDirective(headers))
+	assert.Equal(t, unsafeErr.Index, 1)
+	assert.Error(t, err, `unsafe parameter in "add_header" directive: parameter "a; evil on" must be quoted`)
+
+	// nothing is written
+	assert.Assert(t, errors.As(WriteConfig(c, IndentedStyle, false), &unsafeErr))
+	_, err = os.Stat(c.FilePath)
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+
+	headers.Parameters[1].SetUnquoted("a; evil on")
+	assert.NilError(t, CheckConfig(c))
+	assert.NilError(t, WriteConfig(c, IndentedStyle, false))
+	data, err := os.ReadFile(c.FilePath)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "server {\n    add_header X-User \"a; evil on\";\n}")
+}
+
+func TestCheckConfig_Comments(t *testing.T) {
+	t.Parallel()
+	listen := &config.Directive{Name: "listen", Parameters: []config.Parameter{{Value: "80"}}}
+	c := &config.Config{Block: &config.Block{Directives: []config.IDirective{
+		&config.Directive{Name: "server", Block: &config.Block{Directives: []config.IDirective{listen}}},
+	}}}
+
+	// a value set directly in the map skips SetAnnotation, the dump would hold a directive
+	listen.SetAnnotations(map[string]string{"owner": "x\nworker_processes 99;"})
+	assert.Assert(t, strings.Contains(DumpConfig(c, IndentedStyle), "\nworker_processes 99;"))
+	err := CheckConfig(c)
+	var commentErr *UnsafeCommentError
+	assert.Assert(t, errors.As(err, &commentErr))
+	assert.Equal(t, commentErr.Directive, config.IDirective(listen))
+	assert.Error(t, err, `unsafe comment in "listen" directive: annotation "owner" must not contain a line break`)
+	assert.Assert(t, errors.As(WriteConfig(c, IndentedStyle, false), &commentErr))
+
+	listen.SetAnnotations(nil)
+	listen.Comment = []string{"# ok", "worker_processes 99;"}
+	assert.Error(t, CheckConfig(c), `unsafe comment in "listen" directive: comment "worker_processes 99;" must start with #`)
+	listen.Comment = []string{"# x\nworker_processes 99;"}
+	assert.Error(t, CheckConfig(c), `unsafe comment in "listen" directive: comment "# x\nworker_processes 99;" must not contain a line break`)
+	listen.Comment = nil
+	listen.InlineComment = []config.InlineComment{{Value: "# x\nworker_processes 99;"}}
+	assert.Assert(t, errors.As(CheckConfig(c), &commentErr))
+
+	listen.InlineComment = []config.InlineComment{{Value: "# port"}}
+	assert.NilError(t, listen.SetAnnotation("owner", "payments"))
+	assert.NilError(t, CheckConfig(c))
+}
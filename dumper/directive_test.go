@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/tufanbarisyildirim/gonginx/config"
+	"gotest.tools/v3/assert"
 )
 
 func TestDirective_ToString(t *testing.T) {
@@ -96,3 +97,23 @@ func TestDirective_ToString(t *testing.T) {
 		})
 	}
 }
+
+func TestDumpDirective_Comment(t *testing.T) {
+	t.Parallel()
+	block := &config.Block{Directives: []config.IDirective{
+		&config.Directive{Name: "listen", Parameters: []config.Parameter{{Value: "80"}}},
+		config.NewComment("# root /a;", "# index index.html;"),
+	}}
+	server := &config.Directive{Name: "server", Block: block}
+	assert.Equal(t, DumpDirective(server, IndentedStyle), "server {\n    listen 80;\n    # root /a;\n    # index index.html;\n}")
+	assert.Equal(t, DumpDirective(server, LosslessStyle), "server {\n    listen 80;\n    # root /a;\n    # index index.html;\n}")
+}
+
+func TestDumpDirective_Annotations(t *testing.T) {
+	t.Parallel()
+	d := &config.Directive{Name: "listen", Parameters: []config.Parameter{{Value: "80"}}, Comment: []string{"# public", "# @owner: payments"}}
+	d.SetAnnotationSyntax(config.AnnotationSyntax{Prefix: "@", Separator: ": "})
+	d.SetAnnotation("owner", "billing")
+	d.SetAnnotation("ticket", "OPS-123")
+	assert.Equal(t, DumpDirective(d, IndentedStyle), "# public\n# @owner: billing\n# @ticket: OPS-123\nlisten 80;")
+}
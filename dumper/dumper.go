@@ -44,6 +44,15 @@ var (
 		Indent:            0,
 		Debug:             false,
 	}
+
+	//LosslessStyle re-emits unmodified directives parsed with parser.WithPreserveTrivia byte-for-byte
+	LosslessStyle = &Style{
+		SortDirectives: false,
+		StartIndent:    0,
+		Indent:         4,
+		Debug:          false,
+		PreserveTrivia: true,
+	}
 )
 
 // Style dumping style
@@ -55,6 +64,11 @@ type Style struct {
 	Debug                bool
 	DisableLuaFormatting bool
 	LuaFormatter         LuaFormatterFunc
+	// CodeFormatters format the code of raw-content blocks, by language
+	CodeFormatters map[string]CodeFormatterFunc
+	// PreserveTrivia re-emits directives that carry source trivia as they were parsed,
+	// only re-rendering the ones that were modified. Directive order follows the source.
+	PreserveTrivia bool
 }
 
 // NewStyle create new style
@@ -78,6 +92,8 @@ func (s *Style) Iterate() *Style {
 		Debug:                s.Debug,
 		DisableLuaFormatting: s.DisableLuaFormatting,
 		LuaFormatter:         s.LuaFormatter,
+		CodeFormatters:       s.CodeFormatters,
+		PreserveTrivia:       s.PreserveTrivia,
 	}
 	return newStyle
 }
@@ -94,24 +110,61 @@ func (s *Style) WithLuaFormatter(formatter LuaFormatterFunc) *Style {
 	return s
 }
 
+// WithCodeFormatter sets the formatter of the code of raw-content blocks written in language, e.g. "js".
+// The code of the languages without formatter is dumped verbatim
+func (s *Style) WithCodeFormatter(language string, formatter CodeFormatterFunc) *Style {
+	formatters := make(map[string]CodeFormatterFunc, len(s.CodeFormatters)+1)
+	for l, f := range s.CodeFormatters {
+		formatters[l] = f
+	}
+	formatters[language] = formatter
+	s.CodeFormatters = formatters
+	return s
+}
+
 // DumpDirective convert a directive to a string
 func DumpDirective(d config.IDirective, style *Style) string {
 	if d == nil {
 		return ""
 	}
 
+	if style.PreserveTrivia {
+		return dumpDirectiveWithTrivia(d, style, strings.Repeat(" ", style.StartIndent), newlineOf([]config.IDirective{d}))
+	}
+
+	if comment, ok := d.(*config.Comment); ok {
+		indent := strings.Repeat(" ", style.StartIndent)
+		return indent + strings.Join(comment.Comment, "\n"+indent)
+	}
+
 	var buf bytes.Buffer
 
 	if style.SpaceBeforeBlocks && d.GetBlock() != nil {
 		buf.WriteString("\n")
 	}
-	// outline comment
-	if len(d.GetComment()) > 0 {
-		for _, comment := range d.GetComment() {
+	buf.WriteString(dumpDirectiveHead(d, style))
+	if d.GetBlock() != nil {
+		buf.WriteString(DumpBlock(d.GetBlock(), style.Iterate()))
+		buf.WriteString(fmt.Sprintf("\n%s}", strings.Repeat(" ", style.StartIndent)))
+	}
+	return buf.String()
+}
+
+// dumpDirectiveHead writes the comments, name and parameters of a directive,
+// followed by its semicolon and inline comment or by the opening of its block
+func dumpDirectiveHead(d config.IDirective, style *Style) string {
+	var buf bytes.Buffer
+
+	// outline comment, with the annotations as they are now
+	if comments := config.AnnotatedComment(d); len(comments) > 0 {
+		for _, comment := range comments {
 			buf.WriteString(fmt.Sprintf("%s%s\n", strings.Repeat(" ", style.StartIndent), comment))
 		}
 	}
 	buf.WriteString(fmt.Sprintf("%s%s", strings.Repeat(" ", style.StartIndent), d.GetName()))
+	if _, ok := d.GetParent().(*config.Types); ok && d.GetBlock() == nil && len(d.GetParameters()) > 0 {
+		return buf.String() + dumpTypesEntry(d, style)
+	}
 
 	inlineComments := make(map[int]config.InlineComment)
 	for _, comment := range d.GetInlineComment() {
@@ -146,8 +199,6 @@ func DumpDirective(d config.IDirective, style *Style) string {
 		}
 	} else {
 		buf.WriteString(" {\n")
-		buf.WriteString(DumpBlock(d.GetBlock(), style.Iterate()))
-		buf.WriteString(fmt.Sprintf("\n%s}", strings.Repeat(" ", style.StartIndent)))
 	}
 	return buf.String()
 }
@@ -155,9 +206,16 @@ func DumpDirective(d config.IDirective, style *Style) string {
 // DumpBlock convert a directive to a string
 func DumpBlock(b config.IBlock, style *Style) string {
 	if b.GetCodeBlock() != "" {
+		if codeLanguage(b) != "" {
+			return DumpRawBlock(b, style)
+		}
 		return DumpLuaBlock(b, style)
 	}
 
+	if style.PreserveTrivia {
+		return dumpBlockWithTrivia(b, style, strings.Repeat(" ", style.StartIndent), newlineOf(b.GetDirectives()), false)
+	}
+
 	var buf bytes.Buffer
 	directives := append([]config.IDirective(nil), b.GetDirectives()...)
 	if style.SortDirectives {
@@ -184,6 +242,9 @@ func DumpBlock(b config.IBlock, style *Style) string {
 
 // DumpConfig dump whole config
 func DumpConfig(c *config.Config, style *Style) string {
+	if style.PreserveTrivia {
+		return DumpBlock(c.Block, style) + c.TrailingTrivia
+	}
 	return DumpBlock(c.Block, style)
 }
 
@@ -196,9 +257,23 @@ func DumpInclude(i *config.Include, style *Style) map[string]string {
 	return mp
 }
 
-// WriteConfig writes config
+// WriteConfig writes config, along with the Lua files moved out of it by config.ExtractLua.
+// It writes nothing when a parameter would not be read back as is, see CheckConfig
 func WriteConfig(c *config.Config, style *Style, writeInclude bool) error {
+	if err := CheckConfig(c); err != nil {
+		return err
+	}
 	if writeInclude {
+		for _, include := range c.FindDirectives("include") {
+			if i, ok := include.(*config.Include); ok {
+				for _, cfg := range i.Configs {
+					if err := CheckConfig(cfg); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
 		includes := c.FindDirectives("include")
 		for _, include := range includes {
 			i, ok := include.(*config.Include)
@@ -226,6 +301,14 @@ func WriteConfig(c *config.Config, style *Style, writeInclude bool) error {
 			}
 		}
 	}
+	for _, file := range luaFiles(c.Block, writeInclude) {
+		if err := os.MkdirAll(filepath.Dir(file.FilePath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file.FilePath, []byte(file.Code), 0644); err != nil {
+			return err
+		}
+	}
 	// create parent directories, if not exit
 	dir, _ := filepath.Split(c.FilePath)
 	if dir != "" {
@@ -236,3 +319,26 @@ func WriteConfig(c *config.Config, style *Style, writeInclude bool) error {
 	}
 	return os.WriteFile(c.FilePath, []byte(DumpConfig(c, style)), 0644)
 }
+
+// luaFiles returns the Lua files of the block with their code in memory, see config.ExtractLua
+func luaFiles(b config.IBlock, withIncludes bool) []*config.LuaFile {
+	files := make([]*config.LuaFile, 0)
+	for _, directive := range b.GetDirectives() {
+		switch d := directive.(type) {
+		case *config.LuaFile:
+			files = append(files, d)
+		case *config.Include:
+			if withIncludes {
+				for _, c := range d.Configs {
+					files = append(files, luaFiles(c.Block, withIncludes)...)
+				}
+			}
+		case *config.LuaBlock:
+		default:
+			if d.GetBlock() != nil {
+				files = append(files, luaFiles(d.GetBlock(), withIncludes)...)
+			}
+		}
+	}
+	return files
+}
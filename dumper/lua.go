@@ -14,19 +14,26 @@ const hashCommentSentinel = "__GONGINX_HASH_COMMENT__"
 
 var hashCommentRestoreRegex = regexp.MustCompile(`--\s*` + hashCommentSentinel + `\s*`)
 
+// longBracketRegex matches the opening of a long string or of a block comment, [[ or [==[ after --
+var longBracketRegex = regexp.MustCompile(`\[=*\[`)
+
 // LuaFormatterFunc lets callers override Lua formatting implementation.
 type LuaFormatterFunc func(code string, style *Style) (string, error)
 
 // DumpLuaBlock convert a lua block to a string
 func DumpLuaBlock(b config.IBlock, style *Style) (luaCode string) {
-	luaCode = b.GetCodeBlock()
+	code := b.GetCodeBlock()
+	// the parsed code is the verbatim body of the block, with the line ends and indentation around it
+	luaCode = strings.TrimSpace(code)
 
 	if luaCode == "" {
 		return ""
 	}
 
-	if style.DisableLuaFormatting {
-		return strings.TrimRight(luaCode, "\n")
+	// the formatter and the hash comment conversion read the code line by line and lose or rewrite
+	// what long strings and block comments hold, such code is kept verbatim
+	if style.DisableLuaFormatting || longBracketRegex.MatchString(luaCode) {
+		return strings.TrimRight(trimLeadingBlankLines(code), " \t\r\n")
 	}
 
 	converted := convertHashComments(luaCode)
@@ -40,6 +47,17 @@ func DumpLuaBlock(b config.IBlock, style *Style) (luaCode string) {
 	return strings.TrimRight(indentLuaCode(formatted, style.StartIndent), "\n")
 }
 
+// trimLeadingBlankLines removes the blank lines before the first line of code, keeping its indentation
+func trimLeadingBlankLines(code string) string {
+	for {
+		line, rest, found := strings.Cut(code, "\n")
+		if !found || strings.TrimSpace(line) != "" {
+			return code
+		}
+		code = rest
+	}
+}
+
 func formatLuaCode(luaCode string, style *Style) (formatted string, err error) {
 	if style.LuaFormatter != nil {
 		return style.LuaFormatter(luaCode, style)
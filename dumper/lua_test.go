@@ -1,6 +1,8 @@
 package dumper
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -112,3 +114,41 @@ func TestDumpLuaBlock_UsesCustomFormatter(t *testing.T) {
 	assert.Assert(t, called)
 	assert.Equal(t, got, "    return 42")
 }
+
+func TestWriteConfig_ExtractedLua(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	included := &config.Config{
+		FilePath: filepath.Join(tmp, "conf.d", "lua.conf"),
+		Block: &config.Block{Directives: []config.IDirective{
+			&config.LuaBlock{Name: "init_worker_by_lua_block", LuaCode: "\n    ngx.log(ngx.INFO, 'worker')\n"},
+		}},
+	}
+	include, err := config.NewInclude(&config.Directive{Name: "include", Parameters: []config.Parameter{{Value: "conf.d/lua.conf"}}})
+	assert.NilError(t, err)
+	include.Configs = []*config.Config{included}
+	c := &config.Config{
+		FilePath: filepath.Join(tmp, "nginx.conf"),
+		Block: &config.Block{Directives: []config.IDirective{
+			include,
+			&config.Directive{Name: "location", Parameters: []config.Parameter{{Value: "/"}}, Block: &config.Block{Directives: []config.IDirective{
+				&config.LuaBlock{Name: "content_by_lua_block", LuaCode: "\n        ngx.say('hello')\n    "},
+			}}},
+		}},
+	}
+
+	_, err = config.ExtractLua(c)
+	assert.NilError(t, err)
+	assert.NilError(t, WriteConfig(c, IndentedStyle, true))
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(tmp, name))
+		assert.NilError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, read("nginx.conf"), "include conf.d/lua.conf;\nlocation / {\n    content_by_lua_file lua/content_2.lua;\n}")
+	assert.Equal(t, read("conf.d/lua.conf"), "init_worker_by_lua_file lua/init_worker_1.lua;")
+	assert.Equal(t, read("lua/init_worker_1.lua"), "ngx.log(ngx.INFO, 'worker')\n")
+	assert.Equal(t, read("lua/content_2.lua"), "ngx.say('hello')\n")
+}
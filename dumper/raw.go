@@ -0,0 +1,42 @@
+package dumper
+
+import (
+	"strings"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+)
+
+// CodeFormatterFunc formats the code of a raw-content block, without indentation
+type CodeFormatterFunc func(code string, style *Style) (string, error)
+
+// DumpRawBlock convert the body of a raw-content block to a string, see parser.WithRawBlocks.
+// The code is formatted by the formatter registered for its language with WithCodeFormatter,
+// it is kept verbatim when there is none or when formatting fails
+func DumpRawBlock(b config.IBlock, style *Style) string {
+	code := b.GetCodeBlock()
+	verbatim := strings.TrimRight(trimLeadingBlankLines(code), " \t\r\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+
+	formatter, ok := style.CodeFormatters[codeLanguage(b)]
+	if !ok {
+		return verbatim
+	}
+	formatted, err := formatter(strings.TrimSpace(code), style)
+	if err != nil {
+		return verbatim
+	}
+	return strings.TrimRight(indentLuaCode(formatted, style.StartIndent), "\n")
+}
+
+// codeLanguage returns the language of a raw-content block other than Lua, empty for Lua blocks
+func codeLanguage(b config.IBlock) string {
+	switch b := b.(type) {
+	case *config.RawBlock:
+		return b.Language
+	case *config.Block:
+		return b.Language
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package dumper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"gotest.tools/v3/assert"
+)
+
+func TestDumpRawBlock(t *testing.T) {
+	t.Parallel()
+	block := &config.RawBlock{Name: "js_block", Language: "js", Code: "\n  function f() {\n  return 1;\n  }\n"}
+	d := &config.Directive{Name: "location", Parameters: []config.Parameter{{Value: "/"}}, Block: &config.Block{Directives: []config.IDirective{block}}}
+
+	// verbatim without formatter
+	assert.Equal(t, DumpDirective(d, NewStyle()), "location / {\n    js_block {\n  function f() {\n  return 1;\n  }\n    }\n}")
+
+	upper := func(code string, style *Style) (string, error) {
+		return strings.ToUpper(code), nil
+	}
+	style := NewStyle().WithCodeFormatter("js", upper)
+	assert.Equal(t, DumpDirective(d, style), "location / {\n    js_block {\n        FUNCTION F() {\n          RETURN 1;\n          }\n    }\n}")
+
+	// the formatters of other languages are not used and formatting errors keep the code
+	failing := func(string, *Style) (string, error) {
+		return "", errors.New("invalid")
+	}
+	assert.Equal(t, DumpRawBlock(block, NewStyle().WithCodeFormatter("perl", upper)), "  function f() {\n  return 1;\n  }")
+	assert.Equal(t, DumpRawBlock(block, NewStyle().WithCodeFormatter("js", failing)), "  function f() {\n  return 1;\n  }")
+	assert.Equal(t, DumpRawBlock(&config.RawBlock{Language: "js", Code: " \n "}, style), "")
+}
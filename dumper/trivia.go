@@ -0,0 +1,144 @@
+package dumper
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+)
+
+// dumpBlockWithTrivia writes the directives of a block using their source trivia.
+// indent is used for directives that have no trivia (added after parsing),
+// leadingNewline tells whether the first of them should start on a new line.
+func dumpBlockWithTrivia(b config.IBlock, style *Style, indent string, newline string, leadingNewline bool) string {
+	var buf strings.Builder
+	directives := sourceOrder(b.GetDirectives())
+
+	// follow the indentation the source already uses in this block
+	for _, d := range directives {
+		if t := d.GetTrivia(); t != nil {
+			if i := strings.LastIndex(t.Leading, "\n"); i >= 0 {
+				indent = t.Leading[i+1:]
+				break
+			}
+		}
+	}
+
+	for i, d := range directives {
+		t := d.GetTrivia()
+		if t == nil {
+			if i > 0 || leadingNewline {
+				buf.WriteString(newline)
+				buf.WriteString(indent)
+			}
+			buf.WriteString(dumpDirectiveWithTrivia(d, style, indent, newline))
+			continue
+		}
+
+		buf.WriteString(t.Leading)
+		directiveIndent := indent
+		if j := strings.LastIndex(t.Leading, "\n"); j >= 0 {
+			directiveIndent = t.Leading[j+1:]
+		}
+		buf.WriteString(dumpDirectiveWithTrivia(d, style, directiveIndent, newline))
+	}
+	return buf.String()
+}
+
+// dumpDirectiveWithTrivia writes a directive, without its leading trivia.
+// Unmodified parts are copied from the source, the rest is rendered with style.
+func dumpDirectiveWithTrivia(d config.IDirective, style *Style, indent string, newline string) string {
+	plain := style.Iterate()
+	plain.StartIndent = 0
+	plain.PreserveTrivia = false
+
+	t := d.GetTrivia()
+	block := d.GetBlock()
+	isCode := block == nil || block.GetCodeBlock() != ""
+
+	var buf strings.Builder
+	switch {
+	case t == nil && isCode:
+		return reindent(DumpDirective(d, plain), indent, newline)
+	case t == nil:
+		buf.WriteString(reindent(strings.TrimSuffix(dumpDirectiveHead(d, plain), "\n"), indent, newline))
+	case !t.Modified(d):
+		buf.WriteString(t.Head)
+	case isCode:
+		return reindent(DumpDirective(d, plain), indent, newline)
+	default:
+		buf.WriteString(reindent(strings.TrimSuffix(dumpDirectiveHead(d, plain), "\n"), indent, newline))
+	}
+
+	if isCode {
+		return buf.String()
+	}
+
+	childIndent := indent + strings.Repeat(" ", style.Indent)
+	buf.WriteString(dumpBlockWithTrivia(block, style, childIndent, newline, true))
+	if t != nil && t.Closing != "" {
+		buf.WriteString(t.Closing)
+	} else {
+		buf.WriteString(newline)
+		buf.WriteString(indent)
+		buf.WriteString("}")
+	}
+	return buf.String()
+}
+
+// sourceOrder sorts directives by their source offset. Directives added after
+// parsing stay right after the directive that precedes them.
+func sourceOrder(directives []config.IDirective) []config.IDirective {
+	keys := make([]int, len(directives))
+	key := -1
+	for i, d := range directives {
+		if d.GetTrivia() != nil && d.GetSpan().IsValid() {
+			key = d.GetSpan().Start.Offset
+		}
+		keys[i] = key
+	}
+
+	index := make([]int, len(directives))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		return keys[index[i]] < keys[index[j]]
+	})
+
+	sorted := make([]config.IDirective, 0, len(directives))
+	for _, i := range index {
+		sorted = append(sorted, directives[i])
+	}
+	return sorted
+}
+
+// newlineOf returns the line ending used by the source of the directives
+func newlineOf(directives []config.IDirective) string {
+	for _, d := range directives {
+		t := d.GetTrivia()
+		if t == nil {
+			continue
+		}
+		for _, s := range []string{t.Leading, t.Head, t.Closing} {
+			if i := strings.Index(s, "\n"); i >= 0 {
+				if i > 0 && s[i-1] == '\r' {
+					return "\r\n"
+				}
+				return "\n"
+			}
+		}
+	}
+	return "\n"
+}
+
+// reindent prefixes every line but the first with indent and converts line endings
+func reindent(s string, indent string, newline string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, newline)
+}
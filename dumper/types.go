@@ -0,0 +1,33 @@
+package dumper
+
+import (
+	"strings"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+)
+
+// typesColumn is the column, after the indentation, where the mime.types file of nginx aligns the extensions
+const typesColumn = 49
+
+// dumpTypesEntry writes the extensions of a types block entry aligned as in the mime.types file of nginx,
+// on the next line when the MIME type is too long, followed by the semicolon and the inline comment
+func dumpTypesEntry(d config.IDirective, style *Style) string {
+	var buf strings.Builder
+	if pad := typesColumn - len(d.GetName()); pad > 0 {
+		buf.WriteString(strings.Repeat(" ", pad))
+	} else {
+		buf.WriteString("\n")
+		buf.WriteString(strings.Repeat(" ", style.StartIndent+typesColumn))
+	}
+
+	extensions := make([]string, 0, len(d.GetParameters()))
+	for _, parameter := range d.GetParameters() {
+		extensions = append(extensions, parameter.GetValue())
+	}
+	buf.WriteString(strings.Join(extensions, " "))
+	buf.WriteRune(';')
+	for _, comment := range d.GetInlineComment() {
+		buf.WriteString(comment.Value)
+	}
+	return buf.String()
+}
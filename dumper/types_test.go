@@ -0,0 +1,22 @@
+package dumper
+
+import (
+	"testing"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"gotest.tools/v3/assert"
+)
+
+func TestDumpTypes(t *testing.T) {
+	t.Parallel()
+	types, err := config.NewTypes(&config.Directive{Name: "types", Block: &config.Block{}})
+	assert.NilError(t, err)
+	types.Add("text/html", "html", "htm")
+	types.Add("application/vnd.openxmlformats-officedocument.wordprocessingml.document", "docx")
+
+	assert.Equal(t, DumpDirective(types, IndentedStyle), `types {
+    text/html                                        html htm;
+    application/vnd.openxmlformats-officedocument.wordprocessingml.document
+                                                     docx;
+}`)
+}
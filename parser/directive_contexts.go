@@ -0,0 +1,918 @@
+package parser
+
+import (
+	"strings"
+)
+
+// Context is a set of nginx configuration contexts, such as http or server
+type Context uint32
+
+// Configuration contexts, as named in the nginx documentation
+const (
+	ContextMain Context = 1 << iota
+	ContextEvents
+	ContextHTTP
+	ContextServer
+	ContextLocation
+	ContextIfInServer
+	ContextIfInLocation
+	ContextLimitExcept
+	ContextUpstream
+	ContextStream
+	ContextStreamServer
+	ContextStreamUpstream
+	ContextMail
+	ContextMailServer
+)
+
+// ContextStreamAll is the set of the contexts of the stream subsystem
+const ContextStreamAll = ContextStream | ContextStreamServer | ContextStreamUpstream
+
+// ContextMailAll is the set of the contexts of the mail subsystem
+const ContextMailAll = ContextMail | ContextMailServer
+
+var contextNames = []struct {
+	context Context
+	name    string
+}{
+	{ContextMain, "main"},
+	{ContextEvents, "events"},
+	{ContextHTTP, "http"},
+	{ContextServer, "server"},
+	{ContextLocation, "location"},
+	{ContextIfInServer, "if_in_server"},
+	{ContextIfInLocation, "if_in_location"},
+	{ContextLimitExcept, "limit_except"},
+	{ContextUpstream, "upstream"},
+	{ContextStream, "stream"},
+	{ContextStreamServer, "stream_server"},
+	{ContextStreamUpstream, "stream_upstream"},
+	{ContextMail, "mail"},
+	{ContextMailServer, "mail_server"},
+}
+
+// String returns the names of the contexts in the set, separated by commas
+func (c Context) String() string {
+	names := make([]string, 0)
+	for _, cn := range contextNames {
+		if c&cn.context != 0 {
+			names = append(names, cn.name)
+		}
+	}
+	if len(names) == 0 {
+		return "unknown"
+	}
+	return strings.Join(names, ", ")
+}
+
+// blockContext returns the context opened by a block directive inside parent,
+// 0 when the block content is not made of regular directives (map, types, lua code...)
+func blockContext(name string, parent Context) Context {
+	switch name {
+	case "events":
+		return ContextEvents
+	case "http":
+		return ContextHTTP
+	case "stream":
+		return ContextStream
+	case "mail":
+		return ContextMail
+	case "server":
+		switch parent {
+		case ContextHTTP:
+			return ContextServer
+		case ContextStream:
+			return ContextStreamServer
+		case ContextMail:
+			return ContextMailServer
+		}
+	case "upstream":
+		switch parent {
+		case ContextHTTP:
+			return ContextUpstream
+		case ContextStream:
+			return ContextStreamUpstream
+		}
+	case "location":
+		return ContextLocation
+	case "if":
+		switch parent {
+		case ContextServer:
+			return ContextIfInServer
+		case ContextLocation:
+			return ContextIfInLocation
+		}
+	case "limit_except":
+		return ContextLimitExcept
+	}
+	return 0
+}
+
+// got the contexts from: https://nginx.org/en/docs/dirindex.html and
+// https://github.com/openresty/lua-nginx-module?tab=readme-ov-file#directives
+// each line is a directive name followed by the contexts it is allowed in.
+// Directives missing here (e.g. include) are allowed everywhere.
+var directiveContextsRawList = `absolute_redirect http server location
+accept_mutex events
+accept_mutex_delay events
+access_by_lua http server location if_in_location
+access_by_lua_block http server location if_in_location
+access_by_lua_file http server location if_in_location
+access_by_lua_no_postpone http
+access_log http server location if_in_location limit_except stream stream_server
+add_after_body location
+add_before_body location
+add_header http server location if_in_location
+add_trailer http server location if_in_location
+addition_types http server location
+aio http server location
+aio_write http server location
+alias location
+allow http server location limit_except stream stream_server
+ancient_browser location
+ancient_browser_value location
+api location
+auth_basic http server location limit_except
+auth_basic_user_file http server location limit_except
+auth_delay http server location
+auth_http mail mail_server
+auth_http_header mail mail_server
+auth_http_pass_client_cert mail mail_server
+auth_http_timeout mail mail_server
+auth_jwt http server location limit_except
+auth_jwt_claim_set http
+auth_jwt_header_set http
+auth_jwt_key_cache http server location
+auth_jwt_key_file http server location limit_except
+auth_jwt_key_request http server location limit_except
+auth_jwt_leeway http server location
+auth_jwt_require http server location limit_except
+auth_jwt_type http server location limit_except
+auth_request http server location
+auth_request_set http server location
+autoindex http server location
+autoindex_exact_size http server location
+autoindex_format http server location
+autoindex_localtime http server location
+balancer_by_lua_block upstream
+balancer_by_lua_file upstream
+balancer_keepalive upstream
+body_filter_by_lua http server location if_in_location
+body_filter_by_lua_block http server location if_in_location
+body_filter_by_lua_file http server location if_in_location
+break server location if_in_server if_in_location
+charset http server location if_in_location
+charset_map http
+charset_types http server location
+chunked_transfer_encoding http server location
+client_body_buffer_size http server location
+client_body_in_file_only http server location
+client_body_in_single_buffer http server location
+client_body_temp_path http server location
+client_body_timeout http server location
+client_header_buffer_size http server
+client_header_timeout http server
+client_max_body_size http server location
+connection_pool_size http server
+content_by_lua location if_in_location
+content_by_lua_block location if_in_location
+content_by_lua_file location if_in_location
+create_full_put_path http server location
+daemon main
+dav_access http server location
+dav_methods http server location
+debug_connection events
+debug_points main
+default_type http server location
+deny http server location limit_except stream stream_server
+directio http server location
+directio_alignment http server location
+disable_symlinks http server location
+empty_gif location
+env main
+error_log main http server location stream stream_server mail mail_server
+error_page http server location if_in_location
+etag http server location
+events main
+exit_worker_by_lua_block http
+exit_worker_by_lua_file http
+expires http server location if_in_location
+f4f location
+f4f_buffer_size http server location
+fastcgi_bind http server location
+fastcgi_buffer_size http server location
+fastcgi_buffering http server location
+fastcgi_buffers http server location
+fastcgi_busy_buffers_size http server location
+fastcgi_cache http server location
+fastcgi_cache_background_update http server location
+fastcgi_cache_bypass http server location
+fastcgi_cache_key http server location
+fastcgi_cache_lock http server location
+fastcgi_cache_lock_age http server location
+fastcgi_cache_lock_timeout http server location
+fastcgi_cache_max_range_offset http server location
+fastcgi_cache_methods http server location
+fastcgi_cache_min_uses http server location
+fastcgi_cache_path http
+fastcgi_cache_purge http server location
+fastcgi_cache_revalidate http server location
+fastcgi_cache_use_stale http server location
+fastcgi_cache_valid http server location
+fastcgi_catch_stderr http server location
+fastcgi_connect_timeout http server location
+fastcgi_force_ranges http server location
+fastcgi_hide_header http server location
+fastcgi_ignore_client_abort http server location
+fastcgi_ignore_headers http server location
+fastcgi_index http server location
+fastcgi_intercept_errors http server location
+fastcgi_keep_conn http server location
+fastcgi_limit_rate http server location
+fastcgi_max_temp_file_size http server location
+fastcgi_next_upstream http server location
+fastcgi_next_upstream_timeout http server location
+fastcgi_next_upstream_tries http server location
+fastcgi_no_cache http server location
+fastcgi_param http server location
+fastcgi_pass location if_in_location
+fastcgi_pass_header http server location
+fastcgi_pass_request_body http server location
+fastcgi_pass_request_headers http server location
+fastcgi_read_timeout http server location
+fastcgi_request_buffering http server location
+fastcgi_send_lowat http server location
+fastcgi_send_timeout http server location
+fastcgi_socket_keepalive http server location
+fastcgi_split_path_info location
+fastcgi_store http server location
+fastcgi_store_access http server location
+fastcgi_temp_file_write_size http server location
+fastcgi_temp_path http server location
+flv location
+geo http stream
+geoip_city http stream
+geoip_country http stream
+geoip_org http stream
+geoip_proxy http
+geoip_proxy_recursive http
+google_perftools_profiles main
+grpc_bind http server location
+grpc_buffer_size http server location
+grpc_connect_timeout http server location
+grpc_hide_header http server location
+grpc_ignore_headers http server location
+grpc_intercept_errors http server location
+grpc_next_upstream http server location
+grpc_next_upstream_timeout http server location
+grpc_next_upstream_tries http server location
+grpc_pass location if_in_location
+grpc_pass_header http server location
+grpc_read_timeout http server location
+grpc_send_timeout http server location
+grpc_set_header http server location
+grpc_socket_keepalive http server location
+grpc_ssl_certificate http server location
+grpc_ssl_certificate_key http server location
+grpc_ssl_ciphers http server location
+grpc_ssl_conf_command http server location
+grpc_ssl_crl http server location
+grpc_ssl_name http server location
+grpc_ssl_password_file http server location
+grpc_ssl_protocols http server location
+grpc_ssl_server_name http server location
+grpc_ssl_session_reuse http server location
+grpc_ssl_trusted_certificate http server location
+grpc_ssl_verify http server location
+grpc_ssl_verify_depth http server location
+gunzip http server location
+gunzip_buffers http server location
+gzip http server location if_in_location
+gzip_buffers http server location
+gzip_comp_level http server location
+gzip_disable http server location
+gzip_http_version http server location
+gzip_min_length http server location
+gzip_proxied http server location
+gzip_static http server location
+gzip_types http server location
+gzip_vary http server location
+hash upstream stream_upstream
+header_filter_by_lua http server location if_in_location
+header_filter_by_lua_block http server location if_in_location
+header_filter_by_lua_file http server location if_in_location
+health_check location stream_server
+health_check_timeout stream stream_server
+hls location
+hls_buffers http server location
+hls_forward_args http server location
+hls_fragment http server location
+hls_mp4_buffer_size http server location
+hls_mp4_max_buffer_size http server location
+http main
+http2 http server
+http2_body_preread_size http server
+http2_chunk_size http server location
+http2_idle_timeout http server
+http2_max_concurrent_pushes http server
+http2_max_concurrent_streams http server
+http2_max_field_size http server
+http2_max_header_size http server
+http2_max_requests http server
+http2_push http server location
+http2_push_preload http server location
+http2_recv_buffer_size http
+http2_recv_timeout http server
+http3 http server
+http3_hq http server
+http3_max_concurrent_streams http server
+http3_stream_buffer_size http server
+if server location
+if_modified_since http server location
+ignore_invalid_headers http server
+image_filter location
+image_filter_buffer http server location
+image_filter_interlace http server location
+image_filter_jpeg_quality http server location
+image_filter_sharpen http server location
+image_filter_transparency http server location
+image_filter_webp_quality http server location
+imap_auth mail mail_server
+imap_capabilities mail mail_server
+imap_client_buffer mail mail_server
+index http server location
+init_by_lua http
+init_by_lua_block http
+init_by_lua_file http
+init_worker_by_lua http
+init_worker_by_lua_block http
+init_worker_by_lua_file http
+internal location
+internal_redirect server location
+ip_hash upstream
+js_access stream stream_server
+js_body_filter location if_in_location limit_except
+js_content location if_in_location limit_except
+js_fetch_buffer_size http server location stream stream_server
+js_fetch_ciphers http server location stream stream_server
+js_fetch_max_response_buffer_size http server location stream stream_server
+js_fetch_protocols http server location stream stream_server
+js_fetch_timeout http server location stream stream_server
+js_fetch_trusted_certificate http server location stream stream_server
+js_fetch_verify http server location stream stream_server
+js_fetch_verify_depth http server location stream stream_server
+js_filter stream stream_server
+js_header_filter location if_in_location limit_except
+js_import http server location stream stream_server
+js_include http stream
+js_path http server location stream stream_server
+js_periodic location stream_server
+js_preload_object http server location stream stream_server
+js_preread stream stream_server
+js_set http server location stream stream_server
+js_shared_dict_zone http stream
+js_var http server location stream stream_server
+keepalive upstream
+keepalive_disable http server location
+keepalive_requests http server location upstream
+keepalive_time http server location upstream
+keepalive_timeout http server location upstream
+keyval http stream
+keyval_zone http stream
+large_client_header_buffers http server
+least_conn upstream stream_upstream
+least_time upstream stream_upstream
+limit_conn http server location stream stream_server
+limit_conn_dry_run http server location stream stream_server
+limit_conn_log_level http server location stream stream_server
+limit_conn_status http server location
+limit_conn_zone http stream
+limit_except location
+limit_rate http server location if_in_location
+limit_rate_after http server location if_in_location
+limit_req http server location
+limit_req_dry_run http server location
+limit_req_log_level http server location
+limit_req_status http server location
+limit_req_zone http
+limit_zone http
+lingering_close http server location
+lingering_time http server location
+lingering_timeout http server location
+listen server stream_server mail_server
+load_module main
+location server location
+lock_file main
+log_by_lua http server location if_in_location
+log_by_lua_block http server location if_in_location
+log_by_lua_file http server location if_in_location
+log_format http stream
+log_not_found http server location
+log_subrequest http server location
+lua_capture_error_log http
+lua_check_client_abort http server location if_in_location
+lua_code_cache http server location if_in_location
+lua_http10_buffering http server location if_in_location
+lua_load_resty_core http
+lua_malloc_trim http
+lua_max_pending_timers http
+lua_max_running_timers http
+lua_need_request_body http server location if_in_location
+lua_package_cpath http
+lua_package_path http
+lua_regex_cache_max_entries http
+lua_regex_match_limit http
+lua_sa_restart http
+lua_shared_dict http
+lua_socket_buffer_size http server location
+lua_socket_connect_timeout http server location
+lua_socket_keepalive_timeout http server location
+lua_socket_log_errors http server location
+lua_socket_pool_size http server location
+lua_socket_read_timeout http server location
+lua_socket_send_lowat http server location
+lua_socket_send_timeout http server location
+lua_ssl_certificate http server location
+lua_ssl_certificate_key http server location
+lua_ssl_ciphers http server location
+lua_ssl_conf_command http server location
+lua_ssl_crl http server location
+lua_ssl_protocols http server location
+lua_ssl_trusted_certificate http server location
+lua_ssl_verify_depth http server location
+lua_thread_cache_max_entries http
+lua_transform_underscores_in_response_headers http server location if_in_location
+lua_use_default_type http server location if_in_location
+lua_worker_thread_vm_pool_size http
+mail main
+map http stream
+map_hash_bucket_size http stream
+map_hash_max_size http stream
+master_process main
+match http stream
+max_errors mail mail_server
+max_ranges http server location
+memcached_bind http server location
+memcached_buffer_size http server location
+memcached_connect_timeout http server location
+memcached_gzip_flag http server location
+memcached_next_upstream http server location
+memcached_next_upstream_timeout http server location
+memcached_next_upstream_tries http server location
+memcached_pass location if_in_location
+memcached_read_timeout http server location
+memcached_send_timeout http server location
+memcached_socket_keepalive http server location
+merge_slashes http server
+mgmt main
+min_delete_depth http server location
+mirror http server location
+mirror_request_body http server location
+modern_browser http server location
+modern_browser_value http server location
+mp4 location
+mp4_buffer_size http server location
+mp4_limit_rate http server location
+mp4_limit_rate_after http server location
+mp4_max_buffer_size http server location
+mp4_start_key_frame http server location
+mqtt stream stream_server
+mqtt_buffers stream stream_server
+mqtt_preread stream stream_server
+mqtt_rewrite_buffer_size stream stream_server
+mqtt_set_connect stream stream_server
+msie_padding http server location
+msie_refresh http server location
+multi_accept events
+ntlm upstream
+open_file_cache http server location
+open_file_cache_errors http server location
+open_file_cache_min_uses http server location
+open_file_cache_valid http server location
+open_log_file_cache http server location stream stream_server
+otel_exporter http
+otel_service_name http
+otel_span_attr http server location
+otel_span_name http server location
+otel_trace http server location
+otel_trace_context http server location
+output_buffers http server location
+override_charset http server location if_in_location
+pcre_jit main
+perl location
+perl_modules http
+perl_require http
+perl_set http
+pid main
+pop3_auth mail mail_server
+pop3_capabilities mail mail_server
+port_in_redirect http server location
+postpone_output http server location
+preread_buffer_size stream stream_server
+preread_timeout stream stream_server
+protocol mail_server
+proxy_bind http server location stream stream_server
+proxy_buffer mail mail_server
+proxy_buffer_size http server location stream stream_server
+proxy_buffering http server location
+proxy_buffers http server location
+proxy_busy_buffers_size http server location
+proxy_cache http server location
+proxy_cache_background_update http server location
+proxy_cache_bypass http server location
+proxy_cache_convert_head http server location
+proxy_cache_key http server location
+proxy_cache_lock http server location
+proxy_cache_lock_age http server location
+proxy_cache_lock_timeout http server location
+proxy_cache_max_range_offset http server location
+proxy_cache_methods http server location
+proxy_cache_min_uses http server location
+proxy_cache_path http
+proxy_cache_purge http server location
+proxy_cache_revalidate http server location
+proxy_cache_use_stale http server location
+proxy_cache_valid http server location
+proxy_connect_timeout http server location stream stream_server
+proxy_cookie_domain http server location
+proxy_cookie_flags http server location
+proxy_cookie_path http server location
+proxy_download_rate stream stream_server
+proxy_force_ranges http server location
+proxy_half_close stream stream_server
+proxy_headers_hash_bucket_size http server location
+proxy_headers_hash_max_size http server location
+proxy_hide_header http server location
+proxy_http_version http server location
+proxy_ignore_client_abort http server location
+proxy_ignore_headers http server location
+proxy_intercept_errors http server location
+proxy_limit_rate http server location
+proxy_max_temp_file_size http server location
+proxy_method http server location
+proxy_next_upstream http server location stream stream_server
+proxy_next_upstream_timeout http server location stream stream_server
+proxy_next_upstream_tries http server location stream stream_server
+proxy_no_cache http server location
+proxy_pass location if_in_location limit_except stream_server
+proxy_pass_error_message mail mail_server
+proxy_pass_header http server location
+proxy_pass_request_body http server location
+proxy_pass_request_headers http server location
+proxy_protocol stream stream_server mail mail_server
+proxy_protocol_timeout stream stream_server
+proxy_read_timeout http server location
+proxy_redirect http server location
+proxy_request_buffering http server location
+proxy_requests stream stream_server
+proxy_responses stream stream_server
+proxy_send_lowat http server location
+proxy_send_timeout http server location
+proxy_session_drop stream stream_server
+proxy_set_body http server location
+proxy_set_header http server location
+proxy_smtp_auth mail mail_server
+proxy_socket_keepalive http server location stream stream_server
+proxy_ssl stream stream_server
+proxy_ssl_certificate http server location stream stream_server
+proxy_ssl_certificate_key http server location stream stream_server
+proxy_ssl_ciphers http server location stream stream_server
+proxy_ssl_conf_command http server location stream stream_server
+proxy_ssl_crl http server location stream stream_server
+proxy_ssl_name http server location stream stream_server
+proxy_ssl_password_file http server location stream stream_server
+proxy_ssl_protocols http server location stream stream_server
+proxy_ssl_server_name http server location stream stream_server
+proxy_ssl_session_reuse http server location stream stream_server
+proxy_ssl_trusted_certificate http server location stream stream_server
+proxy_ssl_verify http server location stream stream_server
+proxy_ssl_verify_depth http server location stream stream_server
+proxy_store http server location
+proxy_store_access http server location
+proxy_temp_file_write_size http server location
+proxy_temp_path http server location
+proxy_timeout stream stream_server mail mail_server
+proxy_upload_rate stream stream_server
+queue upstream
+quic_active_connection_id_limit http server
+quic_bpf main
+quic_gso http server
+quic_host_key http server
+quic_retry http server
+random upstream stream_upstream
+random_index location
+read_ahead http server location
+real_ip_header http server location
+real_ip_recursive http server location
+recursive_error_pages http server location
+referer_hash_bucket_size server location
+referer_hash_max_size server location
+request_pool_size http server
+reset_timedout_connection http server location
+resolver http server location upstream stream stream_server stream_upstream mail mail_server
+resolver_timeout http server location upstream stream stream_server stream_upstream mail mail_server
+return server location if_in_server if_in_location stream_server
+rewrite server location if_in_server if_in_location
+rewrite_by_lua http server location if_in_location
+rewrite_by_lua_block http server location if_in_location
+rewrite_by_lua_file http server location if_in_location
+rewrite_by_lua_no_postpone http
+rewrite_log http server location if_in_server if_in_location
+root http server location if_in_location
+satisfy http server location
+scgi_bind http server location
+scgi_buffer_size http server location
+scgi_buffering http server location
+scgi_buffers http server location
+scgi_busy_buffers_size http server location
+scgi_cache http server location
+scgi_cache_background_update http server location
+scgi_cache_bypass http server location
+scgi_cache_key http server location
+scgi_cache_lock http server location
+scgi_cache_lock_age http server location
+scgi_cache_lock_timeout http server location
+scgi_cache_max_range_offset http server location
+scgi_cache_methods http server location
+scgi_cache_min_uses http server location
+scgi_cache_path http
+scgi_cache_purge http server location
+scgi_cache_revalidate http server location
+scgi_cache_use_stale http server location
+scgi_cache_valid http server location
+scgi_connect_timeout http server location
+scgi_force_ranges http server location
+scgi_hide_header http server location
+scgi_ignore_client_abort http server location
+scgi_ignore_headers http server location
+scgi_intercept_errors http server location
+scgi_limit_rate http server location
+scgi_max_temp_file_size http server location
+scgi_next_upstream http server location
+scgi_next_upstream_timeout http server location
+scgi_next_upstream_tries http server location
+scgi_no_cache http server location
+scgi_param http server location
+scgi_pass location if_in_location
+scgi_pass_header http server location
+scgi_pass_request_body http server location
+scgi_pass_request_headers http server location
+scgi_read_timeout http server location
+scgi_request_buffering http server location
+scgi_send_timeout http server location
+scgi_socket_keepalive http server location
+scgi_store http server location
+scgi_store_access http server location
+scgi_temp_file_write_size http server location
+scgi_temp_path http server location
+secure_link http server location
+secure_link_md5 http server location
+secure_link_secret location
+send_lowat http server location
+send_timeout http server location
+sendfile http server location if_in_location
+sendfile_max_chunk http server location
+server http upstream stream stream_upstream mail
+server_name server mail mail_server
+server_name_in_redirect http server location
+server_names_hash_bucket_size http
+server_names_hash_max_size http
+server_rewrite_by_lua_block http server
+server_rewrite_by_lua_file http server
+server_tokens http server location
+session_log http server location
+session_log_format http
+session_log_zone http
+set server location if_in_server if_in_location stream_server
+set_by_lua server location if_in_server if_in_location
+set_by_lua_block server location if_in_server if_in_location
+set_by_lua_file server location if_in_server if_in_location
+set_real_ip_from http server location stream stream_server
+slice http server location
+smtp_auth mail mail_server
+smtp_capabilities mail mail_server
+smtp_client_buffer mail mail_server
+smtp_greeting_delay mail mail_server
+source_charset http server location if_in_location
+split_clients http stream
+ssi http server location if_in_location
+ssi_last_modified http server location
+ssi_min_file_chunk http server location
+ssi_silent_errors http server location
+ssi_types http server location
+ssi_value_length http server location
+ssl http server mail mail_server
+ssl_alpn stream stream_server
+ssl_buffer_size http server
+ssl_certificate http server stream stream_server mail mail_server
+ssl_certificate_by_lua_block server
+ssl_certificate_by_lua_file server
+ssl_certificate_key http server stream stream_server mail mail_server
+ssl_ciphers http server stream stream_server mail mail_server
+ssl_client_certificate http server stream stream_server mail mail_server
+ssl_client_hello_by_lua_block http server
+ssl_client_hello_by_lua_file http server
+ssl_conf_command http server stream stream_server mail mail_server
+ssl_crl http server stream stream_server mail mail_server
+ssl_dhparam http server stream stream_server mail mail_server
+ssl_early_data http server
+ssl_ecdh_curve http server stream stream_server mail mail_server
+ssl_engine main
+ssl_handshake_timeout stream stream_server
+ssl_ocsp http server location stream stream_server
+ssl_ocsp_cache http server location stream stream_server
+ssl_ocsp_responder http server location stream stream_server
+ssl_password_file http server stream stream_server mail mail_server
+ssl_prefer_server_ciphers http server stream stream_server mail mail_server
+ssl_preread stream stream_server
+ssl_protocols http server stream stream_server mail mail_server
+ssl_reject_handshake http server location stream stream_server
+ssl_session_cache http server stream stream_server mail mail_server
+ssl_session_fetch_by_lua_block http
+ssl_session_fetch_by_lua_file http
+ssl_session_store_by_lua_block http server
+ssl_session_store_by_lua_file http server
+ssl_session_ticket_key http server stream stream_server mail mail_server
+ssl_session_tickets http server stream stream_server mail mail_server
+ssl_session_timeout http server stream stream_server mail mail_server
+ssl_stapling http server location stream stream_server
+ssl_stapling_file http server location stream stream_server
+ssl_stapling_responder http server location stream stream_server
+ssl_stapling_verify http server location stream stream_server
+ssl_trusted_certificate http server stream stream_server mail mail_server
+ssl_verify_client http server stream stream_server mail mail_server
+ssl_verify_depth http server stream stream_server mail mail_server
+starttls mail mail_server
+state upstream stream_upstream
+status location
+status_format http server location
+status_zone server location if_in_location stream_server
+sticky upstream
+sticky_cookie_insert upstream
+stream main
+stub_status server location
+sub_filter http server location
+sub_filter_last_modified http server location
+sub_filter_once http server location
+sub_filter_types http server location
+subrequest_output_buffer_size http server location
+tcp_nodelay http server location stream stream_server
+tcp_nopush http server location
+thread_pool main
+timeout mail mail_server
+timer_resolution main
+try_files server location
+types http server location
+types_hash_bucket_size http server location
+types_hash_max_size http server location
+underscores_in_headers http server
+uninitialized_variable_warn http server location if_in_server if_in_location
+upstream http stream
+upstream_conf location
+use events
+user main
+userid http server location
+userid_domain http server location
+userid_expires http server location
+userid_flags http server location
+userid_mark http server location
+userid_name http server location
+userid_p3p http server location
+userid_path http server location
+userid_service http server location
+uwsgi_bind http server location
+uwsgi_buffer_size http server location
+uwsgi_buffering http server location
+uwsgi_buffers http server location
+uwsgi_busy_buffers_size http server location
+uwsgi_cache http server location
+uwsgi_cache_background_update http server location
+uwsgi_cache_bypass http server location
+uwsgi_cache_key http server location
+uwsgi_cache_lock http server location
+uwsgi_cache_lock_age http server location
+uwsgi_cache_lock_timeout http server location
+uwsgi_cache_max_range_offset http server location
+uwsgi_cache_methods http server location
+uwsgi_cache_min_uses http server location
+uwsgi_cache_path http
+uwsgi_cache_purge http server location
+uwsgi_cache_revalidate http server location
+uwsgi_cache_use_stale http server location
+uwsgi_cache_valid http server location
+uwsgi_connect_timeout http server location
+uwsgi_force_ranges http server location
+uwsgi_hide_header http server location
+uwsgi_ignore_client_abort http server location
+uwsgi_ignore_headers http server location
+uwsgi_intercept_errors http server location
+uwsgi_limit_rate http server location
+uwsgi_max_temp_file_size http server location
+uwsgi_modifier1 http server location
+uwsgi_modifier2 http server location
+uwsgi_next_upstream http server location
+uwsgi_next_upstream_timeout http server location
+uwsgi_next_upstream_tries http server location
+uwsgi_no_cache http server location
+uwsgi_param http server location
+uwsgi_pass location if_in_location
+uwsgi_pass_header http server location
+uwsgi_pass_request_body http server location
+uwsgi_pass_request_headers http server location
+uwsgi_read_timeout http server location
+uwsgi_request_buffering http server location
+uwsgi_send_timeout http server location
+uwsgi_socket_keepalive http server location
+uwsgi_ssl_certificate http server location
+uwsgi_ssl_certificate_key http server location
+uwsgi_ssl_ciphers http server location
+uwsgi_ssl_conf_command http server location
+uwsgi_ssl_crl http server location
+uwsgi_ssl_name http server location
+uwsgi_ssl_password_file http server location
+uwsgi_ssl_protocols http server location
+uwsgi_ssl_server_name http server location
+uwsgi_ssl_session_reuse http server location
+uwsgi_ssl_trusted_certificate http server location
+uwsgi_ssl_verify http server location
+uwsgi_ssl_verify_depth http server location
+uwsgi_store http server location
+uwsgi_store_access http server location
+uwsgi_temp_file_write_size http server location
+uwsgi_temp_path http server location
+valid_referers server location
+variables_hash_bucket_size http stream
+variables_hash_max_size http stream
+worker_aio_requests main
+worker_connections events
+worker_cpu_affinity main
+worker_priority main
+worker_processes main
+worker_rlimit_core main
+worker_rlimit_nofile main
+worker_shutdown_timeout main
+working_directory main
+xclient mail mail_server
+xml_entities http server location
+xslt_last_modified http server location
+xslt_param http server location
+xslt_string_param http server location
+xslt_stylesheet http server location
+xslt_types http server location
+zone upstream stream_upstream
+zone_sync stream_server
+zone_sync_buffers stream stream_server
+zone_sync_connect_retry_interval stream stream_server
+zone_sync_connect_timeout stream stream_server
+zone_sync_interval stream stream_server
+zone_sync_recv_buffer_size stream stream_server
+zone_sync_server stream_server
+zone_sync_ssl stream stream_server
+zone_sync_ssl_certificate stream stream_server
+zone_sync_ssl_certificate_key stream stream_server
+zone_sync_ssl_ciphers stream stream_server
+zone_sync_ssl_conf_command stream stream_server
+zone_sync_ssl_crl stream stream_server
+zone_sync_ssl_name stream stream_server
+zone_sync_ssl_password_file stream stream_server
+zone_sync_ssl_protocols stream stream_server
+zone_sync_ssl_server_name stream stream_server
+zone_sync_ssl_trusted_certificate stream stream_server
+zone_sync_ssl_verify stream stream_server
+zone_sync_ssl_verify_depth stream stream_server
+zone_sync_timeout stream stream_server
+`
+
+// DirectiveContexts maps known directives to the contexts they are allowed in
+var DirectiveContexts map[string]Context = map[string]Context{}
+
+// StreamOnly reports whether a known directive is only allowed in the contexts of the stream subsystem,
+// such as ssl_preread or proxy_responses
+func StreamOnly(name string) bool {
+	contexts, ok := DirectiveContexts[name]
+	return ok && contexts != 0 && contexts&^ContextStreamAll == 0
+}
+
+// MailOnly reports whether a known directive is only allowed in the contexts of the mail subsystem,
+// such as auth_http or starttls
+func MailOnly(name string) bool {
+	contexts, ok := DirectiveContexts[name]
+	return ok && contexts != 0 && contexts&^ContextMailAll == 0
+}
+
+func init() {
+	contexts := make(map[string]Context, len(contextNames))
+	for _, cn := range contextNames {
+		contexts[cn.name] = cn.context
+	}
+
+	for _, line := range strings.Split(directiveContextsRawList, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, name := range fields[1:] {
+			DirectiveContexts[fields[0]] |= contexts[name]
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParser_ContextValidation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		conf    string
+		wantErr string
+	}{
+		{
+			name: "valid config",
+			conf: `user nginx;
+worker_processes auto;
+events {
+    worker_connections 1024;
+}
+http {
+    upstream backend {
+        server 127.0.0.1:8080;
+        keepalive 16;
+    }
+    server {
+        listen 80;
+        if ($host = a) {
+            return 301 https://$host$request_uri;
+        }
+        location / {
+            if ($request_method = POST) {
+                proxy_pass http://backend;
+            }
+            limit_except GET {
+                deny all;
+            }
+            proxy_pass http://backend;
+        }
+    }
+}
+stream {
+    upstream dns {
+        server 1.1.1.1:53;
+    }
+    server {
+        listen 53 udp;
+        proxy_pass dns;
+    }
+}
+mail {
+    server {
+        listen 25;
+        protocol smtp;
+    }
+}`,
+		},
+		{
+			name:    "http directive at the top level",
+			conf:    "proxy_pass http://backend;",
+			wantErr: "directive 'proxy_pass' is not allowed in main context on line 1, column 1",
+		},
+		{
+			name:    "main directive in a location",
+			conf:    "http {\n  server {\n    location / {\n      worker_processes 2;\n    }\n  }\n}",
+			wantErr: "directive 'worker_processes' is not allowed in location context on line 4, column 7",
+		},
+		{
+			name:    "location directive in if in server",
+			conf:    "http {\n  server {\n    if ($a) {\n      proxy_pass http://a;\n    }\n  }\n}",
+			wantErr: "directive 'proxy_pass' is not allowed in if_in_server context on line 4, column 7",
+		},
+		{
+			name:    "http directive in a stream server",
+			conf:    "stream {\n  server {\n    proxy_set_header Host a;\n  }\n}",
+			wantErr: "directive 'proxy_set_header' is not allowed in stream_server context on line 3, column 5",
+		},
+		{
+			name:    "block in the wrong context",
+			conf:    "http {\n  events {}\n}",
+			wantErr: "directive 'events' is not allowed in http context on line 2, column 3",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewStringParser(tt.conf, WithContextValidation()).Parse()
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tt.wantErr)
+			var contextErr *ContextError
+			assert.Assert(t, errors.As(err, &contextErr))
+		})
+	}
+}
+
+func TestParser_ContextValidation_Disabled(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser("proxy_pass http://backend;").Parse()
+	assert.NilError(t, err)
+}
+
+func TestParser_ContextValidation_ContextError(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser("http {\n    listen 80;\n}", WithContextValidation()).Parse()
+
+	var contextErr *ContextError
+	assert.Assert(t, errors.As(err, &contextErr))
+	assert.Equal(t, contextErr.Name, "listen")
+	assert.Equal(t, contextErr.Context, ContextHTTP)
+	assert.Equal(t, contextErr.Allowed, ContextServer|ContextStreamServer|ContextMailServer)
+	assert.Equal(t, contextErr.Allowed.String(), "server, stream_server, mail_server")
+	assert.Equal(t, contextErr.Snippet, "2 |     listen 80;\n  |     ^")
+}
+
+func TestParser_ContextValidation_RootContext(t *testing.T) {
+	t.Parallel()
+	conf := "server {\n    listen 80;\n}"
+
+	_, err := NewStringParser(conf, WithContextValidation()).Parse()
+	assert.Error(t, err, "directive 'server' is not allowed in main context on line 1, column 1")
+
+	_, err = NewStringParser(conf, WithContextValidation(), WithRootContext(ContextHTTP)).Parse()
+	assert.NilError(t, err)
+}
+
+func TestParser_ContextValidation_CustomDirectives(t *testing.T) {
+	t.Parallel()
+	conf := "http {\n    my_directive on;\n    server {\n        my_directive off;\n    }\n}"
+
+	// custom directives without contexts are allowed everywhere
+	_, err := NewStringParser(conf, WithContextValidation(), WithCustomDirectives("my_directive")).Parse()
+	assert.NilError(t, err)
+
+	_, err = NewStringParser(conf, WithContextValidation(), WithCustomDirectiveContexts("my_directive", ContextHTTP)).Parse()
+	assert.Error(t, err, "directive 'my_directive' is not allowed in server context on line 4, column 9")
+}
+
+func TestParser_ContextValidation_IncludedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mainConf := filepath.Join(dir, "nginx.conf")
+	serverConf := filepath.Join(dir, "server.conf")
+	assert.NilError(t, os.WriteFile(mainConf, []byte("http {\n    server {\n        include server.conf;\n    }\n}\n"), 0644))
+	assert.NilError(t, os.WriteFile(serverConf, []byte("listen 80;\nworker_processes 1;\n"), 0644))
+
+	p, err := NewParser(mainConf, WithIncludeParsing(), WithContextValidation())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+
+	var contextErr *ContextError
+	assert.Assert(t, errors.As(err, &contextErr))
+	assert.Equal(t, contextErr.Name, "worker_processes")
+	assert.Equal(t, contextErr.Context, ContextServer)
+	assert.Equal(t, contextErr.Pos.Filename, serverConf)
+	assert.Equal(t, len(contextErr.IncludeChain), 1)
+}
+
+func TestParser_ContextValidation_SkipsFreeFormBlocks(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser(`http {
+    types {
+        text/html html;
+    }
+    map $a $b {
+        default 0;
+    }
+    server {
+        location / {
+            content_by_lua_block {
+                ngx.say("hello")
+            }
+        }
+    }
+}`, WithContextValidation()).Parse()
+	assert.NilError(t, err)
+}
+
+func TestDirectiveContexts(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, DirectiveContexts["worker_processes"], ContextMain)
+	assert.Equal(t, DirectiveContexts["rewrite"], ContextServer|ContextLocation|ContextIfInServer|ContextIfInLocation)
+	assert.Equal(t, DirectiveContexts["server"], ContextHTTP|ContextUpstream|ContextStream|ContextStreamUpstream|ContextMail)
+	_, ok := DirectiveContexts["include"]
+	assert.Assert(t, !ok)
+
+	assert.Assert(t, StreamOnly("ssl_preread"))
+	assert.Assert(t, StreamOnly("proxy_responses"))
+	assert.Assert(t, !StreamOnly("proxy_pass"))
+	assert.Assert(t, !StreamOnly("listen"))
+	assert.Assert(t, !StreamOnly("include"))
+	assert.Assert(t, MailOnly("auth_http"))
+	assert.Assert(t, MailOnly("protocol"))
+	assert.Assert(t, !MailOnly("server_name"))
+
+	// every annotated directive is a known directive
+	for name := range DirectiveContexts {
+		_, ok := ValidDirectives[name]
+		assert.Assert(t, ok, name)
+	}
+}
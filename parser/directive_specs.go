@@ -0,0 +1,952 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"github.com/tufanbarisyildirim/gonginx/config/value"
+)
+
+// Arity is the set of argument counts a directive accepts, after the NGX_CONF_* flags of nginx
+type Arity uint32
+
+// Argument counts, combine them for directives taking several counts (e.g. ArityTake1|ArityTake2 for NGX_CONF_TAKE12)
+const (
+	ArityNoArgs Arity = 1 << iota
+	ArityTake1
+	ArityTake2
+	ArityTake3
+	ArityTake4
+	ArityTake5
+	ArityTake6
+	ArityTake7
+	ArityFlag
+	Arity1More
+	Arity2More
+	ArityAny
+)
+
+var arityNames = map[string]Arity{
+	"noargs": ArityNoArgs,
+	"take1":  ArityTake1,
+	"take2":  ArityTake2,
+	"take3":  ArityTake3,
+	"take4":  ArityTake4,
+	"take5":  ArityTake5,
+	"take6":  ArityTake6,
+	"take7":  ArityTake7,
+	"flag":   ArityFlag,
+	"1more":  Arity1More,
+	"2more":  Arity2More,
+	"any":    ArityAny,
+}
+
+// Allows reports whether a directive with n arguments is accepted
+func (a Arity) Allows(n int) bool {
+	switch {
+	case a&ArityAny != 0:
+		return true
+	case a&Arity1More != 0 && n >= 1:
+		return true
+	case a&Arity2More != 0 && n >= 2:
+		return true
+	case a&ArityFlag != 0 && n == 1:
+		return true
+	case n >= 0 && n <= 7:
+		return a&(ArityNoArgs<<n) != 0
+	}
+	return false
+}
+
+// DirectiveSpec describes the arguments, block and multiplicity nginx accepts for a directive
+type DirectiveSpec struct {
+	Arity    Arity
+	Block    bool    // the directive takes a block instead of ending with ';'
+	Multiple bool    // the directive may appear more than once in the same block
+	Contexts Context // the contexts the spec applies to, 0 for all of them
+}
+
+// check returns why the directive does not match the spec, an empty string when it does
+func (s DirectiveSpec) check(d config.IDirective) string {
+	name := d.GetName()
+	hasBlock := d.GetBlock() != nil
+	if s.Block && !hasBlock {
+		return fmt.Sprintf("directive \"%s\" has no opening \"{\"", name)
+	}
+	if !s.Block && hasBlock {
+		return fmt.Sprintf("directive \"%s\" is not terminated by \";\"", name)
+	}
+
+	params := d.GetParameters()
+	if !s.Arity.Allows(len(params)) {
+		return fmt.Sprintf("invalid number of arguments in \"%s\" directive", name)
+	}
+	if s.Arity&ArityFlag != 0 && len(params) == 1 {
+		if _, err := value.ParseFlag(params[0].Unquoted()); err != nil {
+			return fmt.Sprintf("invalid value \"%s\" in \"%s\" directive, it must be \"on\" or \"off\"", params[0].Unquoted(), name)
+		}
+	}
+	switch name {
+	case "if":
+		if _, err := config.ParseCondition(params); err != nil {
+			return err.Error()
+		}
+	case "geo":
+		if _, err := config.NewGeo(d); err != nil {
+			return err.Error()
+		}
+	case "protocol":
+		if _, err := config.ParseMailProtocol(params[0].Unquoted()); err != nil {
+			return err.Error()
+		}
+	case "starttls":
+		if _, err := config.ParseStartTLS(params[0].Unquoted()); err != nil {
+			return err.Error()
+		}
+	case "smtp_auth":
+		if _, err := config.ParseSMTPAuth(params); err != nil {
+			return err.Error()
+		}
+	case "map":
+		if _, err := config.NewMap(d); err != nil {
+			return err.Error()
+		}
+	case "split_clients":
+		if _, err := config.NewSplitClients(d); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+// LookupDirectiveSpec returns the spec of a directive placed in the given context
+func LookupDirectiveSpec(name string, context Context) (DirectiveSpec, bool) {
+	for _, spec := range DirectiveSpecs[name] {
+		if spec.Contexts == 0 || spec.Contexts&context != 0 {
+			return spec, true
+		}
+	}
+	return DirectiveSpec{}, false
+}
+
+// got the arguments from the command definitions of nginx and lua-nginx-module.
+// each line is a directive name, optionally followed by @ and the contexts the line applies to,
+// then its argument counts, "block" when it takes a block and "multi" when it can be repeated.
+// Directives missing here (e.g. default) are not checked.
+var directiveSpecsRawList = `absolute_redirect flag
+accept_mutex flag
+accept_mutex_delay take1
+access_by_lua take1
+access_by_lua_block block noargs
+access_by_lua_file take1
+access_by_lua_no_postpone flag
+access_log 1more multi
+add_after_body take1
+add_before_body take1
+add_header take2 take3 multi
+add_trailer take2 take3 multi
+addition_types 1more
+aio take1
+aio_write flag
+alias take1
+allow take1 multi
+ancient_browser 1more multi
+ancient_browser_value take1
+api noargs take1
+auth_basic take1
+auth_basic_user_file take1
+auth_delay take1
+auth_http take1
+auth_http_header take2 multi
+auth_http_pass_client_cert flag
+auth_http_timeout take1
+auth_jwt take1 take2
+auth_jwt_claim_set 2more multi
+auth_jwt_header_set 2more multi
+auth_jwt_key_cache take1 take2
+auth_jwt_key_file take1 multi
+auth_jwt_key_request take1 multi
+auth_jwt_leeway take1
+auth_jwt_require 1more multi
+auth_jwt_type take1
+auth_request take1
+auth_request_set take2 multi
+autoindex flag
+autoindex_exact_size flag
+autoindex_format take1
+autoindex_localtime flag
+balancer_by_lua_block block noargs
+balancer_by_lua_file take1
+balancer_keepalive take1
+body_filter_by_lua take1
+body_filter_by_lua_block block noargs
+body_filter_by_lua_file take1
+break noargs multi
+charset take1
+charset_map block take2 multi
+charset_types 1more
+chunked_transfer_encoding flag
+client_body_buffer_size take1
+client_body_in_file_only take1
+client_body_in_single_buffer flag
+client_body_temp_path take1 take2 take3 take4
+client_body_timeout take1
+client_header_buffer_size take1
+client_header_timeout take1
+client_max_body_size take1
+connect_timeout take1
+connection_pool_size take1
+content_by_lua take1
+content_by_lua_block block noargs
+content_by_lua_file take1
+create_full_put_path flag
+daemon flag
+dav_access take1 take2 take3
+dav_methods 1more multi
+debug_connection take1 multi
+debug_points take1
+default_type take1
+deny take1 multi
+directio take1
+directio_alignment take1
+disable_symlinks take1 take2
+empty_gif noargs
+env take1 multi
+error_log 1more multi
+error_page 2more multi
+etag flag
+events block noargs
+exit_worker_by_lua_block block noargs
+exit_worker_by_lua_file take1
+expires take1 take2
+f4f noargs
+f4f_buffer_size take1
+fastcgi_bind take1 take2
+fastcgi_buffer_size take1
+fastcgi_buffering flag
+fastcgi_buffers take2
+fastcgi_busy_buffers_size take1
+fastcgi_cache take1
+fastcgi_cache_background_update flag
+fastcgi_cache_bypass 1more multi
+fastcgi_cache_key take1
+fastcgi_cache_lock flag
+fastcgi_cache_lock_age take1
+fastcgi_cache_lock_timeout take1
+fastcgi_cache_max_range_offset take1
+fastcgi_cache_methods 1more multi
+fastcgi_cache_min_uses take1
+fastcgi_cache_path 2more multi
+fastcgi_cache_purge 1more
+fastcgi_cache_revalidate flag
+fastcgi_cache_use_stale 1more multi
+fastcgi_cache_valid 1more multi
+fastcgi_catch_stderr take1 multi
+fastcgi_connect_timeout take1
+fastcgi_force_ranges flag
+fastcgi_hide_header take1 multi
+fastcgi_ignore_client_abort flag
+fastcgi_ignore_headers 1more multi
+fastcgi_index take1
+fastcgi_intercept_errors flag
+fastcgi_keep_conn flag
+fastcgi_limit_rate take1
+fastcgi_max_temp_file_size take1
+fastcgi_next_upstream 1more multi
+fastcgi_next_upstream_timeout take1
+fastcgi_next_upstream_tries take1
+fastcgi_no_cache 1more multi
+fastcgi_param take2 take3 multi
+fastcgi_pass take1
+fastcgi_pass_header take1 multi
+fastcgi_pass_request_body flag
+fastcgi_pass_request_headers flag
+fastcgi_read_timeout take1
+fastcgi_request_buffering flag
+fastcgi_send_lowat take1
+fastcgi_send_timeout take1
+fastcgi_socket_keepalive flag
+fastcgi_split_path_info take1
+fastcgi_store take1
+fastcgi_store_access take1 take2 take3
+fastcgi_temp_file_write_size take1
+fastcgi_temp_path take1 take2 take3 take4
+flv noargs
+geo block take1 take2 multi
+geoip_city take1 take2
+geoip_country take1 take2
+geoip_org take1 take2
+geoip_proxy take1 multi
+geoip_proxy_recursive flag
+google_perftools_profiles take1
+grpc_bind take1 take2
+grpc_buffer_size take1
+grpc_connect_timeout take1
+grpc_hide_header take1 multi
+grpc_ignore_headers 1more multi
+grpc_intercept_errors flag
+grpc_next_upstream 1more multi
+grpc_next_upstream_timeout take1
+grpc_next_upstream_tries take1
+grpc_pass take1
+grpc_pass_header take1 multi
+grpc_read_timeout take1
+grpc_send_timeout take1
+grpc_set_header take2 multi
+grpc_socket_keepalive flag
+grpc_ssl_certificate take1
+grpc_ssl_certificate_key take1
+grpc_ssl_ciphers take1
+grpc_ssl_conf_command take2 multi
+grpc_ssl_crl take1
+grpc_ssl_name take1
+grpc_ssl_password_file take1
+grpc_ssl_protocols 1more multi
+grpc_ssl_server_name flag
+grpc_ssl_session_reuse flag
+grpc_ssl_trusted_certificate take1
+grpc_ssl_verify flag
+grpc_ssl_verify_depth take1
+gunzip flag
+gunzip_buffers take2
+gzip flag
+gzip_buffers take2
+gzip_comp_level take1
+gzip_disable 1more multi
+gzip_http_version take1
+gzip_min_length take1
+gzip_proxied 1more multi
+gzip_static take1
+gzip_types 1more
+gzip_vary flag
+hash take1 take2
+header_filter_by_lua take1
+header_filter_by_lua_block block noargs
+header_filter_by_lua_file take1
+health_check any multi
+health_check_timeout take1
+hls noargs
+hls_buffers take2
+hls_forward_args flag
+hls_fragment take1 take2
+hls_mp4_buffer_size take1
+hls_mp4_max_buffer_size take1
+http block noargs
+http2 flag
+http2_body_preread_size take1
+http2_chunk_size take1
+http2_idle_timeout take1
+http2_max_concurrent_pushes take1
+http2_max_concurrent_streams take1
+http2_max_field_size take1
+http2_max_header_size take1
+http2_max_requests take1
+http2_push take1 multi
+http2_push_preload flag
+http2_recv_buffer_size take1
+http2_recv_timeout take1
+http3 flag
+http3_hq flag
+http3_max_concurrent_streams take1
+http3_stream_buffer_size take1
+if block 1more multi
+if_modified_since take1
+ignore_invalid_headers flag
+image_filter take1 take2 take3 multi
+image_filter_buffer take1
+image_filter_interlace flag
+image_filter_jpeg_quality take1
+image_filter_sharpen take1
+image_filter_transparency flag
+image_filter_webp_quality take1
+imap_auth 1more multi
+imap_capabilities 1more multi
+imap_client_buffer take1
+include take1 multi
+index 1more multi
+init_by_lua take1
+init_by_lua_block block noargs
+init_by_lua_file take1
+init_worker_by_lua take1
+init_worker_by_lua_block block noargs
+init_worker_by_lua_file take1
+internal noargs
+internal_redirect take1
+ip_hash noargs
+js_access take1
+js_body_filter take1 take2
+js_content take1
+js_fetch_buffer_size take1
+js_fetch_ciphers take1
+js_fetch_max_response_buffer_size take1
+js_fetch_protocols 1more multi
+js_fetch_timeout take1
+js_fetch_trusted_certificate take1
+js_fetch_verify flag
+js_fetch_verify_depth take1
+js_filter take1
+js_header_filter take1
+js_import take1 take3 multi
+js_include take1
+js_path take1 multi
+js_periodic 1more multi
+js_preload_object take1 take3 multi
+js_preread take1
+js_set take2 take3 multi
+js_shared_dict_zone 1more multi
+js_var take1 take2 multi
+keepalive take1
+keepalive_disable take1 take2
+keepalive_requests take1
+keepalive_time take1
+keepalive_timeout take1 take2
+keyval take3 multi
+keyval_zone 1more multi
+large_client_header_buffers take2
+least_conn noargs
+least_time take1 take2
+limit_conn take2 multi
+limit_conn_dry_run flag
+limit_conn_log_level take1
+limit_conn_status take1
+limit_conn_zone take2 multi
+limit_except block 1more
+limit_rate take1
+limit_rate_after take1
+limit_req take1 take2 take3 multi
+limit_req_dry_run flag
+limit_req_log_level take1
+limit_req_status take1
+limit_req_zone take3 take4 multi
+limit_zone take3 multi
+lingering_close take1
+lingering_time take1
+lingering_timeout take1
+listen 1more multi
+load_module take1 multi
+location block take1 take2 multi
+lock_file take1
+log_by_lua take1
+log_by_lua_block block noargs
+log_by_lua_file take1
+log_format 2more multi
+log_not_found flag
+log_subrequest flag
+lua_capture_error_log take1
+lua_check_client_abort flag
+lua_code_cache flag
+lua_http10_buffering flag
+lua_load_resty_core flag
+lua_malloc_trim take1
+lua_max_pending_timers take1
+lua_max_running_timers take1
+lua_need_request_body flag
+lua_package_cpath take1
+lua_package_path take1
+lua_regex_cache_max_entries take1
+lua_regex_match_limit take1
+lua_sa_restart flag
+lua_shared_dict take2 multi
+lua_socket_buffer_size take1
+lua_socket_connect_timeout take1
+lua_socket_keepalive_timeout take1
+lua_socket_log_errors flag
+lua_socket_pool_size take1
+lua_socket_read_timeout take1
+lua_socket_send_lowat take1
+lua_socket_send_timeout take1
+lua_ssl_certificate take1
+lua_ssl_certificate_key take1
+lua_ssl_ciphers take1
+lua_ssl_conf_command take2 multi
+lua_ssl_crl take1
+lua_ssl_protocols 1more multi
+lua_ssl_trusted_certificate take1
+lua_ssl_verify_depth take1
+lua_thread_cache_max_entries take1
+lua_transform_underscores_in_response_headers flag
+lua_use_default_type flag
+lua_worker_thread_vm_pool_size take1
+mail block noargs
+map block take2 multi
+map_hash_bucket_size take1
+map_hash_max_size take1
+master_process flag
+match block take1 multi
+max_errors take1
+max_ranges take1
+memcached_bind take1 take2
+memcached_buffer_size take1
+memcached_connect_timeout take1
+memcached_gzip_flag take1
+memcached_next_upstream 1more multi
+memcached_next_upstream_timeout take1
+memcached_next_upstream_tries take1
+memcached_pass take1
+memcached_read_timeout take1
+memcached_send_timeout take1
+memcached_socket_keepalive flag
+merge_slashes flag
+mgmt block noargs
+min_delete_depth take1
+mirror take1 multi
+mirror_request_body flag
+modern_browser take1 take2 multi
+modern_browser_value take1
+mp4 noargs
+mp4_buffer_size take1
+mp4_limit_rate take1
+mp4_limit_rate_after take1
+mp4_max_buffer_size take1
+mp4_start_key_frame flag
+mqtt flag
+mqtt_buffers take2
+mqtt_preread flag
+mqtt_rewrite_buffer_size take1
+mqtt_set_connect take2 multi
+msie_padding flag
+msie_refresh flag
+multi_accept flag
+ntlm noargs
+open_file_cache take1 take2
+open_file_cache_errors flag
+open_file_cache_min_uses take1
+open_file_cache_valid take1
+open_log_file_cache take1 take2 take3 take4
+otel_exporter block noargs
+otel_service_name take1
+otel_span_attr take2 multi
+otel_span_name take1
+otel_trace take1
+otel_trace_context take1
+output_buffers take2
+override_charset flag
+pcre_jit flag
+perl take1
+perl_modules take1 multi
+perl_require take1 multi
+perl_set take2 multi
+pid take1
+pop3_auth 1more multi
+pop3_capabilities 1more multi
+port_in_redirect flag
+postpone_output take1
+preread_buffer_size take1
+preread_timeout take1
+protocol take1
+proxy_bind take1 take2
+proxy_buffer take1
+proxy_buffer_size take1
+proxy_buffering flag
+proxy_buffers take2
+proxy_busy_buffers_size take1
+proxy_cache take1
+proxy_cache_background_update flag
+proxy_cache_bypass 1more multi
+proxy_cache_convert_head flag
+proxy_cache_key take1
+proxy_cache_lock flag
+proxy_cache_lock_age take1
+proxy_cache_lock_timeout take1
+proxy_cache_max_range_offset take1
+proxy_cache_methods 1more multi
+proxy_cache_min_uses take1
+proxy_cache_path 2more multi
+proxy_cache_purge 1more
+proxy_cache_revalidate flag
+proxy_cache_use_stale 1more multi
+proxy_cache_valid 1more multi
+proxy_connect_timeout take1
+proxy_cookie_domain take1 take2 multi
+proxy_cookie_flags 1more multi
+proxy_cookie_path take1 take2 multi
+proxy_download_rate take1
+proxy_force_ranges flag
+proxy_half_close flag
+proxy_headers_hash_bucket_size take1
+proxy_headers_hash_max_size take1
+proxy_hide_header take1 multi
+proxy_http_version take1
+proxy_ignore_client_abort flag
+proxy_ignore_headers 1more multi
+proxy_intercept_errors flag
+proxy_limit_rate take1
+proxy_max_temp_file_size take1
+proxy_method take1
+proxy_next_upstream 1more multi
+proxy_next_upstream_timeout take1
+proxy_next_upstream_tries take1
+proxy_no_cache 1more multi
+proxy_pass take1
+proxy_pass_error_message flag
+proxy_pass_header take1 multi
+proxy_pass_request_body flag
+proxy_pass_request_headers flag
+proxy_protocol flag
+proxy_protocol_timeout take1
+proxy_read_timeout take1
+proxy_redirect take1 take2 multi
+proxy_request_buffering flag
+proxy_requests take1
+proxy_responses take1
+proxy_send_lowat take1
+proxy_send_timeout take1
+proxy_session_drop flag
+proxy_set_body take1
+proxy_set_header take2 multi
+proxy_smtp_auth flag
+proxy_socket_keepalive flag
+proxy_ssl flag
+proxy_ssl_certificate take1
+proxy_ssl_certificate_key take1
+proxy_ssl_ciphers take1
+proxy_ssl_conf_command take2 multi
+proxy_ssl_crl take1
+proxy_ssl_name take1
+proxy_ssl_password_file take1
+proxy_ssl_protocols 1more multi
+proxy_ssl_server_name flag
+proxy_ssl_session_reuse flag
+proxy_ssl_trusted_certificate take1
+proxy_ssl_verify flag
+proxy_ssl_verify_depth take1
+proxy_store take1
+proxy_store_access take1 take2 take3
+proxy_temp_file_write_size take1
+proxy_temp_path take1 take2 take3 take4
+proxy_timeout take1
+proxy_upload_rate take1
+queue take1 take2
+quic_active_connection_id_limit take1
+quic_bpf flag
+quic_gso flag
+quic_host_key take1
+quic_retry flag
+random noargs take1 take2
+random_index flag
+read_ahead take1
+read_timeout take1
+real_ip_header take1
+real_ip_recursive flag
+recursive_error_pages flag
+referer_hash_bucket_size take1
+referer_hash_max_size take1
+request_pool_size take1
+reset_timedout_connection flag
+resolver 1more
+resolver_timeout take1
+return take1 take2 multi
+rewrite take2 take3 multi
+rewrite_by_lua take1
+rewrite_by_lua_block block noargs
+rewrite_by_lua_file take1
+rewrite_by_lua_no_postpone flag
+rewrite_log flag
+root take1
+satisfy take1
+scgi_bind take1 take2
+scgi_buffer_size take1
+scgi_buffering flag
+scgi_buffers take2
+scgi_busy_buffers_size take1
+scgi_cache take1
+scgi_cache_background_update flag
+scgi_cache_bypass 1more multi
+scgi_cache_key take1
+scgi_cache_lock flag
+scgi_cache_lock_age take1
+scgi_cache_lock_timeout take1
+scgi_cache_max_range_offset take1
+scgi_cache_methods 1more multi
+scgi_cache_min_uses take1
+scgi_cache_path 2more multi
+scgi_cache_purge 1more
+scgi_cache_revalidate flag
+scgi_cache_use_stale 1more multi
+scgi_cache_valid 1more multi
+scgi_connect_timeout take1
+scgi_force_ranges flag
+scgi_hide_header take1 multi
+scgi_ignore_client_abort flag
+scgi_ignore_headers 1more multi
+scgi_intercept_errors flag
+scgi_limit_rate take1
+scgi_max_temp_file_size take1
+scgi_next_upstream 1more multi
+scgi_next_upstream_timeout take1
+scgi_next_upstream_tries take1
+scgi_no_cache 1more multi
+scgi_param take2 take3 multi
+scgi_pass take1
+scgi_pass_header take1 multi
+scgi_pass_request_body flag
+scgi_pass_request_headers flag
+scgi_read_timeout take1
+scgi_request_buffering flag
+scgi_send_timeout take1
+scgi_socket_keepalive flag
+scgi_store take1
+scgi_store_access take1 take2 take3
+scgi_temp_file_write_size take1
+scgi_temp_path take1 take2 take3 take4
+secure_link take1 take2
+secure_link_md5 take1
+secure_link_secret take1
+send_lowat take1
+send_timeout take1
+sendfile flag
+sendfile_max_chunk take1
+server@http,stream,mail block noargs multi
+server@upstream,stream_upstream 1more multi
+server_name 1more multi
+server_name_in_redirect flag
+server_names_hash_bucket_size take1
+server_names_hash_max_size take1
+server_rewrite_by_lua_block block noargs
+server_rewrite_by_lua_file take1
+server_tokens take1
+session_log take1
+session_log_format 2more
+session_log_zone 2more multi
+set take2 multi
+set_by_lua 2more multi
+set_by_lua_block block take1 multi
+set_by_lua_file 2more multi
+set_real_ip_from take1 multi
+slice take1
+smtp_auth 1more multi
+smtp_capabilities 1more multi
+smtp_client_buffer take1
+smtp_greeting_delay take1
+source_charset take1
+split_clients block take2 multi
+ssi flag
+ssi_last_modified flag
+ssi_min_file_chunk take1
+ssi_silent_errors flag
+ssi_types 1more
+ssi_value_length take1
+ssl flag
+ssl_alpn 1more
+ssl_buffer_size take1
+ssl_certificate take1 multi
+ssl_certificate_by_lua_block block noargs
+ssl_certificate_by_lua_file take1
+ssl_certificate_key take1 multi
+ssl_ciphers take1
+ssl_client_certificate take1
+ssl_client_hello_by_lua_block block noargs
+ssl_client_hello_by_lua_file take1
+ssl_conf_command take2 multi
+ssl_crl take1
+ssl_dhparam take1
+ssl_early_data flag
+ssl_ecdh_curve take1
+ssl_engine take1
+ssl_handshake_timeout take1
+ssl_name take1
+ssl_ocsp take1
+ssl_ocsp_cache take1
+ssl_ocsp_responder take1
+ssl_password_file take1
+ssl_prefer_server_ciphers flag
+ssl_preread flag
+ssl_protocols 1more multi
+ssl_reject_handshake flag
+ssl_server_name flag
+ssl_session_cache take1 take2
+ssl_session_fetch_by_lua_block block noargs
+ssl_session_fetch_by_lua_file take1
+ssl_session_store_by_lua_block block noargs
+ssl_session_store_by_lua_file take1
+ssl_session_ticket_key take1 multi
+ssl_session_tickets flag
+ssl_session_timeout take1
+ssl_stapling flag
+ssl_stapling_file take1
+ssl_stapling_responder take1
+ssl_stapling_verify flag
+ssl_trusted_certificate take1
+ssl_verify flag
+ssl_verify_client take1
+ssl_verify_depth take1
+starttls take1
+state take1
+status noargs
+status_format take1 take2
+status_zone take1
+sticky 1more
+sticky_cookie_insert 1more
+stream block noargs
+stub_status noargs take1
+sub_filter take2 multi
+sub_filter_last_modified flag
+sub_filter_once flag
+sub_filter_types 1more
+subrequest_output_buffer_size take1
+tcp_nodelay flag
+tcp_nopush flag
+thread_pool take2 take3 multi
+timeout take1
+timer_resolution take1
+try_files 2more
+types block noargs multi
+types_hash_bucket_size take1
+types_hash_max_size take1
+underscores_in_headers flag
+uninitialized_variable_warn flag
+upstream block take1 multi
+upstream_conf noargs
+usage_report any
+use take1
+user take1 take2
+userid take1
+userid_domain take1
+userid_expires take1
+userid_flags 1more multi
+userid_mark take1
+userid_name take1
+userid_p3p take1
+userid_path take1
+userid_service take1
+uuid_file take1
+uwsgi_bind take1 take2
+uwsgi_buffer_size take1
+uwsgi_buffering flag
+uwsgi_buffers take2
+uwsgi_busy_buffers_size take1
+uwsgi_cache take1
+uwsgi_cache_background_update flag
+uwsgi_cache_bypass 1more multi
+uwsgi_cache_key take1
+uwsgi_cache_lock flag
+uwsgi_cache_lock_age take1
+uwsgi_cache_lock_timeout take1
+uwsgi_cache_max_range_offset take1
+uwsgi_cache_methods 1more multi
+uwsgi_cache_min_uses take1
+uwsgi_cache_path 2more multi
+uwsgi_cache_purge 1more
+uwsgi_cache_revalidate flag
+uwsgi_cache_use_stale 1more multi
+uwsgi_cache_valid 1more multi
+uwsgi_connect_timeout take1
+uwsgi_force_ranges flag
+uwsgi_hide_header take1 multi
+uwsgi_ignore_client_abort flag
+uwsgi_ignore_headers 1more multi
+uwsgi_intercept_errors flag
+uwsgi_limit_rate take1
+uwsgi_max_temp_file_size take1
+uwsgi_modifier1 take1
+uwsgi_modifier2 take1
+uwsgi_next_upstream 1more multi
+uwsgi_next_upstream_timeout take1
+uwsgi_next_upstream_tries take1
+uwsgi_no_cache 1more multi
+uwsgi_param take2 take3 multi
+uwsgi_pass take1
+uwsgi_pass_header take1 multi
+uwsgi_pass_request_body flag
+uwsgi_pass_request_headers flag
+uwsgi_read_timeout take1
+uwsgi_request_buffering flag
+uwsgi_send_timeout take1
+uwsgi_socket_keepalive flag
+uwsgi_ssl_certificate take1
+uwsgi_ssl_certificate_key take1
+uwsgi_ssl_ciphers take1
+uwsgi_ssl_conf_command take2 multi
+uwsgi_ssl_crl take1
+uwsgi_ssl_name take1
+uwsgi_ssl_password_file take1
+uwsgi_ssl_protocols 1more multi
+uwsgi_ssl_server_name flag
+uwsgi_ssl_session_reuse flag
+uwsgi_ssl_trusted_certificate take1
+uwsgi_ssl_verify flag
+uwsgi_ssl_verify_depth take1
+uwsgi_store take1
+uwsgi_store_access take1 take2 take3
+uwsgi_temp_file_write_size take1
+uwsgi_temp_path take1 take2 take3 take4
+valid_referers 1more multi
+variables_hash_bucket_size take1
+variables_hash_max_size take1
+worker_aio_requests take1
+worker_connections take1
+worker_cpu_affinity 1more
+worker_priority take1
+worker_processes take1
+worker_rlimit_core take1
+worker_rlimit_nofile take1
+worker_shutdown_timeout take1
+working_directory take1
+xclient flag
+xml_entities take1
+xslt_last_modified flag
+xslt_param take2 multi
+xslt_string_param take2 multi
+xslt_stylesheet 1more multi
+xslt_types 1more
+zone take1 take2
+zone_sync noargs
+zone_sync_buffers take2
+zone_sync_connect_retry_interval take1
+zone_sync_connect_timeout take1
+zone_sync_interval take1
+zone_sync_recv_buffer_size take1
+zone_sync_server take1 take2 multi
+zone_sync_ssl flag
+zone_sync_ssl_certificate take1
+zone_sync_ssl_certificate_key take1
+zone_sync_ssl_ciphers take1
+zone_sync_ssl_conf_command take2 multi
+zone_sync_ssl_crl take1
+zone_sync_ssl_name take1
+zone_sync_ssl_password_file take1
+zone_sync_ssl_protocols 1more multi
+zone_sync_ssl_server_name flag
+zone_sync_ssl_trusted_certificate take1
+zone_sync_ssl_verify flag
+zone_sync_ssl_verify_depth take1
+zone_sync_timeout take1
+`
+
+// DirectiveSpecs maps known directives to their specs, most of them have a single spec
+// that applies to every context
+var DirectiveSpecs map[string][]DirectiveSpec = map[string][]DirectiveSpec{}
+
+func init() {
+	contexts := make(map[string]Context, len(contextNames))
+	for _, cn := range contextNames {
+		contexts[cn.name] = cn.context
+	}
+
+	for _, line := range strings.Split(directiveSpecsRawList, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var spec DirectiveSpec
+		name, scope, _ := strings.Cut(fields[0], "@")
+		if scope != "" {
+			for _, context := range strings.Split(scope, ",") {
+				spec.Contexts |= contexts[context]
+			}
+		}
+		for _, field := range fields[1:] {
+			switch field {
+			case "block":
+				spec.Block = true
+			case "multi":
+				spec.Multiple = true
+			default:
+				spec.Arity |= arityNames[field]
+			}
+		}
+		DirectiveSpecs[name] = append(DirectiveSpecs[name], spec)
+	}
+}
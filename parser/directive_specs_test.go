@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParser_ArgumentValidation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		conf    string
+		wantErr string
+	}{
+		{
+			name: "valid config",
+			conf: `worker_processes auto;
+events {
+    worker_connections 1024;
+}
+http {
+    gzip "on";
+    keepalive_timeout 65 60;
+    upstream backend {
+        server 127.0.0.1:8080 weight=5;
+        random two least_conn;
+    }
+    server {
+        listen 80;
+        location = /a {
+            return 301 /b;
+        }
+        location / {
+            proxy_pass http://backend;
+        }
+    }
+}`,
+		},
+		{
+			name:    "missing argument",
+			conf:    "http {\n    proxy_pass;\n}",
+			wantErr: `invalid number of arguments in "proxy_pass" directive on line 2, column 5`,
+		},
+		{
+			name:    "too many arguments",
+			conf:    "worker_processes 1 2;",
+			wantErr: `invalid number of arguments in "worker_processes" directive on line 1, column 1`,
+		},
+		{
+			name:    "take13 with 2 arguments",
+			conf:    "http {\n    js_import a b;\n}",
+			wantErr: `invalid number of arguments in "js_import" directive on line 2, column 5`,
+		},
+		{
+			name:    "invalid flag",
+			conf:    "http {\n    gzip yes;\n}",
+			wantErr: `invalid value "yes" in "gzip" directive, it must be "on" or "off" on line 2, column 5`,
+		},
+		{
+			name:    "block without opening brace",
+			conf:    "events;",
+			wantErr: `directive "events" has no opening "{" on line 1, column 1`,
+		},
+		{
+			name:    "leaf with a block",
+			conf:    "http {\n    gzip on {}\n}",
+			wantErr: `directive "gzip" is not terminated by ";" on line 2, column 5`,
+		},
+		{
+			name:    "server in upstream takes an address",
+			conf:    "http {\n    upstream a {\n        server;\n    }\n}",
+			wantErr: `invalid number of arguments in "server" directive on line 3, column 9`,
+		},
+		{
+			name:    "server in http takes a block",
+			conf:    "http {\n    server;\n}",
+			wantErr: `directive "server" has no opening "{" on line 2, column 5`,
+		},
+		{
+			name:    "invalid if condition",
+			conf:    "http {\n    server {\n        if ($a = b c) {\n        }\n    }\n}",
+			wantErr: `invalid if condition on line 3, column 9`,
+		},
+		{
+			name:    "invalid geo network",
+			conf:    "http {\n    geo $a {\n        10.0.0.0/33 1;\n    }\n}",
+			wantErr: `invalid network 10.0.0.0/33 on line 2, column 5`,
+		},
+		{
+			name:    "invalid starttls mode",
+			conf:    "mail {\n    starttls always;\n}",
+			wantErr: `invalid value "always" in "starttls" directive, it must be "on", "off" or "only" on line 2, column 5`,
+		},
+		{
+			name:    "unknown mail protocol",
+			conf:    "mail {\n    server {\n        protocol http;\n    }\n}",
+			wantErr: `unknown protocol "http" on line 3, column 9`,
+		},
+		{
+			name:    "map conflicting keys",
+			conf:    "http {\n    map $uri $x {\n        a 1;\n        A 2;\n    }\n}",
+			wantErr: `conflicting map parameter A on line 2, column 5`,
+		},
+		{
+			name:    "map regex Go cannot compile",
+			conf:    "http {\n    map $uri $x {\n        ~^/(?!api) 1;\n    }\n}",
+			wantErr: "map regex ~^/(?!api): error parsing regexp: invalid or unsupported Perl syntax: `(?!` on line 2, column 5",
+		},
+		{
+			name:    "split_clients entry after *",
+			conf:    "http {\n    split_clients $a $b {\n        * x;\n        10% y;\n    }\n}",
+			wantErr: `the * bucket of x must be the last one on line 2, column 5`,
+		},
+		{
+			name:    "split_clients above 100%",
+			conf:    "http {\n    split_clients $a $b {\n        60% a;\n        50% b;\n    }\n}",
+			wantErr: `percent total is greater than 100% on line 2, column 5`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewStringParser(tt.conf, WithArgumentValidation()).Parse()
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tt.wantErr)
+			var argumentErr *ArgumentError
+			assert.Assert(t, errors.As(err, &argumentErr))
+		})
+	}
+}
+
+func TestParser_ArgumentValidation_Disabled(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser("http {\n    gzip yes;\n    proxy_pass;\n}").Parse()
+	assert.NilError(t, err)
+}
+
+func TestParser_DuplicateValidation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		conf    string
+		wantErr string
+	}{
+		{
+			name: "repeatable directives",
+			conf: `http {
+    server {
+        listen 80;
+        listen 443 ssl;
+        add_header A a;
+        add_header B b;
+        location / {
+            root /a;
+        }
+        location /b {
+            root /b;
+        }
+    }
+    server {
+        listen 81;
+    }
+}`,
+		},
+		{
+			name:    "duplicate in the same block",
+			conf:    "http {\n    server {\n        root /a;\n        root /b;\n    }\n}",
+			wantErr: `"root" directive is duplicate on line 4, column 9`,
+		},
+		{
+			name:    "duplicate block",
+			conf:    "events {}\nevents {}",
+			wantErr: `"events" directive is duplicate on line 2, column 1`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewStringParser(tt.conf, WithDuplicateValidation()).Parse()
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParser_DuplicateValidation_DuplicateError(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser("gzip on;\ngzip off;", WithDuplicateValidation()).Parse()
+
+	var duplicateErr *DuplicateError
+	assert.Assert(t, errors.As(err, &duplicateErr))
+	assert.Equal(t, duplicateErr.Name, "gzip")
+	assert.Equal(t, duplicateErr.Previous.Line, 1)
+	assert.Equal(t, duplicateErr.Pos.Line, 2)
+}
+
+func TestParser_DuplicateValidation_IncludedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mainConf := filepath.Join(dir, "nginx.conf")
+	serverConf := filepath.Join(dir, "server.conf")
+	assert.NilError(t, os.WriteFile(mainConf, []byte("http {\n    server {\n        root /a;\n        include server.conf;\n    }\n    server {\n        include server.conf;\n    }\n}\n"), 0644))
+	assert.NilError(t, os.WriteFile(serverConf, []byte("listen 80;\nroot /b;\n"), 0644))
+
+	p, err := NewParser(mainConf, WithIncludeParsing(), WithDuplicateValidation(), WithErrorRecovery())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+
+	// only the first server has root twice, the second one reuses the parsed include
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 1)
+	var duplicateErr *DuplicateError
+	assert.Assert(t, errors.As(diagnostics[0], &duplicateErr))
+	assert.Equal(t, duplicateErr.Pos.Filename, serverConf)
+	assert.Equal(t, duplicateErr.Previous.Filename, mainConf)
+}
+
+func TestArity_Allows(t *testing.T) {
+	t.Parallel()
+	assert.Assert(t, ArityNoArgs.Allows(0))
+	assert.Assert(t, !ArityNoArgs.Allows(1))
+	assert.Assert(t, (ArityTake1 | ArityTake3).Allows(3))
+	assert.Assert(t, !(ArityTake1 | ArityTake3).Allows(2))
+	assert.Assert(t, Arity1More.Allows(9))
+	assert.Assert(t, !Arity2More.Allows(1))
+	assert.Assert(t, ArityFlag.Allows(1))
+	assert.Assert(t, ArityAny.Allows(0))
+}
+
+func TestDirectiveSpecs(t *testing.T) {
+	t.Parallel()
+	spec, ok := LookupDirectiveSpec("server", ContextUpstream)
+	assert.Assert(t, ok)
+	assert.Equal(t, spec, DirectiveSpec{Arity: Arity1More, Multiple: true, Contexts: ContextUpstream | ContextStreamUpstream})
+
+	spec, ok = LookupDirectiveSpec("server", ContextHTTP)
+	assert.Assert(t, ok)
+	assert.Assert(t, spec.Block)
+
+	// every known directive has a spec
+	for name := range ValidDirectives {
+		if name == "" || name == "default" {
+			continue
+		}
+		_, ok := DirectiveSpecs[name]
+		assert.Assert(t, ok, name)
+	}
+}
@@ -0,0 +1,280 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tufanbarisyildirim/gonginx/parser/lua"
+	"github.com/tufanbarisyildirim/gonginx/parser/token"
+)
+
+// ErrIncludeCycle is wrapped by the *IncludeError returned when WithIncludeCycleErr is set and a file includes itself
+var ErrIncludeCycle = errors.New("include cycle detected")
+
+// Diagnostic is an error the parser recovered from, with the position it was found at
+type Diagnostic struct {
+	Pos token.Position
+	Err error
+}
+
+// Error returns the error message
+func (d *Diagnostic) Error() string {
+	return d.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Diagnostics is the list of errors collected by a parser running with WithErrorRecovery
+type Diagnostics []*Diagnostic
+
+// Error joins all messages, one per line, prefixed by their position
+func (ds Diagnostics) Error() string {
+	lines := make([]string, 0, len(ds))
+	for _, d := range ds {
+		lines = append(lines, fmt.Sprintf("%s: %s", d.Pos, d.Err))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns all collected errors, so errors.Is and errors.As look into each of them
+func (ds Diagnostics) Unwrap() []error {
+	errs := make([]error, 0, len(ds))
+	for _, d := range ds {
+		errs = append(errs, d)
+	}
+	return errs
+}
+
+// SyntaxError reports malformed input, such as an unexpected token,
+// an unterminated quoted string or an unclosed block
+type SyntaxError struct {
+	Pos          token.Position
+	Token        token.Token      // the offending token
+	Message      string           // the error message, without snippet
+	IncludeChain []token.Position // positions of the include directives that led to the file, outermost first
+	Snippet      string           // the source line with a caret under Pos
+}
+
+// Error returns the error message
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+// UnknownDirectiveError reports a directive that is neither a known nginx directive nor a custom one
+type UnknownDirectiveError struct {
+	Pos          token.Position
+	Token        token.Token
+	Name         string
+	Suggestions  []string // known directive names close to Name, best match first
+	IncludeChain []token.Position
+	Snippet      string
+}
+
+// Error returns the error message
+func (e *UnknownDirectiveError) Error() string {
+	return fmt.Sprintf("unknown directive '%s' on line %d, column %d", e.Name, e.Pos.Line, e.Pos.Column)
+}
+
+// ContextError reports a known directive placed in a context nginx does not allow it in,
+// e.g. proxy_pass at the top level
+type ContextError struct {
+	Pos          token.Position
+	Token        token.Token
+	Name         string
+	Context      Context // the context the directive was found in
+	Allowed      Context // the contexts the directive is allowed in
+	IncludeChain []token.Position
+	Snippet      string
+}
+
+// Error returns the error message
+func (e *ContextError) Error() string {
+	return fmt.Sprintf("directive '%s' is not allowed in %s context on line %d, column %d", e.Name, e.Context, e.Pos.Line, e.Pos.Column)
+}
+
+// ArgumentError reports a known directive whose arguments or block do not match its DirectiveSpec
+type ArgumentError struct {
+	Pos          token.Position
+	Name         string
+	Message      string // the error message, without position
+	IncludeChain []token.Position
+	Snippet      string
+}
+
+// Error returns the error message
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("%s on line %d, column %d", e.Message, e.Pos.Line, e.Pos.Column)
+}
+
+// DuplicateError reports a directive that nginx accepts once per block, found again in the same block
+type DuplicateError struct {
+	Pos          token.Position
+	Name         string
+	Previous     token.Position // position of the first occurrence
+	IncludeChain []token.Position
+	Snippet      string
+}
+
+// Error returns the error message
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("\"%s\" directive is duplicate on line %d, column %d", e.Name, e.Pos.Line, e.Pos.Column)
+}
+
+// DirectiveError reports a directive that could not be turned into its typed wrapper,
+// e.g. a location with too many arguments
+type DirectiveError struct {
+	Pos          token.Position
+	Name         string
+	IncludeChain []token.Position
+	Snippet      string
+	Err          error
+}
+
+// Error returns the error message of the wrapper
+func (e *DirectiveError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the wrapper
+func (e *DirectiveError) Unwrap() error {
+	return e.Err
+}
+
+// IncludeError reports an include directive whose files could not be resolved or opened
+type IncludeError struct {
+	Pos          token.Position
+	Path         string // the include path, or the matched file when the error is about a single file
+	IncludeChain []token.Position
+	Snippet      string
+	Err          error
+}
+
+// Error returns the error message
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("include '%s' on line %d, column %d: %v", e.Path, e.Pos.Line, e.Pos.Column, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// LuaError reports a syntax error in the Lua code of a *_by_lua_block directive,
+// at its position in the nginx file. Err holds the position in the code
+type LuaError struct {
+	Pos          token.Position
+	Name         string // the directive name, e.g. content_by_lua_block
+	Message      string // the Lua error message, e.g. "'=' expected near 'x'"
+	IncludeChain []token.Position
+	Snippet      string
+	Err          *lua.SyntaxError
+}
+
+// Error returns the error message
+func (e *LuaError) Error() string {
+	return fmt.Sprintf("lua syntax error in \"%s\": %s on line %d, column %d", e.Name, e.Message, e.Pos.Line, e.Pos.Column)
+}
+
+// Unwrap returns the error in the code
+func (e *LuaError) Unwrap() error {
+	return e.Err
+}
+
+// snippet renders the source line of pos with a caret under its column
+func snippet(source []byte, pos token.Position) string {
+	if !pos.IsValid() || pos.Offset > len(source) {
+		return ""
+	}
+
+	start := bytes.LastIndexByte(source[:pos.Offset], '\n') + 1
+	end := len(source)
+	if i := bytes.IndexByte(source[pos.Offset:], '\n'); i >= 0 {
+		end = pos.Offset + i
+	}
+	line := strings.TrimRight(string(source[start:end]), "\r")
+
+	// keep tabs so that the caret lines up with the source
+	var marker strings.Builder
+	for _, r := range string(source[start:pos.Offset]) {
+		if r == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+	marker.WriteRune('^')
+
+	number := strconv.Itoa(pos.Line)
+	gutter := strings.Repeat(" ", len(number))
+	return fmt.Sprintf("%s | %s\n%s | %s", number, line, gutter, marker.String())
+}
+
+// suggestDirectives returns up to 3 known directive names close to name
+func suggestDirectives(name string, custom map[string]string) []string {
+	maxDistance := len(name) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	if maxDistance > 3 {
+		maxDistance = 3
+	}
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	candidates := make([]candidate, 0)
+	seen := make(map[string]struct{})
+	for _, directives := range []map[string]string{ValidDirectives, custom} {
+		for known := range directives {
+			if _, ok := seen[known]; ok || known == "" {
+				continue
+			}
+			seen[known] = struct{}{}
+			if d := editDistance(name, known); d <= maxDistance {
+				candidates = append(candidates, candidate{known, d})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	suggestions := make([]string, 0, 3)
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
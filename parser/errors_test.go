@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tufanbarisyildirim/gonginx/parser/token"
+	"gotest.tools/v3/assert"
+)
+
+func TestParser_UnknownDirectiveError(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser(`http {
+	server {
+		proxy_pas http://a;
+	}
+}`).Parse()
+
+	var unknown *UnknownDirectiveError
+	assert.Assert(t, errors.As(err, &unknown))
+	assert.Error(t, err, "unknown directive 'proxy_pas' on line 3, column 3")
+	assert.Equal(t, unknown.Name, "proxy_pas")
+	assert.Equal(t, unknown.Pos, token.Position{Offset: 19, Line: 3, Column: 3})
+	assert.Equal(t, unknown.Suggestions[0], "proxy_pass")
+	assert.Equal(t, unknown.Snippet, "3 | \t\tproxy_pas http://a;\n  | \t\t^")
+}
+
+func TestParser_ErrorMessagesUsePos(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		conf    string
+		opts    []Option
+		wantErr string
+		wantPos token.Position
+	}{
+		{
+			conf:    "http { server { listen 80; foo on; } }",
+			wantErr: "unknown directive 'foo' on line 1, column 28",
+			wantPos: token.Position{Offset: 27, Line: 1, Column: 28},
+		},
+		{
+			conf:    "http { proxy_pass http://a; }",
+			opts:    []Option{WithContextValidation()},
+			wantErr: "directive 'proxy_pass' is not allowed in http context on line 1, column 8",
+			wantPos: token.Position{Offset: 7, Line: 1, Column: 8},
+		},
+		{
+			conf:    "user \"nginx;",
+			wantErr: "unexpected end of file while scanning quoted string starting at line 1, column 6",
+			wantPos: token.Position{Offset: 5, Line: 1, Column: 6},
+		},
+		{
+			conf:    "user nginx }",
+			wantErr: "unexpected token BlockEnd (}) on line 1, column 12",
+			wantPos: token.Position{Offset: 11, Line: 1, Column: 12},
+		},
+	}
+	for _, tt := range tests {
+		_, err := NewStringParser(tt.conf, tt.opts...).Parse()
+		assert.ErrorContains(t, err, tt.wantErr)
+
+		// the message, Pos and the diagnostics of error recovery agree
+		_, err = NewStringParser(tt.conf, append(tt.opts, WithErrorRecovery())...).Parse()
+		var diagnostics Diagnostics
+		assert.Assert(t, errors.As(err, &diagnostics), tt.conf)
+		assert.Equal(t, diagnostics[0].Pos, tt.wantPos)
+		assert.Equal(t, diagnostics[:1].Error(), fmt.Sprintf("%s: %s", tt.wantPos, tt.wantErr))
+	}
+}
+
+func TestParser_UnknownDirectiveError_SuggestsCustomDirectives(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser("my_directiv on;", WithCustomDirectives("my_directive")).Parse()
+
+	var unknown *UnknownDirectiveError
+	assert.Assert(t, errors.As(err, &unknown))
+	assert.DeepEqual(t, unknown.Suggestions, []string{"my_directive"})
+}
+
+func TestParser_SyntaxError(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser("server {\n    listen 80;\n").Parse()
+
+	var syntaxErr *SyntaxError
+	assert.Assert(t, errors.As(err, &syntaxErr))
+	assert.Error(t, err, "unexpected eof in block")
+	assert.Equal(t, syntaxErr.Token.Type, token.EOF)
+	assert.Equal(t, syntaxErr.Pos.Line, 3)
+
+	_, err = NewStringParser("server {\n    listen \"80;\n}\n").Parse()
+	assert.Assert(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, syntaxErr.Token.Type, token.QuotedString)
+	assert.Equal(t, syntaxErr.Pos.Line, 2)
+	assert.Equal(t, syntaxErr.Snippet, "2 |     listen \"80;\n  |            ^")
+}
+
+func TestParser_DirectiveError(t *testing.T) {
+	t.Parallel()
+	_, err := NewStringParser("server {\n    location a b c {}\n}").Parse()
+
+	var directiveErr *DirectiveError
+	assert.Assert(t, errors.As(err, &directiveErr))
+	assert.Error(t, err, "too many arguments for location directive")
+	assert.Equal(t, directiveErr.Name, "location")
+	assert.Equal(t, directiveErr.Pos.Line, 2)
+	assert.Equal(t, directiveErr.Snippet, "2 |     location a b c {}\n  |     ^")
+}
+
+func TestParser_IncludeError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mainConf := filepath.Join(dir, "main.conf")
+	aConf := filepath.Join(dir, "a.conf")
+	bConf := filepath.Join(dir, "b.conf")
+
+	assert.NilError(t, os.WriteFile(mainConf, []byte("http {\n    include a.conf;\n}\n"), 0644))
+	assert.NilError(t, os.WriteFile(aConf, []byte("include b.conf;\n"), 0644))
+	assert.NilError(t, os.WriteFile(bConf, []byte("include a.conf;\n"), 0644))
+
+	p, err := NewParser(mainConf, WithIncludeParsing(), WithIncludeCycleErr())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+
+	var includeErr *IncludeError
+	assert.Assert(t, errors.As(err, &includeErr))
+	assert.Assert(t, errors.Is(err, ErrIncludeCycle))
+	assert.Equal(t, includeErr.Path, aConf)
+	assert.Equal(t, includeErr.Pos.Filename, bConf)
+	assert.Equal(t, includeErr.Snippet, "1 | include a.conf;\n  | ^")
+
+	// the include directives that led to b.conf, outermost first
+	assert.Equal(t, len(includeErr.IncludeChain), 2)
+	assert.Equal(t, includeErr.IncludeChain[0].String(), mainConf+":2:5")
+	assert.Equal(t, includeErr.IncludeChain[1].String(), aConf+":1:1")
+}
+
+func TestParser_ErrorsInIncludedFilesKeepTheirChain(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mainConf := filepath.Join(dir, "main.conf")
+	aConf := filepath.Join(dir, "a.conf")
+
+	assert.NilError(t, os.WriteFile(mainConf, []byte("include a.conf;\n"), 0644))
+	assert.NilError(t, os.WriteFile(aConf, []byte("listen 80;\nlisen 81;\n"), 0644))
+
+	p, err := NewParser(mainConf, WithIncludeParsing(), WithErrorRecovery())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+
+	var unknown *UnknownDirectiveError
+	assert.Assert(t, errors.As(err, &unknown))
+	assert.Equal(t, unknown.Pos.Filename, aConf)
+	assert.Equal(t, len(unknown.IncludeChain), 1)
+	assert.Equal(t, unknown.IncludeChain[0].String(), mainConf+":1:1")
+	assert.Equal(t, unknown.Snippet, "2 | lisen 81;\n  | ^")
+	assert.Equal(t, unknown.Suggestions[0], "listen")
+}
+
+func TestSuggestDirectives(t *testing.T) {
+	t.Parallel()
+	assert.DeepEqual(t, suggestDirectives("servr", nil)[0], "server")
+	assert.DeepEqual(t, suggestDirectives("completely_unrelated_name", nil), []string{})
+}
@@ -0,0 +1,235 @@
+package parser
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"gotest.tools/v3/assert"
+)
+
+func TestNewFSParser(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"etc/nginx/nginx.conf": {Data: []byte(`http {
+    include mime.types;
+    include /etc/nginx/conf.d/*.conf;
+}`)},
+		"etc/nginx/mime.types":          {Data: []byte("types {\n    text/html html;\n}\n")},
+		"etc/nginx/conf.d/a.conf":       {Data: []byte("server {\n    listen 80;\n}\n")},
+		"etc/nginx/conf.d/b.conf":       {Data: []byte("server {\n    listen 81;\n}\n")},
+		"etc/nginx/conf.d/.hidden.conf": {Data: []byte("server {\n    listen 82;\n}\n")},
+	}
+
+	p, err := NewFSParser(fsys, "/etc/nginx/nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	c, err := p.Parse()
+	assert.NilError(t, err)
+
+	assert.Equal(t, c.FilePath, "etc/nginx/nginx.conf")
+	includes := c.FindDirectives("include")
+	assert.Equal(t, len(includes), 2)
+	assert.Equal(t, includes[0].(*config.Include).Configs[0].FilePath, "etc/nginx/mime.types")
+
+	listens := c.FindDirectives("listen")
+	assert.Equal(t, len(listens), 2)
+	assert.Equal(t, listens[0].GetParameters()[0].Value, "80")
+	assert.Equal(t, listens[1].GetParameters()[0].Value, "81")
+	assert.Equal(t, listens[1].GetSpan().Start.Filename, "etc/nginx/conf.d/b.conf")
+}
+
+func TestNewFSParser_Types(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf":  {Data: []byte("http {\n    include mime.types;\n    include extra.types;\n}\n")},
+		"mime.types":  {Data: []byte("types {\n    application/octet-stream bin wasm;\n    text/html html;\n}\n")},
+		"extra.types": {Data: []byte("types {\n    application/wasm wasm;\n}\n")},
+	}
+
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	c, err := p.Parse()
+	assert.NilError(t, err)
+
+	types := c.FindTypes()
+	assert.Equal(t, len(types), 2)
+	mime, ok := config.LookupType("wasm", types...)
+	assert.Assert(t, ok)
+	assert.Equal(t, mime, "application/wasm")
+	duplicates := config.DuplicateExtensions(types...)
+	assert.Equal(t, len(duplicates), 1)
+	assert.Equal(t, duplicates[0].Previous.GetSpan().Start.Filename, "mime.types")
+	assert.Equal(t, duplicates[0].Directive.GetSpan().Start.Filename, "extra.types")
+}
+
+func TestNewFSParser_StreamInclude(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf":       {Data: []byte("stream {\n    include streams/*.conf;\n}\n")},
+		"streams/dns.conf": {Data: []byte("server {\n    listen 53 udp;\n    proxy_pass 10.0.0.1:53;\n}\n")},
+	}
+
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing(), WithContextValidation())
+	assert.NilError(t, err)
+	c, err := p.Parse()
+	assert.NilError(t, err)
+
+	servers := c.FindDirectives("stream")[0].(*config.Stream).Servers()
+	assert.Equal(t, len(servers), 1)
+	assert.Equal(t, servers[0].ProxyPass(), "10.0.0.1:53")
+}
+
+func TestNewFSParser_IncludeContexts(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf":  {Data: []byte("http {\n    include common.conf;\n}\nstream {\n    include common.conf;\n}\n")},
+		"common.conf": {Data: []byte("proxy_pass backend;\n")},
+	}
+
+	// the file is checked in each context it is included in
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing(), WithContextValidation(), WithErrorRecovery())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 2)
+	assert.ErrorContains(t, diagnostics[0], "directive 'proxy_pass' is not allowed in http context")
+	assert.ErrorContains(t, diagnostics[1], "directive 'proxy_pass' is not allowed in stream context")
+}
+
+func TestNewFSParser_IncludeSubsystems(t *testing.T) {
+	t.Parallel()
+	for _, conf := range []string{
+		"stream {\n    include s.conf;\n}\nhttp {\n    include s.conf;\n}\n",
+		"http {\n    include s.conf;\n}\nstream {\n    include s.conf;\n}\n",
+	} {
+		fsys := fstest.MapFS{
+			"nginx.conf": {Data: []byte(conf)},
+			"s.conf":     {Data: []byte("upstream backend {\n    server 127.0.0.1:8080;\n}\nserver {\n    listen 8080;\n}\n")},
+		}
+		p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing())
+		assert.NilError(t, err)
+		c, err := p.Parse()
+		assert.NilError(t, err)
+
+		// each subsystem gets its own types
+		stream := c.FindDirectives("stream")[0].(*config.Stream)
+		assert.Equal(t, len(stream.Servers()), 1)
+		assert.Equal(t, len(stream.Upstreams()), 1)
+		http := c.FindDirectives("http")[0].(*config.HTTP)
+		configs := http.FindDirectives("include")[0].(*config.Include).Configs
+		assert.Equal(t, len(configs), 1)
+		_, ok := configs[0].GetDirectives()[0].(*config.Upstream)
+		assert.Assert(t, ok)
+		_, ok = configs[0].GetDirectives()[1].(*config.Server)
+		assert.Assert(t, ok)
+	}
+}
+
+func TestNewFSParser_IncludeDuplicates(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("http {\n    include b.conf;\n    include b.conf;\n}\n")},
+		"b.conf":     {Data: []byte("sendfile on;\n")},
+	}
+
+	// the second include reuses the parsed file and still reports its directives
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing(), WithDuplicateValidation())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+	var duplicateErr *DuplicateError
+	assert.Assert(t, errors.As(err, &duplicateErr))
+	assert.Equal(t, duplicateErr.Name, "sendfile")
+	assert.Equal(t, duplicateErr.Pos.Filename, "b.conf")
+	assert.Equal(t, len(duplicateErr.IncludeChain), 1)
+	assert.Equal(t, duplicateErr.IncludeChain[0].Line, 3)
+	assert.Equal(t, duplicateErr.Snippet, "1 | sendfile on;\n  | ^")
+}
+
+func TestNewFSParser_MissingFile(t *testing.T) {
+	t.Parallel()
+	_, err := NewFSParser(fstest.MapFS{}, "nginx.conf")
+	assert.Assert(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestWithFS_IncludeFromString(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"conf.d/a.conf": {Data: []byte("listen 80;\n")},
+	}
+
+	// an unsaved buffer, with its includes read from the FS
+	c, err := NewStringParser("server {\n    include conf.d/*.conf;\n}", WithIncludeParsing(), WithFS(fsys)).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, len(c.FindDirectives("listen")), 1)
+}
+
+func TestWithFS_IncludeCycle(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("include a.conf;\n")},
+		"a.conf":     {Data: []byte("include nginx.conf;\n")},
+	}
+
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+	assert.NilError(t, err)
+
+	p, err = NewFSParser(fsys, "nginx.conf", WithIncludeParsing(), WithIncludeCycleErr())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+	assert.Assert(t, errors.Is(err, ErrIncludeCycle))
+}
+
+func TestWithFS_ErrorSnippets(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("include a.conf;\n")},
+		"a.conf":     {Data: []byte("listen 80;\nlisen 81;\n")},
+	}
+
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+
+	var unknown *UnknownDirectiveError
+	assert.Assert(t, errors.As(err, &unknown))
+	assert.Equal(t, unknown.Pos.Filename, "a.conf")
+	assert.Equal(t, unknown.Snippet, "2 | lisen 81;\n  | ^")
+}
+
+func TestNewFSParser_MapInclude(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"etc/nginx/nginx.conf": {Data: []byte(`http {
+    map $uri $target {
+        default /;
+        include redirects.map;
+        /old /new;
+    }
+}`)},
+		"etc/nginx/redirects.map": {Data: []byte("# generated\n/a /b;\n~^/blog/(?<slug>.+)$ /posts/$slug;\n")},
+	}
+
+	p, err := NewFSParser(fsys, "/etc/nginx/nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	c, err := p.Parse()
+	assert.NilError(t, err)
+
+	// the entries of the included file are read without validation, ~ keys as regexes
+	m, ok := c.FindDirectives("map")[0].(*config.Map)
+	assert.Assert(t, ok)
+	assert.Equal(t, len(m.Includes), 1)
+	assert.Equal(t, len(m.Entries), 3)
+	result, matched := m.Lookup("/blog/hello")
+	assert.Equal(t, result, "/posts/hello")
+	assert.Assert(t, matched)
+	result, _ = m.Lookup("/a")
+	assert.Equal(t, result, "/b")
+	result, matched = m.Lookup("/missing")
+	assert.Equal(t, result, "/")
+	assert.Assert(t, !matched)
+}
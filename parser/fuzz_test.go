@@ -27,5 +27,8 @@ func FuzzParserParseNoPanic(f *testing.F) {
 
 		p := NewStringParser(conf, WithSkipValidDirectivesErr())
 		_, _ = p.Parse()
+
+		p = NewStringParser(conf, WithErrorRecovery(), WithPreserveTrivia())
+		_, _ = p.Parse()
 	})
 }
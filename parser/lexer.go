@@ -7,33 +7,70 @@ import (
 	"io"
 	"strings"
 
+	"github.com/tufanbarisyildirim/gonginx/parser/lua"
 	"github.com/tufanbarisyildirim/gonginx/parser/token"
 )
 
 // lexer is the main tokenizer
 type lexer struct {
-	reader     *bufio.Reader
-	file       string
-	line       int
-	column     int
-	inLuaBlock bool
-	Latest     token.Token
-	Err        error
+	reader    *bufio.Reader
+	file      string
+	line      int
+	column    int
+	pos       token.Position
+	rawBlocks rawBlocks    // blocks whose body is read verbatim, see WithRawBlocks
+	rawBody   *RawLanguage // language of the block body to scan next
+	source    *bytes.Buffer
+	directive string   // name of the directive being scanned, empty between statements
+	args      int      // number of its parameters scanned so far
+	lastArg   string   // literal of its latest parameter
+	blocks    []string // names of the enclosing block directives
+	Latest    token.Token
+	Err       error
 }
 
 // lex initializes a lexer from string conetnt
 func lex(content string) *lexer {
-	return newLexer(bytes.NewBuffer([]byte(content)))
+	l := newLexer(strings.NewReader(content))
+	// the whole content is at hand, keep it for trivia and error snippets
+	l.source = bytes.NewBufferString(content)
+	return l
 }
 
 // newLexer initilizes a lexer from a reader
 func newLexer(r io.Reader) *lexer {
 	return &lexer{
 		line:   1,
+		pos:    token.Position{Line: 1, Column: 1},
 		reader: bufio.NewReader(r),
 	}
 }
 
+// keepSource records every byte read from the input, so that the source text
+// can be sliced by token offsets. It must be called before the first scan.
+func (s *lexer) keepSource() {
+	if s.source != nil {
+		return
+	}
+	s.source = &bytes.Buffer{}
+	s.reader = bufio.NewReader(io.TeeReader(s.reader, s.source))
+}
+
+// sourceText returns the source between two byte offsets
+func (s *lexer) sourceText(from, to int) string {
+	if s.source == nil {
+		return ""
+	}
+	src := s.source.Bytes()
+	if to > len(src) {
+		to = len(src)
+	}
+	if from < 0 || from >= to {
+		return ""
+	}
+	return string(src[from:to])
+}
+
 // Scan gives you next token
 func (s *lexer) scan() token.Token {
 	if s.Err != nil {
@@ -42,9 +79,89 @@ func (s *lexer) scan() token.Token {
 	}
 
 	s.Latest = s.getNextToken()
+	s.Latest.End = s.position()
+	s.track(s.Latest)
 	return s.Latest
 }
 
+// track follows the statement and the block being scanned, to tell regular expressions apart
+func (s *lexer) track(tok token.Token) {
+	switch {
+	case tok.IsParameterEligible():
+		if s.directive == "" {
+			s.directive = tok.Literal
+			return
+		}
+		s.args++
+		s.lastArg = tok.Literal
+		return
+	case tok.Is(token.BlockStart):
+		s.blocks = append(s.blocks, s.directive)
+	case tok.Is(token.BlockEnd):
+		if len(s.blocks) > 0 {
+			s.blocks = s.blocks[:len(s.blocks)-1]
+		}
+	case !tok.Is(token.Semicolon):
+		// comments and line ends do not end a statement
+		return
+	}
+	s.directive, s.args, s.lastArg = "", 0, ""
+}
+
+// classify turns a keyword or a quoted string into a Regex or Variable token from its place in the statement
+func (s *lexer) classify(tok token.Token) token.Token {
+	if s.isRegex(tok.Literal) {
+		tok.Type = token.Regex
+	} else if tok.Is(token.Keyword) && isVariable(tok.Literal) {
+		tok.Type = token.Variable
+	}
+	return tok
+}
+
+// isRegex reports whether the next parameter is a regular expression:
+// after a ~, ~*, !~ or !~* operator (location, if), the first parameter of rewrite,
+// a ~ server name or a ~ key of a map block
+func (s *lexer) isRegex(literal string) bool {
+	if s.directive == "" {
+		return len(s.blocks) > 0 && s.blocks[len(s.blocks)-1] == "map" && hasRegexPrefix(literal)
+	}
+	switch s.lastArg {
+	case "~", "~*", "!~", "!~*":
+		if s.args > 0 {
+			return true
+		}
+	}
+	return (s.directive == "rewrite" && s.args == 0) || (s.directive == "server_name" && hasRegexPrefix(literal))
+}
+
+// hasRegexPrefix reports whether a possibly quoted literal is a ~regex
+func hasRegexPrefix(literal string) bool {
+	if len(literal) > 0 && isQuote(rune(literal[0])) {
+		literal = literal[1:]
+	}
+	return len(literal) > 1 && literal[0] == '~'
+}
+
+// isVariable reports whether a keyword is a single variable, $name or ${name}
+func isVariable(literal string) bool {
+	name, ok := strings.CutPrefix(literal, "$")
+	if !ok {
+		return false
+	}
+	if braced, ok := strings.CutPrefix(name, "{"); ok {
+		name, ok = strings.CutSuffix(braced, "}")
+		if !ok {
+			return false
+		}
+	}
+	for _, ch := range name {
+		if ch != '_' && (ch < 'a' || ch > 'z') && (ch < 'A' || ch > 'Z') && (ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
 // All scans all token and returns them as a slice
 func (s *lexer) all() token.Tokens {
 	tokens := make([]token.Token, 0)
@@ -59,9 +176,12 @@ func (s *lexer) all() token.Tokens {
 }
 
 func (s *lexer) getNextToken() token.Token {
-	if s.inLuaBlock {
-		s.inLuaBlock = false
-		return s.scanLuaCode()
+	if language := s.rawBody; language != nil {
+		s.rawBody = nil
+		if language.Name == LanguageLua.Name {
+			return s.scanLuaCode()
+		}
+		return s.scanRawCode(*language)
 	}
 reToken:
 	ch := s.peek()
@@ -74,8 +194,8 @@ reToken:
 	case ch == ';':
 		return s.NewToken(token.Semicolon).Lit(string(s.read()))
 	case ch == '{':
-		if isLuaBlock(s.Latest) {
-			s.inLuaBlock = true
+		if language, ok := s.rawBlocks.lookup(s.directive); ok {
+			s.rawBody = &language
 		}
 		return s.NewToken(token.BlockStart).Lit(string(s.read()))
 	case ch == '}':
@@ -145,9 +265,17 @@ func (s *lexer) NewToken(tokenType token.Type) token.Token {
 		Type:   tokenType,
 		Line:   s.line,
 		Column: s.column,
+		Pos:    s.position(),
 	}
 }
 
+// position returns the position of the next rune to be read
+func (s *lexer) position() token.Position {
+	pos := s.pos
+	pos.Filename = s.file
+	return pos
+}
+
 func (s *lexer) readWhile(while runeCheck) string {
 	var buf bytes.Buffer
 	buf.WriteRune(s.read())
@@ -171,41 +299,154 @@ func (s *lexer) scanComment() token.Token {
 	return s.NewToken(token.Comment).Lit(s.readUntil(isEndOfLine))
 }
 
-// TODO: support unpaired bracket in string
+// scanLuaCode returns the body of a *_by_lua_block verbatim, up to the brace closing the block.
+// Braces inside Lua strings, long brackets ([[ ]], [==[ ]==]) and comments (--, --[[ ]]) are not counted.
+// A # is read as a comment where it cannot be the length operator of Lua, as lua.HashComment tells
 func (s *lexer) scanLuaCode() token.Token {
 	// used to save the real line and column
 	ret := s.NewToken(token.LuaCode)
-	stack := make([]rune, 0, 50)
 	code := strings.Builder{}
+	depth := 0
+	last := "" // the last Lua token as given to lua.HashComment, or the end of operators
 
 	for {
+		prev := s.pos
 		ch := s.read()
 		if ch == rune(token.EOF) {
-			s.setErrOnce("unexpected end of file while scanning lua code starting at line %d, column %d", ret.Line, ret.Column)
+			s.setErrOnce(ret, "unexpected end of file while scanning lua code starting at line %d, column %d", ret.Pos.Line, ret.Pos.Column)
 			return s.NewToken(token.EOF).Lit("")
 		}
-		if ch == '#' {
+
+		switch {
+		case ch == '}' && depth == 0:
+			// the end of block
+			_ = s.reader.UnreadRune()
+			s.pos = prev
+			return ret.Lit(code.String())
+		case ch == '}':
+			depth--
+		case ch == '{':
+			depth++
+		case ch == '-' && s.peek() == '-':
 			code.WriteRune(ch)
-			code.WriteString(s.readUntil(isEndOfLine))
-			continue
-		} else if ch == '}' {
-			if len(stack) == 0 {
-				// the end of block
-				_ = s.reader.UnreadRune()
-				return ret.Lit(code.String())
+			code.WriteRune(s.read())
+			if s.peek() == '[' {
+				code.WriteRune(s.read())
+				if level, ok := s.luaLongBracket(); ok {
+					code.WriteString(s.readLuaLongBracket(level))
+					continue
+				}
 			}
-			// maybe it's lua table end, pop stack
-			if stack[len(stack)-1] == '{' {
-				stack = stack[0 : len(stack)-1]
+			code.WriteString(s.readLuaUntil(isEndOfLine))
+			continue
+		case ch == '#' && lua.HashComment(last):
+			code.WriteRune(ch)
+			code.WriteString(s.readLuaUntil(isEndOfLine))
+			continue
+		case ch == '"' || ch == '\'':
+			code.WriteRune(ch)
+			code.WriteString(s.readLuaString(ch))
+			last = `"`
+			continue
+		case ch == '[':
+			if level, ok := s.luaLongBracket(); ok {
+				code.WriteRune(ch)
+				code.WriteString(s.readLuaLongBracket(level))
+				last = `"`
+				continue
 			}
-		} else if ch == '{' {
-			// maybe it's lua table start, push stack
-			stack = append(stack, ch)
 		}
 		code.WriteRune(ch)
+		switch {
+		case isSpace(ch) || isEndOfLine(ch):
+		case isLuaNameChar(ch) && last != "" && isLuaNameChar(rune(last[len(last)-1])):
+			last += string(ch)
+		case (ch == '.' || ch == ':') && strings.Trim(last, string(ch)) == "":
+			last += string(ch)
+		default:
+			last = string(ch)
+		}
 	}
 }
 
+func isLuaNameChar(ch rune) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch >= 0x80
+}
+
+// luaLongBracket reports whether the next runes, following a [ already read, open a Lua long bracket
+// ([ or =...=[), without consuming them. level is the number of = signs
+func (s *lexer) luaLongBracket() (level int, ok bool) {
+	for {
+		next, err := s.reader.Peek(level + 1)
+		if err != nil {
+			return 0, false
+		}
+		switch next[level] {
+		case '=':
+			level++
+		case '[':
+			return level, true
+		default:
+			return 0, false
+		}
+	}
+}
+
+// readLuaLongBracket reads the rest of a long bracket of the given level, up to and including its closing bracket.
+// At the end of file, the read text is returned and the error is reported by scanLuaCode
+func (s *lexer) readLuaLongBracket(level int) string {
+	var buf strings.Builder
+	// the opening =...=[
+	for i := 0; i <= level; i++ {
+		buf.WriteRune(s.read())
+	}
+	closing := strings.Repeat("=", level) + "]"
+	for {
+		ch := s.peek()
+		if isEOF(ch) {
+			return buf.String()
+		}
+		buf.WriteRune(s.read())
+		if ch != ']' {
+			continue
+		}
+		if next, err := s.reader.Peek(len(closing)); err == nil && string(next) == closing {
+			for range closing {
+				buf.WriteRune(s.read())
+			}
+			return buf.String()
+		}
+	}
+}
+
+// readLuaString reads the rest of a Lua short string, up to and including its closing quote.
+// A backslash escapes the next rune; an unfinished string ends at the end of the line
+func (s *lexer) readLuaString(quote rune) string {
+	var buf strings.Builder
+	for {
+		ch := s.peek()
+		if isEOF(ch) || isEndOfLine(ch) {
+			return buf.String()
+		}
+		buf.WriteRune(s.read())
+		if ch == quote {
+			return buf.String()
+		}
+		if ch == '\\' && !isEOF(s.peek()) {
+			buf.WriteRune(s.read())
+		}
+	}
+}
+
+// readLuaUntil reads up to, but not including, the first rune matching until
+func (s *lexer) readLuaUntil(until runeCheck) string {
+	var buf strings.Builder
+	for ch := s.peek(); !isEOF(ch) && !until(ch); ch = s.peek() {
+		buf.WriteRune(s.read())
+	}
+	return buf.String()
+}
+
 /*
 *
 \” – To escape " within double quoted string.
@@ -222,11 +463,12 @@ func (s *lexer) scanQuotedString(delimiter rune) token.Token {
 		ch := s.read()
 
 		if ch == rune(token.EOF) {
-			s.setErrOnce("unexpected end of file while scanning quoted string starting at line %d, column %d", tok.Line, tok.Column)
+			s.setErrOnce(tok, "unexpected end of file while scanning quoted string starting at line %d, column %d", tok.Pos.Line, tok.Pos.Column)
 			return s.NewToken(token.EOF).Lit("")
 		}
 
-		if ch == '\\' && (s.peek() == delimiter) {
+		// as in nginx, a backslash escapes any character, \\ included
+		if ch == '\\' && !isEOF(s.peek()) {
 			buf.WriteRune(ch)       // the backslash
 			buf.WriteRune(s.read()) // the char needed escaping
 			continue
@@ -238,7 +480,7 @@ func (s *lexer) scanQuotedString(delimiter rune) token.Token {
 		}
 	}
 
-	return tok.Lit(buf.String())
+	return s.classify(tok.Lit(buf.String()))
 }
 
 func (s *lexer) scanKeyword() token.Token {
@@ -285,20 +527,24 @@ func (s *lexer) scanKeyword() token.Token {
 		}
 	}
 
-	return tok.Lit(buf.String())
+	return s.classify(tok.Lit(buf.String()))
 }
 
 func (s *lexer) read() rune {
-	ch, _, err := s.reader.ReadRune()
+	ch, size, err := s.reader.ReadRune()
 	if err != nil {
 		return rune(token.EOF)
 	}
 
+	s.pos.Offset += size
 	if ch == '\n' {
 		s.column = 1
 		s.line++
+		s.pos.Line++
+		s.pos.Column = 1
 	} else {
 		s.column++
+		s.pos.Column++
 	}
 	return ch
 }
@@ -319,14 +565,18 @@ func isEndOfLine(ch rune) bool {
 	return ch == '\r' || ch == '\n'
 }
 
-func isLuaBlock(t token.Token) bool {
-	return t.Type == token.Keyword && strings.HasSuffix(t.Literal, "_by_lua_block")
+func isLuaBlock(directive string) bool {
+	return strings.HasSuffix(directive, "_by_lua_block")
 }
 
-func (s *lexer) setErrOnce(format string, args ...any) {
+func (s *lexer) setErrOnce(tok token.Token, format string, args ...any) {
 	if s.Err != nil {
 		return
 	}
 
-	s.Err = fmt.Errorf(format, args...)
+	s.Err = &SyntaxError{
+		Pos:     tok.Pos,
+		Token:   tok,
+		Message: fmt.Sprintf(format, args...),
+	}
 }
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"errors"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"github.com/tufanbarisyildirim/gonginx/parser/lua"
+	"github.com/tufanbarisyildirim/gonginx/parser/token"
+)
+
+// ValidateLuaBlock checks the syntax of the Lua code of a *_by_lua_block directive and returns
+// a *LuaError for the first error found. The error position is in the nginx file when the block
+// was parsed, and in the code when it was built programmatically. Other directives are ignored
+func ValidateLuaBlock(d config.IDirective) error {
+	block := d.GetBlock()
+	if block == nil || !isLuaBlock(d.GetName()) {
+		return nil
+	}
+	code := block.GetCodeBlock()
+	syntaxErr := checkLua(code)
+	if syntaxErr == nil {
+		return nil
+	}
+	err := luaError(d, syntaxErr)
+	err.Snippet = snippet([]byte(code), token.Position{Offset: syntaxErr.Offset, Line: err.Pos.Line})
+	return err
+}
+
+// ValidateLua checks the syntax of every *_by_lua_block directive of the config,
+// included configs too, and returns the errors found as Diagnostics
+func ValidateLua(c *config.Config) error {
+	var diagnostics Diagnostics
+	validateLuaBlock(c.Block, &diagnostics)
+	if len(diagnostics) > 0 {
+		return diagnostics
+	}
+	return nil
+}
+
+func validateLuaBlock(b config.IBlock, diagnostics *Diagnostics) {
+	if b == nil {
+		return
+	}
+	for _, directive := range b.GetDirectives() {
+		if err := ValidateLuaBlock(directive); err != nil {
+			var luaErr *LuaError
+			errors.As(err, &luaErr)
+			*diagnostics = append(*diagnostics, &Diagnostic{Pos: luaErr.Pos, Err: err})
+		}
+		if include, ok := directive.(*config.Include); ok {
+			for _, c := range include.Configs {
+				validateLuaBlock(c.Block, diagnostics)
+			}
+		}
+		if !isLuaBlock(directive.GetName()) {
+			validateLuaBlock(directive.GetBlock(), diagnostics)
+		}
+	}
+}
+
+// validateLua checks the code of a parsed *_by_lua_block directive when WithLuaValidation is set
+func (p *Parser) validateLua(d *config.Directive) error {
+	if !p.opts.luaValidation {
+		return nil
+	}
+	syntaxErr := checkLua(d.Block.GetCodeBlock())
+	if syntaxErr == nil {
+		return nil
+	}
+	err := luaError(d, syntaxErr)
+	err.IncludeChain = p.includeChain
+	err.Snippet = p.snippet(err.Pos)
+	if p.tolerate(err.Pos, err) {
+		return nil
+	}
+	return err
+}
+
+func checkLua(code string) *lua.SyntaxError {
+	var syntaxErr *lua.SyntaxError
+	if errors.As(lua.Check(code), &syntaxErr) {
+		return syntaxErr
+	}
+	return nil
+}
+
+// luaError creates a *LuaError for a syntax error in the code of d, mapping its position
+// from the code to the nginx file with the position of the opening brace of the block
+func luaError(d config.IDirective, err *lua.SyntaxError) *LuaError {
+	pos := token.Position{Offset: err.Offset, Line: err.Line, Column: err.Column}
+	if lbrace, _ := d.GetBlock().GetBraces(); lbrace.IsValid() {
+		// the code starts right after the brace
+		pos.Filename = lbrace.End.Filename
+		pos.Offset += lbrace.End.Offset
+		pos.Line += lbrace.End.Line - 1
+		if err.Line == 1 {
+			pos.Column += lbrace.End.Column - 1
+		}
+	}
+	return &LuaError{
+		Pos:     pos,
+		Name:    d.GetName(),
+		Message: err.Message,
+		Err:     err,
+	}
+}
@@ -0,0 +1,339 @@
+package lua
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+type kind int
+
+const (
+	kindEOF kind = iota
+	kindName
+	kindNumber
+	kindString
+	kindKeyword
+	kindSymbol
+)
+
+// token is a Lua token, text is the source text of the token
+type token struct {
+	kind    kind
+	text    string
+	offset  int
+	line    int
+	column  int
+	endLine int // line of the end of the token, long strings span several lines
+}
+
+// near returns the token as Lua shows it in error messages
+func (t token) near() string {
+	if t.kind == kindEOF {
+		return "<eof>"
+	}
+	return t.text
+}
+
+// is reports whether the token is the given keyword or symbol
+func (t token) is(text string) bool {
+	return (t.kind == kindKeyword || t.kind == kindSymbol) && t.text == text
+}
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true,
+	"false": true, "for": true, "function": true, "goto": true, "if": true, "in": true,
+	"local": true, "nil": true, "not": true, "or": true, "repeat": true, "return": true,
+	"then": true, "true": true, "until": true, "while": true,
+}
+
+// symbols, longest first
+var symbols = []string{
+	"...", "..", "==", "~=", "<=", ">=", "::",
+	"+", "-", "*", "/", "%", "^", "#", "<", ">", "=", "(", ")", "{", "}", "[", "]", ";", ":", ",", ".",
+}
+
+// numbers as read by LuaJIT, with the 64 bit integer and imaginary suffixes of its FFI
+var numberRegexp = regexp.MustCompile(`^(?:(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?|0[xX](?:[0-9a-fA-F]+\.?[0-9a-fA-F]*|\.[0-9a-fA-F]+)(?:[pP][+-]?[0-9]+)?)[iI]?$|^(?:[0-9]+|0[xX][0-9a-fA-F]+)[uU]?[lL][lL]$`)
+
+// scanner splits Lua code into tokens, it panics with a *SyntaxError on invalid input
+type scanner struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int    // offset of the start of the current line
+	last      string // text of the last token, " for strings, as given to HashComment
+}
+
+// HashComment reports whether a # following the Lua token last starts a comment up to the end of the line,
+// nginx-style comments being common in Lua blocks. It is a comment where it cannot be the length operator:
+// at the start of the code, after an expression or a statement. last is "" at the start and " after a string
+func HashComment(last string) bool {
+	switch last {
+	case "", `"`, ")", "]", "}", ";", "...", "::":
+		return true
+	case "and", "or", "not", "return", "if", "elseif", "while", "until", "in":
+		return false
+	}
+	return isNameChar(last[len(last)-1])
+}
+
+// errorAt reports a syntax error at the start of t
+func errorAt(t token, format string, args ...any) {
+	panic(&SyntaxError{
+		Offset:  t.offset,
+		Line:    t.line,
+		Column:  t.column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// here returns an empty token at the current position
+func (s *scanner) here() token {
+	return token{offset: s.pos, line: s.line, column: utf8.RuneCountInString(s.src[s.lineStart:s.pos]) + 1}
+}
+
+func (s *scanner) peekByte(i int) byte {
+	if s.pos+i < len(s.src) {
+		return s.src[s.pos+i]
+	}
+	return 0
+}
+
+// newline moves past a line end, \r\n and \n\r count as one
+func (s *scanner) newline() {
+	first := s.src[s.pos]
+	s.pos++
+	if next := s.peekByte(0); (next == '\n' || next == '\r') && next != first {
+		s.pos++
+	}
+	s.line++
+	s.lineStart = s.pos
+}
+
+// scan returns the next token
+func (s *scanner) scan() token {
+	t := s.scanToken()
+	s.last = t.text
+	if t.kind == kindString {
+		s.last = `"`
+	}
+	return t
+}
+
+func (s *scanner) scanToken() token {
+	s.skipSpacesAndComments()
+	t := s.here()
+	if s.pos >= len(s.src) {
+		t.kind = kindEOF
+		t.endLine = s.line
+		return t
+	}
+
+	ch := s.src[s.pos]
+	switch {
+	case isNameStart(ch):
+		for s.pos < len(s.src) && isNameChar(s.src[s.pos]) {
+			s.pos++
+		}
+		t.kind = kindName
+		if keywords[s.src[t.offset:s.pos]] {
+			t.kind = kindKeyword
+		}
+	case isDigit(ch) || (ch == '.' && isDigit(s.peekByte(1))):
+		s.scanNumber(t)
+		t.kind = kindNumber
+	case ch == '"' || ch == '\'':
+		s.scanString(t)
+		t.kind = kindString
+	case ch == '[' && (s.peekByte(1) == '[' || s.peekByte(1) == '='):
+		level, ok := s.longBracket()
+		if !ok {
+			errorAt(t, "invalid long string delimiter near '%s'", s.src[t.offset:s.pos+1+level])
+		}
+		s.scanLongBracket(level, "string")
+		t.kind = kindString
+	default:
+		for _, symbol := range symbols {
+			if strings.HasPrefix(s.src[s.pos:], symbol) {
+				s.pos += len(symbol)
+				t.kind = kindSymbol
+				t.text = symbol
+				t.endLine = s.line
+				return t
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s.src[s.pos:])
+		errorAt(t, "unexpected symbol near '%s'", s.src[s.pos:s.pos+size])
+	}
+	t.text = s.src[t.offset:s.pos]
+	t.endLine = s.line
+	return t
+}
+
+func (s *scanner) skipSpacesAndComments() {
+	for s.pos < len(s.src) {
+		switch ch := s.src[s.pos]; {
+		case ch == '\n' || ch == '\r':
+			s.newline()
+		case ch == ' ' || ch == '\t' || ch == '\v' || ch == '\f':
+			s.pos++
+		case ch == '#' && HashComment(s.last):
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' && s.src[s.pos] != '\r' {
+				s.pos++
+			}
+		case ch == '-' && s.peekByte(1) == '-':
+			s.pos += 2
+			if s.peekByte(0) == '[' {
+				if level, ok := s.longBracket(); ok {
+					s.scanLongBracket(level, "comment")
+					continue
+				}
+			}
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' && s.src[s.pos] != '\r' {
+				s.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// longBracket reports whether the scanner is at a long bracket opening, [[ or [=...=[.
+// level is the number of = signs
+func (s *scanner) longBracket() (level int, ok bool) {
+	for s.peekByte(1+level) == '=' {
+		level++
+	}
+	return level, s.peekByte(1+level) == '['
+}
+
+// scanLongBracket moves past a long string or comment of the given level,
+// the scanner is at its opening bracket
+func (s *scanner) scanLongBracket(level int, what string) {
+	s.pos += level + 2
+	closing := "]" + strings.Repeat("=", level) + "]"
+	for s.pos < len(s.src) {
+		switch {
+		case strings.HasPrefix(s.src[s.pos:], closing):
+			s.pos += len(closing)
+			return
+		case s.src[s.pos] == '\n' || s.src[s.pos] == '\r':
+			s.newline()
+		default:
+			s.pos++
+		}
+	}
+	errorAt(s.here(), "unfinished long %s near '<eof>'", what)
+}
+
+func (s *scanner) scanNumber(t token) {
+	s.pos++
+	for s.pos < len(s.src) {
+		ch := s.src[s.pos]
+		if isNameChar(ch) || ch == '.' || ((ch == '+' || ch == '-') && strings.IndexByte("eEpP", s.src[s.pos-1]) >= 0) {
+			s.pos++
+			continue
+		}
+		break
+	}
+	if text := s.src[t.offset:s.pos]; !numberRegexp.MatchString(text) {
+		errorAt(t, "malformed number near '%s'", text)
+	}
+}
+
+func (s *scanner) scanString(t token) {
+	quote := s.src[s.pos]
+	s.pos++
+	for {
+		if s.pos >= len(s.src) || s.src[s.pos] == '\n' || s.src[s.pos] == '\r' {
+			errorAt(t, "unfinished string near '%s'", s.src[t.offset:s.pos])
+		}
+		ch := s.src[s.pos]
+		if ch == quote {
+			s.pos++
+			return
+		}
+		if ch != '\\' {
+			s.pos++
+			continue
+		}
+
+		s.pos++
+		switch esc := s.peekByte(0); {
+		case strings.IndexByte("abfnrtv\\\"'", esc) >= 0:
+			s.pos++
+		case esc == '\n' || esc == '\r':
+			s.newline()
+		case esc == 'z':
+			// skips the following spaces, line ends included
+			s.pos++
+			for s.pos < len(s.src) && strings.IndexByte(" \t\v\f\r\n", s.src[s.pos]) >= 0 {
+				if s.src[s.pos] == '\n' || s.src[s.pos] == '\r' {
+					s.newline()
+				} else {
+					s.pos++
+				}
+			}
+		case esc == 'x':
+			s.pos++
+			for i := 0; i < 2; i++ {
+				if !isHexDigit(s.peekByte(0)) {
+					s.invalidEscape(t)
+				}
+				s.pos++
+			}
+		case esc == 'u':
+			s.pos++
+			if s.peekByte(0) != '{' || !isHexDigit(s.peekByte(1)) {
+				s.invalidEscape(t)
+			}
+			s.pos++
+			for isHexDigit(s.peekByte(0)) {
+				s.pos++
+			}
+			if s.peekByte(0) != '}' {
+				s.invalidEscape(t)
+			}
+			s.pos++
+		case isDigit(esc):
+			value := 0
+			for i := 0; i < 3 && isDigit(s.peekByte(0)); i++ {
+				value = value*10 + int(s.peekByte(0)-'0')
+				s.pos++
+			}
+			if value > 255 {
+				s.invalidEscape(t)
+			}
+		case s.pos >= len(s.src):
+			// unfinished, reported above
+		default:
+			s.invalidEscape(t)
+		}
+	}
+}
+
+func (s *scanner) invalidEscape(t token) {
+	end := s.pos + 1
+	if end > len(s.src) {
+		end = len(s.src)
+	}
+	errorAt(t, "invalid escape sequence near '%s'", s.src[t.offset:end])
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch >= 0x80
+}
+
+func isNameChar(ch byte) bool {
+	return isNameStart(ch) || isDigit(ch)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
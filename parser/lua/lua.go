@@ -0,0 +1,443 @@
+// Package lua checks the syntax of Lua code as LuaJIT, the Lua of OpenResty, reads it:
+// Lua 5.1 with goto, labels, and the escapes and number suffixes of LuaJIT.
+package lua
+
+import (
+	"fmt"
+)
+
+// SyntaxError is a Lua syntax error
+type SyntaxError struct {
+	Offset  int    // byte offset in the code, starting at 0
+	Line    int    // line number in the code, starting at 1
+	Column  int    // column number in runes, starting at 1
+	Message string // the error message as Lua reports it, e.g. "'=' expected near 'x'"
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s on line %d, column %d", e.Message, e.Line, e.Column)
+}
+
+// Check parses code and returns its first syntax error as a *SyntaxError, or nil
+func Check(code string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = syntaxErr
+		}
+	}()
+
+	p := &parser{scanner: scanner{src: code, line: 1}, vararg: []bool{true}}
+	p.next()
+	p.block()
+	if p.tok.kind != kindEOF {
+		p.errorExpected("<eof>")
+	}
+	return nil
+}
+
+// parser is a recursive descent parser following the grammar of the Lua 5.1 manual
+type parser struct {
+	scanner
+	tok      token
+	ahead    *token
+	lastLine int    // line where the previous token ends
+	vararg   []bool // whether the enclosing functions take ..., innermost last
+	loops    int    // number of loops enclosing the statement in the current function
+}
+
+func (p *parser) next() {
+	p.lastLine = p.tok.endLine
+	if p.ahead != nil {
+		p.tok, p.ahead = *p.ahead, nil
+		return
+	}
+	p.tok = p.scan()
+}
+
+func (p *parser) lookahead() token {
+	if p.ahead == nil {
+		t := p.scan()
+		p.ahead = &t
+	}
+	return *p.ahead
+}
+
+func (p *parser) errorNear(format string, args ...any) {
+	errorAt(p.tok, "%s near '%s'", fmt.Sprintf(format, args...), p.tok.near())
+}
+
+func (p *parser) errorExpected(what string) {
+	p.errorNear("'%s' expected", what)
+}
+
+// accept moves past the given keyword or symbol when it is the current token
+func (p *parser) accept(text string) bool {
+	if p.tok.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) {
+	if !p.accept(text) {
+		p.errorExpected(text)
+	}
+}
+
+// expectMatch expects the token closing what was opened by who on line
+func (p *parser) expectMatch(what, who string, line int) {
+	if p.accept(what) {
+		return
+	}
+	if line == p.tok.line {
+		p.errorExpected(what)
+	}
+	p.errorNear("'%s' expected (to close '%s' at line %d)", what, who, line)
+}
+
+func (p *parser) name() {
+	if p.tok.kind != kindName {
+		p.errorNear("<name> expected")
+	}
+	p.next()
+}
+
+func (p *parser) blockFollow() bool {
+	if p.tok.kind == kindEOF {
+		return true
+	}
+	return p.tok.kind == kindKeyword && (p.tok.text == "else" || p.tok.text == "elseif" || p.tok.text == "end" || p.tok.text == "until")
+}
+
+// block parses statements up to the end of the block, return being the last one
+func (p *parser) block() {
+	for !p.blockFollow() {
+		if p.tok.is("return") {
+			p.next()
+			if !p.blockFollow() && !p.tok.is(";") {
+				p.exprList()
+			}
+			p.accept(";")
+			return
+		}
+		p.statement()
+	}
+}
+
+func (p *parser) statement() {
+	line := p.tok.line
+	switch {
+	case p.accept(";"):
+	case p.accept("if"):
+		p.condition("then")
+		for p.accept("elseif") {
+			p.condition("then")
+		}
+		if p.accept("else") {
+			p.block()
+		}
+		p.expectMatch("end", "if", line)
+	case p.accept("while"):
+		p.expression()
+		p.expect("do")
+		p.loopBlock()
+		p.expectMatch("end", "while", line)
+	case p.accept("do"):
+		p.block()
+		p.expectMatch("end", "do", line)
+	case p.accept("for"):
+		p.forStatement(line)
+	case p.accept("repeat"):
+		p.loopBlock()
+		p.expectMatch("until", "repeat", line)
+		p.expression()
+	case p.accept("function"):
+		p.name()
+		for p.accept(".") {
+			p.name()
+		}
+		method := p.accept(":")
+		if method {
+			p.name()
+		}
+		p.functionBody(line)
+	case p.accept("local"):
+		if p.accept("function") {
+			p.name()
+			p.functionBody(line)
+			break
+		}
+		p.name()
+		for p.accept(",") {
+			p.name()
+		}
+		if p.accept("=") {
+			p.exprList()
+		}
+	case p.tok.is("break"):
+		if p.loops == 0 {
+			p.errorNear("no loop to break")
+		}
+		p.next()
+	case p.accept("goto"):
+		p.name()
+	case p.accept("::"):
+		p.name()
+		p.expect("::")
+	default:
+		p.expressionStatement()
+	}
+}
+
+// condition parses an expression followed by keyword and a block
+func (p *parser) condition(keyword string) {
+	p.expression()
+	p.expect(keyword)
+	p.block()
+}
+
+func (p *parser) forStatement(line int) {
+	p.name()
+	switch {
+	case p.accept("="):
+		p.expression()
+		p.expect(",")
+		p.expression()
+		if p.accept(",") {
+			p.expression()
+		}
+	case p.tok.is(",") || p.tok.is("in"):
+		for p.accept(",") {
+			p.name()
+		}
+		p.expect("in")
+		p.exprList()
+	default:
+		p.errorNear("'=' or 'in' expected")
+	}
+	p.expect("do")
+	p.loopBlock()
+	p.expectMatch("end", "for", line)
+}
+
+// loopBlock parses the body of a loop, where break is allowed
+func (p *parser) loopBlock() {
+	p.loops++
+	p.block()
+	p.loops--
+}
+
+// expressionStatement parses a function call or an assignment
+func (p *parser) expressionStatement() {
+	assignable, call := p.suffixedExpression()
+	if !p.tok.is("=") && !p.tok.is(",") {
+		if !call {
+			p.errorNear("syntax error")
+		}
+		return
+	}
+	for {
+		if !assignable {
+			p.errorNear("syntax error")
+		}
+		if !p.accept(",") {
+			break
+		}
+		assignable, _ = p.suffixedExpression()
+	}
+	p.expect("=")
+	p.exprList()
+}
+
+func (p *parser) exprList() {
+	p.expression()
+	for p.accept(",") {
+		p.expression()
+	}
+}
+
+// binary operators with their left and right priorities
+var binaryPriority = map[string][2]int{
+	"or":  {1, 1},
+	"and": {2, 2},
+	"<":   {3, 3},
+	">":   {3, 3},
+	"<=":  {3, 3},
+	">=":  {3, 3},
+	"~=":  {3, 3},
+	"==":  {3, 3},
+	"..":  {5, 4},
+	"+":   {6, 6},
+	"-":   {6, 6},
+	"*":   {7, 7},
+	"/":   {7, 7},
+	"%":   {7, 7},
+	"^":   {10, 9},
+}
+
+const unaryPriority = 8
+
+func (p *parser) expression() {
+	p.subExpression(0)
+}
+
+// subExpression parses an expression whose binary operators are greater than limit
+func (p *parser) subExpression(limit int) {
+	if p.tok.is("not") || p.tok.is("-") || p.tok.is("#") {
+		p.next()
+		p.subExpression(unaryPriority)
+	} else {
+		p.simpleExpression()
+	}
+	for {
+		if p.tok.kind != kindKeyword && p.tok.kind != kindSymbol {
+			return
+		}
+		priority, ok := binaryPriority[p.tok.text]
+		if !ok || priority[0] <= limit {
+			return
+		}
+		p.next()
+		p.subExpression(priority[1])
+	}
+}
+
+func (p *parser) simpleExpression() {
+	switch {
+	case p.tok.kind == kindNumber || p.tok.kind == kindString:
+		p.next()
+	case p.tok.is("nil") || p.tok.is("true") || p.tok.is("false"):
+		p.next()
+	case p.tok.is("..."):
+		if !p.vararg[len(p.vararg)-1] {
+			p.errorNear("cannot use '...' outside a vararg function")
+		}
+		p.next()
+	case p.tok.is("{"):
+		p.table()
+	case p.tok.is("function"):
+		line := p.tok.line
+		p.next()
+		p.functionBody(line)
+	default:
+		p.suffixedExpression()
+	}
+}
+
+// suffixedExpression parses a name or a parenthesized expression followed by
+// fields, indexes and calls. It reports whether the expression can be assigned
+// and whether it is a function call
+func (p *parser) suffixedExpression() (assignable, call bool) {
+	switch {
+	case p.tok.kind == kindName:
+		p.next()
+		assignable = true
+	case p.tok.is("("):
+		line := p.tok.line
+		p.next()
+		p.expression()
+		p.expectMatch(")", "(", line)
+	default:
+		p.errorNear("unexpected symbol")
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			p.name()
+			assignable, call = true, false
+		case p.tok.is("["):
+			p.next()
+			p.expression()
+			p.expect("]")
+			assignable, call = true, false
+		case p.accept(":"):
+			p.name()
+			p.callArguments()
+			assignable, call = false, true
+		case p.tok.is("(") || p.tok.is("{") || p.tok.kind == kindString:
+			p.callArguments()
+			assignable, call = false, true
+		default:
+			return assignable, call
+		}
+	}
+}
+
+func (p *parser) callArguments() {
+	switch {
+	case p.tok.kind == kindString:
+		p.next()
+	case p.tok.is("{"):
+		p.table()
+	case p.tok.is("("):
+		// Lua 5.1 reads a line starting with ( as a new statement
+		if p.tok.line != p.lastLine {
+			p.errorNear("ambiguous syntax (function call x new statement)")
+		}
+		open := p.tok.line
+		p.next()
+		if !p.tok.is(")") {
+			p.exprList()
+		}
+		p.expectMatch(")", "(", open)
+	default:
+		p.errorNear("function arguments expected")
+	}
+}
+
+func (p *parser) table() {
+	line := p.tok.line
+	p.expect("{")
+	for !p.tok.is("}") {
+		switch {
+		case p.tok.is("["):
+			p.next()
+			p.expression()
+			p.expect("]")
+			p.expect("=")
+			p.expression()
+		case p.tok.kind == kindName && p.lookahead().is("="):
+			p.next()
+			p.next()
+			p.expression()
+		default:
+			p.expression()
+		}
+		if !p.accept(",") && !p.accept(";") {
+			break
+		}
+	}
+	p.expectMatch("}", "{", line)
+}
+
+// functionBody parses the parameters and the body of a function defined on line
+func (p *parser) functionBody(line int) {
+	p.expect("(")
+	vararg := false
+	if !p.tok.is(")") {
+		for {
+			if p.accept("...") {
+				vararg = true
+				break
+			}
+			p.name()
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	p.expect(")")
+
+	// a loop around the function does not allow break in its body
+	loops := p.loops
+	p.vararg, p.loops = append(p.vararg, vararg), 0
+	p.block()
+	p.vararg, p.loops = p.vararg[:len(p.vararg)-1], loops
+	p.expectMatch("end", "function", line)
+}
@@ -0,0 +1,69 @@
+package lua
+
+import (
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCheck_Valid(t *testing.T) {
+	t.Parallel()
+	tests := []string{
+		"",
+		"ngx.say('hello')",
+		"local a = 1\nreturn a",
+		"local t = {a = 1, [2] = 3; 4, f = function(...) return ... end,}",
+		"for i = 1, 10, 2 do print(i) end\nfor k, v in pairs(t) do end",
+		"if a then elseif b then else end\nwhile x do break end\nrepeat x = x - 1 until x == 0",
+		"function a.b:c(x, ...) local n = select('#', ...) end\nlocal function f() end",
+		"a.b[1]:c 'x' {y}\n;(f)()",
+		"goto done\n::done::",
+		"local s = [==[ ]] ]==] .. 0x1p4 .. 1e-3 .. 10ULL .. 0x10LL .. 1i\n--[[ comment\n]] x = \"\\z\n  \\x41\\u{48}\\65\"",
+		"x = #t + -1 ^ 2 .. 'a' == not b and c or d",
+		"a, b.c, d[1] = 1, 2",
+		"local x = [[\n]]\n(f)()",
+		"return;",
+		"# comment {\nx = f(1) # comment\nlocal n = # t + #{1} .. # \"a\"\nreturn\n  # t",
+		"while x do break end\nfor i = 1, 2 do if i then break end end\nrepeat do break end until x",
+	}
+	for _, code := range tests {
+		assert.NilError(t, Check(code), code)
+	}
+}
+
+func TestCheck_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		code string
+		want SyntaxError
+	}{
+		{"local foo = if", SyntaxError{12, 1, 13, "unexpected symbol near 'if'"}},
+		{"x", SyntaxError{1, 1, 2, "syntax error near '<eof>'"}},
+		{"f() = 1", SyntaxError{4, 1, 5, "syntax error near '='"}},
+		{"function f() return ... end", SyntaxError{20, 1, 21, "cannot use '...' outside a vararg function near '...'"}},
+		{"print(\"a\\q\")", SyntaxError{6, 1, 7, "invalid escape sequence near '\"a\\q'"}},
+		{"local x = \"abc\nx = 1", SyntaxError{10, 1, 11, "unfinished string near '\"abc'"}},
+		{"local x = 3x", SyntaxError{10, 1, 11, "malformed number near '3x'"}},
+		{"while true do\n  x = 1\n", SyntaxError{22, 3, 1, "'end' expected (to close 'while' at line 1) near '<eof>'"}},
+		{"if x y", SyntaxError{5, 1, 6, "'then' expected near 'y'"}},
+		{"for in", SyntaxError{4, 1, 5, "<name> expected near 'in'"}},
+		{"for i do", SyntaxError{6, 1, 7, "'=' or 'in' expected near 'do'"}},
+		{"f\n(1)", SyntaxError{2, 2, 1, "ambiguous syntax (function call x new statement) near '('"}},
+		{"return 1; x = 2", SyntaxError{10, 1, 11, "'<eof>' expected near 'x'"}},
+		{"x = #", SyntaxError{5, 1, 6, "unexpected symbol near '<eof>'"}},
+		{"if x then break end", SyntaxError{10, 1, 11, "no loop to break near 'break'"}},
+		{"while x do f = function() break end end", SyntaxError{26, 1, 27, "no loop to break near 'break'"}},
+		{"x = [==[ abc ]]", SyntaxError{15, 1, 16, "unfinished long string near '<eof>'"}},
+		{"x = 'é' @", SyntaxError{9, 1, 9, "unexpected symbol near '@'"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.code, func(t *testing.T) {
+			t.Parallel()
+			var syntaxErr *SyntaxError
+			assert.Assert(t, errors.As(Check(tt.code), &syntaxErr))
+			assert.DeepEqual(t, *syntaxErr, tt.want)
+		})
+	}
+}
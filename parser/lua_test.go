@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"github.com/tufanbarisyildirim/gonginx/parser/lua"
+	"github.com/tufanbarisyildirim/gonginx/parser/token"
+	"gotest.tools/v3/assert"
+)
+
+func TestParser_LuaValidation(t *testing.T) {
+	t.Parallel()
+	conf := `server {
+    location / {
+        content_by_lua_block {
+            local name = ngx.var.arg_name
+            if name then
+                ngx.say("hello ", name)
+            end
+        }
+        access_by_lua_block { local x = = 1 }
+        rewrite_by_lua_block {
+            local t = {
+                a = 1
+                b = 2
+            }
+        }
+    }
+}`
+	// the code is not checked by default
+	_, err := NewStringParser(conf).Parse()
+	assert.NilError(t, err)
+
+	_, err = NewStringParser(conf, WithLuaValidation()).Parse()
+	var luaErr *LuaError
+	assert.Assert(t, errors.As(err, &luaErr))
+	assert.Error(t, err, `lua syntax error in "access_by_lua_block": unexpected symbol near '=' on line 9, column 41`)
+	assert.Equal(t, luaErr.Name, "access_by_lua_block")
+	assert.DeepEqual(t, luaErr.Pos, token.Position{Offset: 230, Line: 9, Column: 41})
+	assert.Equal(t, luaErr.Snippet, "9 |         access_by_lua_block { local x = = 1 }\n  |                                         ^")
+
+	// the position in the code is kept
+	var syntaxErr *lua.SyntaxError
+	assert.Assert(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, syntaxErr.Line, 1)
+	assert.Equal(t, syntaxErr.Column, 12)
+
+	c, err := NewStringParser(conf, WithLuaValidation(), WithErrorRecovery()).Parse()
+	assert.Assert(t, c != nil)
+	assert.Error(t, err, "9:41: lua syntax error in \"access_by_lua_block\": unexpected symbol near '=' on line 9, column 41\n"+
+		"13:17: lua syntax error in \"rewrite_by_lua_block\": '}' expected (to close '{' at line 2) near 'b' on line 13, column 17")
+}
+
+func TestParser_LuaValidation_Includes(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("http {\n    include lua.conf;\n}\n")},
+		"lua.conf":   {Data: []byte("init_by_lua_block {\n    require(\"cjson\"\n}\n")},
+	}
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing(), WithLuaValidation())
+	assert.NilError(t, err)
+	_, err = p.Parse()
+
+	var luaErr *LuaError
+	assert.Assert(t, errors.As(err, &luaErr))
+	assert.Equal(t, luaErr.Pos.String(), "lua.conf:3:1")
+	assert.Equal(t, luaErr.Message, "')' expected (to close '(' at line 2) near '<eof>'")
+	assert.Equal(t, len(luaErr.IncludeChain), 1)
+	assert.Equal(t, luaErr.IncludeChain[0].String(), "nginx.conf:2:5")
+}
+
+func TestParser_LuaValidation_HashComments(t *testing.T) {
+	t.Parallel()
+	// nginx-style comments in Lua blocks are read as the lexer reads them
+	for _, file := range []string{"../testdata/issues/20.conf", "../testdata/issues/22.conf"} {
+		p, err := NewParser(file, WithLuaValidation())
+		assert.NilError(t, err)
+		_, err = p.Parse()
+		assert.NilError(t, err, file)
+	}
+}
+
+func TestValidateLua(t *testing.T) {
+	t.Parallel()
+	c, err := NewStringParser(`http {
+    init_by_lua_block {
+        require "resty.core"
+    }
+    server {
+        set_by_lua_block $a { return 1 + }
+        location / {
+            content_by_lua_block {
+                ngx.say("ok")
+                ngx.exit(200
+            }
+        }
+    }
+}`).Parse()
+	assert.NilError(t, err)
+
+	err = ValidateLua(c)
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 2)
+	assert.Equal(t, diagnostics[0].Pos.String(), "6:42")
+	assert.Equal(t, diagnostics[1].Pos.String(), "11:13")
+
+	var luaErr *LuaError
+	assert.Assert(t, errors.As(diagnostics[0], &luaErr))
+	assert.Equal(t, luaErr.Name, "set_by_lua_block")
+	assert.Equal(t, luaErr.Message, "unexpected symbol near '<eof>'")
+	assert.Equal(t, luaErr.Snippet, "6 |  return 1 + \n  |             ^")
+
+	assert.NilError(t, ValidateLuaBlock(c.FindDirectives("init_by_lua_block")[0]))
+}
+
+func TestValidateLuaBlock_Programmatic(t *testing.T) {
+	t.Parallel()
+	// without braces from the parser, the position is the one in the code
+	d := &config.Directive{
+		Name:  "content_by_lua_block",
+		Block: &config.Block{IsLuaBlock: true, LiteralCode: "\nngx.say('a')\nngx.say('b'\n"},
+	}
+	err := ValidateLuaBlock(d)
+	var luaErr *LuaError
+	assert.Assert(t, errors.As(err, &luaErr))
+	assert.Equal(t, luaErr.Pos, token.Position{Offset: 26, Line: 4, Column: 1})
+	assert.Equal(t, luaErr.Snippet, "4 | \n  | ^")
+
+	assert.NilError(t, ValidateLuaBlock(&config.Directive{Name: "server", Block: &config.Block{}}))
+}
@@ -4,8 +4,12 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/tufanbarisyildirim/gonginx/config"
@@ -21,8 +25,19 @@ type options struct {
 	includeCycleErr            bool
 	skipComments               bool
 	customDirectives           map[string]string
+	customDirectiveContexts    map[string]Context
 	skipValidSubDirectiveBlock map[string]struct{}
 	skipValidDirectivesErr     bool
+	preserveTrivia             bool
+	errorRecovery              bool
+	contextValidation          bool
+	rootContext                Context
+	argumentValidation         bool
+	duplicateValidation        bool
+	luaValidation              bool
+	annotations                *config.AnnotationSyntax
+	rawBlocks                  rawBlocks
+	fsys                       fs.FS
 }
 
 func defaultOptions() options {
@@ -32,27 +47,50 @@ func defaultOptions() options {
 		includeCycleErr:            false,
 		skipComments:               false,
 		customDirectives:           map[string]string{},
+		customDirectiveContexts:    map[string]Context{},
 		skipValidSubDirectiveBlock: map[string]struct{}{},
 		skipValidDirectivesErr:     false,
+		preserveTrivia:             false,
+		errorRecovery:              false,
+		contextValidation:          false,
+		rootContext:                ContextMain,
+		argumentValidation:         false,
+		duplicateValidation:        false,
+		luaValidation:              false,
+		annotations:                nil,
+		rawBlocks:                  rawBlocks{},
+		fsys:                       nil,
 	}
 }
 
 // Parser is an nginx config parser
 type Parser struct {
-	opts              options
-	configRoot        string // TODO: confirmation needed (whether this is the parent of nginx.conf)
-	lexer             *lexer
-	currentToken      token.Token
-	followingToken    token.Token
-	parsedIncludes    map[string]*config.Config
-	includeStack      map[string]struct{}
-	statementParsers  map[string]func() (config.IDirective, error)
-	blockWrappers     map[string]func(*config.Directive) (config.IDirective, error)
-	directiveWrappers map[string]func(*config.Directive) (config.IDirective, error)
-	includeWrappers   map[string]func(*config.Directive) (config.IDirective, error)
-
-	commentBuffer []string
-	file          *os.File
+	opts             options
+	configRoot       string // TODO: confirmation needed (whether this is the parent of nginx.conf)
+	lexer            *lexer
+	currentToken     token.Token
+	followingToken   token.Token
+	parsedIncludes   map[includeKey]*config.Config
+	includeStack     map[string]struct{}
+	includeChain     []token.Position
+	context          Context                   // context of the block being parsed, 0 when unknown
+	block            string                    // name of the block being parsed, for the files it includes
+	skipValid        bool                      // whether the directives of the block being parsed are not validated
+	seen             map[string]token.Position // directives of the block being parsed, for duplicate validation
+	handler          func(Event) error         // set by Stream, directives are sent to it instead of being kept
+	depth            int                       // block nesting depth, for Stream
+	statementParsers map[string]func() (config.IDirective, error)
+	blockWrappers    map[string]func(*config.Directive) (config.IDirective, error)
+	// subsystemBlockWrappers are the block wrappers of the stream and mail blocks, see config.SubsystemBlockWrappers
+	subsystemBlockWrappers map[string]map[string]func(*config.Directive) (config.IDirective, error)
+	directiveWrappers      map[string]func(*config.Directive) (config.IDirective, error)
+	includeWrappers        map[string]func(*config.Directive) (config.IDirective, error)
+
+	commentBuffer []token.Token
+	closing       string // source text before the closing brace of the latest parsed block
+	diagnostics   Diagnostics
+	eofReported   bool
+	file          io.Closer
 }
 
 // WithSameOptions copy options from another parser
@@ -62,7 +100,16 @@ func WithSameOptions(p *Parser) Option {
 	}
 }
 
-func withParsedIncludes(parsedIncludes map[string]*config.Config) Option {
+// includeKey identifies a parsed include: the same file is parsed again in another context or enclosing
+// block, where its directives are validated and wrapped differently
+type includeKey struct {
+	path      string
+	context   Context
+	block     string
+	skipValid bool
+}
+
+func withParsedIncludes(parsedIncludes map[includeKey]*config.Config) Option {
 	return func(p *Parser) {
 		p.parsedIncludes = parsedIncludes
 	}
@@ -74,6 +121,34 @@ func withIncludeStack(includeStack map[string]struct{}) Option {
 	}
 }
 
+func withIncludeChain(includeChain []token.Position) Option {
+	return func(p *Parser) {
+		p.includeChain = includeChain
+	}
+}
+
+func withSeenDirectives(seen map[string]token.Position) Option {
+	return func(p *Parser) {
+		p.seen = seen
+	}
+}
+
+func withHandler(handler func(Event) error, depth int) Option {
+	return func(p *Parser) {
+		p.handler = handler
+		p.depth = depth
+	}
+}
+
+// withEnclosingBlock parses an included file as the content of the named block,
+// without validating its directives when skipValid is set (e.g. the entries of a map)
+func withEnclosingBlock(name string, skipValid bool) Option {
+	return func(p *Parser) {
+		p.block = name
+		p.skipValid = skipValid
+	}
+}
+
 func withConfigRoot(configRoot string) Option {
 	return func(p *Parser) {
 		p.configRoot = configRoot
@@ -125,6 +200,15 @@ func WithCustomDirectives(directives ...string) Option {
 	}
 }
 
+// WithCustomDirectiveContexts adds a custom directive as valid directive,
+// allowed in the given contexts only when context validation is enabled
+func WithCustomDirectiveContexts(directive string, contexts Context) Option {
+	return func(p *Parser) {
+		p.opts.customDirectives[directive] = directive
+		p.opts.customDirectiveContexts[directive] = contexts
+	}
+}
+
 // WithSkipValidBlocks add your custom block as valid
 func WithSkipValidBlocks(directives ...string) Option {
 	return func(p *Parser) {
@@ -141,6 +225,78 @@ func WithSkipValidDirectivesErr() Option {
 	}
 }
 
+// WithPreserveTrivia keeps whitespace, blank lines and line endings on the parsed directives,
+// so that a dumper style with PreserveTrivia re-emits unmodified directives byte-for-byte
+func WithPreserveTrivia() Option {
+	return func(p *Parser) {
+		p.opts.preserveTrivia = true
+	}
+}
+
+// WithErrorRecovery keeps parsing after syntax and validation errors.
+// Parse then returns the partial config along with a Diagnostics error listing every error found
+func WithErrorRecovery() Option {
+	return func(p *Parser) {
+		p.opts.errorRecovery = true
+	}
+}
+
+// WithContextValidation returns an error for known directives placed in a context
+// nginx does not allow them in, e.g. proxy_pass at the top level
+func WithContextValidation() Option {
+	return func(p *Parser) {
+		p.opts.contextValidation = true
+	}
+}
+
+// WithRootContext sets the context of the top level directives, ContextMain by default.
+// Use it to validate a file that is included from another context, e.g. ContextHTTP for a conf.d file
+func WithRootContext(context Context) Option {
+	return func(p *Parser) {
+		p.opts.rootContext = context
+	}
+}
+
+// WithArgumentValidation returns an error for known directives whose argument count,
+// on/off flag value or block does not match what nginx accepts (see DirectiveSpecs)
+func WithArgumentValidation() Option {
+	return func(p *Parser) {
+		p.opts.argumentValidation = true
+	}
+}
+
+// WithDuplicateValidation returns an error for directives that nginx accepts once per block
+// but are repeated, including the ones pulled in by include
+func WithDuplicateValidation() Option {
+	return func(p *Parser) {
+		p.opts.duplicateValidation = true
+	}
+}
+
+// WithLuaValidation returns an error for *_by_lua_block directives whose code is not valid Lua,
+// as LuaJIT reads it. The error is a *LuaError positioned in the nginx file
+func WithLuaValidation() Option {
+	return func(p *Parser) {
+		p.opts.luaValidation = true
+	}
+}
+
+// WithAnnotations reads the annotations of the outline comments of every directive, such as
+// "# @owner payments" with config.DefaultAnnotationSyntax, into its GetAnnotations map
+func WithAnnotations(syntax config.AnnotationSyntax) Option {
+	return func(p *Parser) {
+		p.opts.annotations = &syntax
+	}
+}
+
+// WithFS reads included files from fsys instead of the operating system.
+// Include paths are resolved as slash separated paths, absolute ones from the root of fsys
+func WithFS(fsys fs.FS) Option {
+	return func(p *Parser) {
+		p.opts.fsys = fsys
+	}
+}
+
 // NewStringParser parses nginx conf from string
 func NewStringParser(str string, opts ...Option) *Parser {
 	return NewParserFromLexer(lex(str), opts...)
@@ -159,13 +315,28 @@ func NewParser(filePath string, opts ...Option) (*Parser, error) {
 	return p, nil
 }
 
+// NewFSParser creates a parser reading filePath from fsys, e.g. an embed.FS or a fstest.MapFS.
+// Included files are read from fsys as well, see WithFS
+func NewFSParser(fsys fs.FS, filePath string, opts ...Option) (*Parser, error) {
+	name := fsPath(filePath)
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	l := newLexer(bufio.NewReader(f))
+	l.file = name
+	p := NewParserFromLexer(l, append(slices.Clip(opts), WithFS(fsys))...)
+	p.file = f
+	return p, nil
+}
+
 // NewParserFromLexer initilizes a new Parser
 func NewParserFromLexer(lexer *lexer, opts ...Option) *Parser {
 	configRoot, _ := filepath.Split(lexer.file)
 	parser := &Parser{
 		lexer:          lexer,
 		opts:           defaultOptions(),
-		parsedIncludes: make(map[string]*config.Config),
+		parsedIncludes: make(map[includeKey]*config.Config),
 		includeStack:   make(map[string]struct{}),
 		configRoot:     configRoot,
 	}
@@ -174,10 +345,20 @@ func NewParserFromLexer(lexer *lexer, opts ...Option) *Parser {
 		o(parser)
 	}
 
+	if parser.opts.preserveTrivia {
+		lexer.keepSource()
+	}
+	lexer.rawBlocks = parser.opts.rawBlocks
+	if parser.block != "" {
+		lexer.blocks = []string{parser.block}
+	}
+	parser.context = parser.opts.rootContext
+
 	parser.nextToken()
 	parser.nextToken()
 
 	parser.blockWrappers = config.BlockWrappers
+	parser.subsystemBlockWrappers = config.SubsystemBlockWrappers
 	parser.directiveWrappers = config.DirectiveWrappers
 	parser.includeWrappers = config.IncludeWrappers
 	return parser
@@ -199,21 +380,14 @@ func (p *Parser) followingTokenIs(t token.Type) bool {
 // Parse the gonginx.
 func (p *Parser) Parse() (_ *config.Config, err error) {
 	if p.file != nil {
-		defer func() {
-			closeErr := p.Close()
-			if closeErr == nil {
-				return
-			}
-
-			if err != nil {
-				err = errors.Join(err, closeErr)
-				return
-			}
-			err = closeErr
-		}()
+		defer p.closeFile(&err)
 	}
 
-	parsedBlock, err := p.parseBlock(false, false)
+	parsedBlock, err := p.parseBlock(false, p.skipValid)
+	p.decorateLexerErr()
+	if p.opts.errorRecovery {
+		return p.recoveredConfig(parsedBlock)
+	}
 	if err != nil {
 		if p.lexer.Err != nil {
 			return nil, errors.Join(p.lexer.Err, err)
@@ -228,9 +402,66 @@ func (p *Parser) Parse() (_ *config.Config, err error) {
 		FilePath: p.lexer.file, //TODO: set filepath here,
 		Block:    parsedBlock,
 	}
+	if p.opts.preserveTrivia {
+		c.TrailingTrivia = p.closing
+	}
+	return c, nil
+}
+
+// closeFile closes the file handler, joining the close error to err
+func (p *Parser) closeFile(err *error) {
+	closeErr := p.Close()
+	if closeErr == nil {
+		return
+	}
+
+	if *err != nil {
+		*err = errors.Join(*err, closeErr)
+		return
+	}
+	*err = closeErr
+}
+
+// recoveredConfig builds the partial config of a parser running with error recovery
+func (p *Parser) recoveredConfig(parsedBlock *config.Block) (*config.Config, error) {
+	if p.lexer.Err != nil {
+		var pos token.Position
+		var syntaxErr *SyntaxError
+		if errors.As(p.lexer.Err, &syntaxErr) {
+			pos = syntaxErr.Pos
+		}
+		p.diagnostics = append(Diagnostics{{Pos: pos, Err: p.lexer.Err}}, p.diagnostics...)
+	}
+
+	c := &config.Config{
+		FilePath: p.lexer.file,
+		Block:    parsedBlock,
+	}
+	if p.opts.preserveTrivia {
+		c.TrailingTrivia = p.closing
+	}
+	if len(p.diagnostics) > 0 {
+		return c, p.diagnostics
+	}
 	return c, nil
 }
 
+// tolerate records err as a diagnostic when error recovery is enabled,
+// it returns false when the caller should stop and return err instead
+func (p *Parser) tolerate(pos token.Position, err error) bool {
+	var handlerErr *handlerError
+	if !p.opts.errorRecovery || errors.As(err, &handlerErr) {
+		return false
+	}
+	var diagnostics Diagnostics
+	if errors.As(err, &diagnostics) {
+		p.diagnostics = append(p.diagnostics, diagnostics...)
+		return true
+	}
+	p.diagnostics = append(p.diagnostics, &Diagnostic{Pos: pos, Err: err})
+	return true
+}
+
 // ParseBlock parse a block statement
 func (p *Parser) parseBlock(inBlock bool, isSkipValidDirective bool) (*config.Block, error) {
 
@@ -240,24 +471,55 @@ func (p *Parser) parseBlock(inBlock bool, isSkipValidDirective bool) (*config.Bl
 	var s config.IDirective
 	var err error
 	var line int
+	var prevEnd int // end offset of the latest directive, used to slice trivia
+	if inBlock {
+		prevEnd = p.currentToken.End.Offset
+	}
+	// included files share the duplicate scope of the block that includes them
+	parentSeen := p.seen
+	if inBlock || p.seen == nil {
+		p.seen = make(map[string]token.Position)
+	}
+	defer func() {
+		p.seen = parentSeen
+	}()
 parsingLoop:
 	for {
 		switch {
 		case p.curTokenIs(token.EOF):
 			if inBlock {
-				return nil, errors.New("unexpected eof in block")
+				err = p.syntaxError(p.currentToken, "unexpected eof in block")
+				if !p.opts.errorRecovery {
+					return nil, err
+				}
+				// report an unclosed block once, enclosing blocks are unclosed as well
+				if !p.eofReported {
+					p.tolerate(p.currentToken.Pos, err)
+					p.eofReported = true
+				}
 			}
+			prevEnd = p.keepDanglingComments(context, prevEnd)
+			p.closing = p.lexer.sourceText(prevEnd, p.currentToken.End.Offset)
 			break parsingLoop
 		case p.curTokenIs(token.LuaCode):
 			context.IsLuaBlock = true
 			context.LiteralCode = p.currentToken.Literal
 		case p.curTokenIs(token.BlockEnd):
+			prevEnd = p.keepDanglingComments(context, prevEnd)
+			p.closing = p.lexer.sourceText(prevEnd, p.currentToken.End.Offset)
 			break parsingLoop
-		case p.curTokenIs(token.Keyword) || p.curTokenIs(token.QuotedString):
+		case p.currentToken.IsParameterEligible():
 			s, err = p.parseStatement(isSkipValidDirective)
 			if err != nil {
 				return nil, err
 			}
+			if s == nil {
+				// the statement was dropped by error recovery, resume at the token that broke it
+				if p.curTokenIs(token.BlockEnd) || p.curTokenIs(token.EOF) {
+					continue
+				}
+				break
+			}
 			if s.GetBlock() == nil {
 				// Root-level leaf directives have no parent.
 				// Nested leaf directives get parent assignment when their containing block wrapper is processed.
@@ -272,13 +534,37 @@ parsingLoop:
 			}
 			line = p.currentToken.Line
 			s.SetLine(line)
-			context.Directives = append(context.Directives, s)
+			if p.opts.annotations != nil {
+				s.SetAnnotationSyntax(*p.opts.annotations)
+				s.SetAnnotations(p.opts.annotations.Annotations(s.GetComment()))
+			}
+			if p.opts.duplicateValidation && !isSkipValidDirective {
+				if err := p.validateDuplicate(s); err != nil && !p.tolerate(s.GetSpan().Start, err) {
+					return nil, err
+				}
+			}
+			if p.opts.preserveTrivia {
+				prevEnd = p.attachTrivia(s, prevEnd)
+			}
+			if p.handler == nil {
+				context.Directives = append(context.Directives, s)
+			} else if !isStreamed(s) {
+				if err := p.emit(Event{Type: EventDirective, Directive: s, Span: s.GetSpan()}); err != nil && !errors.Is(err, SkipBlock) {
+					return nil, err
+				}
+			}
 		case p.curTokenIs(token.Comment):
 			if p.opts.skipComments {
 				break
 			}
 			// outline comment
-			p.commentBuffer = append(p.commentBuffer, p.currentToken.Literal)
+			p.commentBuffer = append(p.commentBuffer, p.currentToken)
+			if p.handler != nil {
+				err := p.emit(Event{Type: EventComment, Comment: p.currentToken.Literal, Span: tokenSpan(p.currentToken)})
+				if err != nil && !errors.Is(err, SkipBlock) {
+					return nil, err
+				}
+			}
 		}
 		p.nextToken()
 	}
@@ -286,17 +572,54 @@ parsingLoop:
 	return context, nil
 }
 
+// keepDanglingComments adds the buffered outline comments, which precede no directive, at the end
+// of the block as a *config.Comment. It returns the offset where the comments end
+func (p *Parser) keepDanglingComments(b *config.Block, prevEnd int) int {
+	if len(p.commentBuffer) == 0 {
+		return prevEnd
+	}
+	comment := &config.Comment{}
+	for _, c := range p.commentBuffer {
+		comment.Comment = append(comment.Comment, c.Literal)
+		comment.CommentSpans = append(comment.CommentSpans, tokenSpan(c))
+	}
+	comment.Span = config.Span{Start: comment.CommentSpans[0].Start, End: comment.CommentSpans[len(comment.CommentSpans)-1].End}
+	comment.SetLine(p.commentBuffer[0].Line)
+	p.commentBuffer = make([]token.Token, 0)
+
+	// Stream already sent the comments
+	if p.handler != nil {
+		return prevEnd
+	}
+	if p.opts.preserveTrivia {
+		prevEnd = p.attachTrivia(comment, prevEnd)
+	}
+	b.Directives = append(b.Directives, comment)
+	return prevEnd
+}
+
 func (p *Parser) parseStatement(isSkipValidDirective bool) (config.IDirective, error) {
 	d := &config.Directive{
 		Name: p.currentToken.Literal,
 	}
+	start := p.currentToken.Pos
 
 	if !p.opts.skipValidDirectivesErr && !isSkipValidDirective {
 		_, ok := ValidDirectives[d.Name]
 		_, ok2 := p.opts.customDirectives[d.Name]
+		_, ok3 := p.opts.rawBlocks.match(d.Name)
+
+		if !ok && !ok2 && !ok3 {
+			err := p.unknownDirectiveError(p.currentToken)
+			if !p.tolerate(start, err) {
+				return nil, err
+			}
+		}
+	}
 
-		if !ok && !ok2 {
-			return nil, fmt.Errorf("unknown directive '%s' on line %d, column %d", d.Name, p.currentToken.Line, p.currentToken.Column)
+	if p.opts.contextValidation && !isSkipValidDirective {
+		if err := p.validateContext(p.currentToken); err != nil && !p.tolerate(start, err) {
+			return nil, err
 		}
 	}
 
@@ -307,8 +630,11 @@ func (p *Parser) parseStatement(isSkipValidDirective bool) (config.IDirective, e
 
 	// set outline comment
 	if len(p.commentBuffer) > 0 {
-		d.Comment = p.commentBuffer
-		p.commentBuffer = make([]string, 0)
+		for _, comment := range p.commentBuffer {
+			d.Comment = append(d.Comment, comment.Literal)
+			d.CommentSpans = append(d.CommentSpans, tokenSpan(comment))
+		}
+		p.commentBuffer = make([]token.Token, 0)
 	}
 
 	directiveLineIndex := p.currentToken.Line // keep track of the line index of the directive
@@ -318,11 +644,16 @@ func (p *Parser) parseStatement(isSkipValidDirective bool) (config.IDirective, e
 		if p.currentToken.IsParameterEligible() {
 			d.Parameters = append(d.Parameters, config.Parameter{
 				Value:             p.currentToken.Literal,
-				RelativeLineIndex: p.currentToken.Line - directiveLineIndex}) // save the relative line index of the parameter
+				RelativeLineIndex: p.currentToken.Line - directiveLineIndex, // save the relative line index of the parameter
+				Span:              tokenSpan(p.currentToken),
+				Quote:             config.QuoteOf(p.currentToken.Literal),
+				Regex:             p.currentToken.Is(token.Regex),
+			})
 			if p.currentToken.Is(token.BlockEnd) {
 				return d, nil
 			}
 		} else if p.curTokenIs(token.Semicolon) {
+			d.Span = config.Span{Start: start, End: p.currentToken.End}
 			// inline comment in following token
 			if !p.opts.skipComments {
 				if p.followingTokenIs(token.Comment) && p.followingToken.Line == p.currentToken.Line {
@@ -331,22 +662,36 @@ func (p *Parser) parseStatement(isSkipValidDirective bool) (config.IDirective, e
 					d.SetInlineComment(config.InlineComment{
 						Value:             p.currentToken.Literal,
 						RelativeLineIndex: p.currentToken.Line - directiveLineIndex,
+						Span:              tokenSpan(p.currentToken),
 					})
 				}
 			}
+			if err := p.validateArguments(d, isSkipValidDirective); err != nil {
+				return nil, err
+			}
 			if iw, ok := p.includeWrappers[d.Name]; ok {
 				include, err := iw(d)
 				if err != nil {
-					return nil, err
+					return p.recoverDirective(d, err)
 				}
 
 				inc, ok := include.(*config.Include)
 				if !ok {
-					return nil, fmt.Errorf("invalid include wrapper result type %T", include)
+					return p.recoverDirective(d, fmt.Errorf("invalid include wrapper result type %T", include))
+				}
+				if p.handler != nil {
+					// send the include before the directives of the included files
+					err := p.emit(Event{Type: EventDirective, Directive: inc, Span: inc.GetSpan()})
+					if errors.Is(err, SkipBlock) {
+						return inc, nil
+					}
+					if err != nil {
+						return nil, err
+					}
 				}
 				return p.ParseInclude(inc)
 			} else if dw, ok := p.directiveWrappers[d.Name]; ok {
-				return dw(d)
+				return p.wrap(d, dw)
 			}
 			return d, nil
 		} else if p.curTokenIs(token.Comment) {
@@ -354,93 +699,187 @@ func (p *Parser) parseStatement(isSkipValidDirective bool) (config.IDirective, e
 			d.SetInlineComment(config.InlineComment{
 				Value:             p.currentToken.Literal,
 				RelativeLineIndex: p.currentToken.Line - directiveLineIndex,
+				Span:              tokenSpan(p.currentToken),
 			})
 		} else if p.curTokenIs(token.BlockStart) {
+			lbrace := tokenSpan(p.currentToken)
 			_, blockSkip1 := SkipValidBlocks[d.Name]
 			_, blockSkip2 := p.opts.skipValidSubDirectiveBlock[d.Name]
 			isSkipBlockSubDirective := blockSkip1 || blockSkip2 || isSkipValidDirective
 
-			// Special handling for *_by_lua_block directives
-			if strings.HasSuffix(d.Name, "_by_lua_block") {
-				// For Lua blocks, we need to capture the content without parsing it as nginx directives
+			// Special handling for raw-content blocks, such as *_by_lua_block directives
+			if language, ok := p.opts.rawBlocks.lookup(d.Name); ok {
+				// the lexer returns the body of the block verbatim, as a single LuaCode or RawCode token
 				b := &config.Block{
-					IsLuaBlock:  true,
-					Directives:  []config.IDirective{},
-					LiteralCode: "",
+					IsLuaBlock: language.Name == LanguageLua.Name,
+					Directives: []config.IDirective{},
+				}
+				if !b.IsLuaBlock {
+					b.Language = language.Name
 				}
-
-				// Skip past the opening brace
 				p.nextToken()
-
-				// Collect all content until the matching closing brace
-				// We need to count braces to handle nested blocks within Lua code
-				braceCount := 1
-				var luaCode strings.Builder
-
-				for braceCount > 0 && !p.curTokenIs(token.EOF) {
-					if p.curTokenIs(token.BlockStart) {
-						braceCount++
-					} else if p.curTokenIs(token.BlockEnd) {
-						braceCount--
-						if braceCount == 0 {
-							// This is the closing brace of the Lua block
-							break
-						}
-					}
-
-					// Append token to Lua code if it's not the closing brace
-					if !(p.curTokenIs(token.BlockEnd) && braceCount == 0) {
-						luaCode.WriteString(p.currentToken.Literal)
-						// Add space between tokens for readability
-						if p.followingToken.Type != token.BlockEnd &&
-							p.followingToken.Type != token.Semicolon &&
-							p.followingToken.Type != token.EndOfLine {
-							luaCode.WriteString(" ")
-						}
-					}
-
+				if p.curTokenIs(token.LuaCode) || p.curTokenIs(token.RawCode) {
+					b.LiteralCode = p.currentToken.Literal
 					p.nextToken()
 				}
-
-				b.LiteralCode = strings.TrimSpace(luaCode.String())
+				b.SetBraces(lbrace, tokenSpan(p.currentToken))
 				d.Block = b
+				d.Span = config.Span{Start: start, End: p.currentToken.End}
+				p.closing = p.lexer.sourceText(lbrace.End.Offset, p.currentToken.End.Offset)
+				if err := p.validateArguments(d, isSkipValidDirective); err != nil {
+					return nil, err
+				}
 
-				// Use the appropriate wrapper based on the directive name
-				if strings.HasSuffix(d.Name, "_by_lua_block") {
-					return p.blockWrappers["_by_lua_block"](d)
+				if b.IsLuaBlock {
+					if err := p.validateLua(d); err != nil {
+						return nil, err
+					}
+					return p.wrap(d, p.blockWrappers["_by_lua_block"])
 				}
-				return d, nil
+				if bw, ok := p.blockWrapper(d.Name); ok {
+					return p.wrap(d, bw)
+				}
+				return p.wrap(d, func(d *config.Directive) (config.IDirective, error) {
+					return config.NewRawBlock(d, language.Name)
+				})
 			}
 
+			if p.handler != nil {
+				return p.streamBlock(d, start, lbrace, isSkipBlockSubDirective)
+			}
+			parentContext, parentBlock, parentSkipValid := p.context, p.block, p.skipValid
+			p.context, p.block, p.skipValid = blockContext(d.Name, parentContext), d.Name, isSkipBlockSubDirective
 			b, err := p.parseBlock(true, isSkipBlockSubDirective)
+			p.context, p.block, p.skipValid = parentContext, parentBlock, parentSkipValid
 			if err != nil {
 				return nil, err
 			}
+			b.SetBraces(lbrace, tokenSpan(p.currentToken))
 			d.Block = b
+			d.Span = config.Span{Start: start, End: p.currentToken.End}
+			if err := p.validateArguments(d, isSkipValidDirective); err != nil {
+				return nil, err
+			}
 
-			if bw, ok := p.blockWrappers[d.Name]; ok {
-				return bw(d)
+			if bw, ok := p.blockWrapper(d.Name); ok {
+				return p.wrap(d, bw)
 			}
 			return d, nil
 		} else if p.currentToken.Is(token.EndOfLine) {
 			continue
 		} else {
-			return nil, fmt.Errorf("unexpected token %s (%s) on line %d, column %d", p.currentToken.Type.String(), p.currentToken.Literal, p.currentToken.Line, p.currentToken.Column)
+			err := p.syntaxError(p.currentToken, fmt.Sprintf("unexpected token %s (%s) on line %d, column %d", p.currentToken.Type.String(), p.currentToken.Literal, p.currentToken.Pos.Line, p.currentToken.Pos.Column))
+			if p.currentToken.Is(token.EOF) && p.opts.errorRecovery {
+				// the unclosed enclosing blocks are not reported again
+				if !p.eofReported {
+					p.tolerate(p.currentToken.Pos, err)
+					p.eofReported = true
+				}
+				return nil, nil
+			}
+			if !p.tolerate(p.currentToken.Pos, err) {
+				return nil, err
+			}
+			// drop the incomplete directive
+			return nil, nil
+		}
+	}
+}
+
+// blockWrapper returns the wrapper of a block directive. Inside a stream or mail block, the wrapper
+// registered for the subsystem wins, e.g. config.SubsystemBlockWrappers["stream"]["server"]
+func (p *Parser) blockWrapper(name string) (func(*config.Directive) (config.IDirective, error), bool) {
+	subsystem := ""
+	switch {
+	case p.context&ContextStreamAll != 0:
+		subsystem = "stream"
+	case p.context&ContextMailAll != 0:
+		subsystem = "mail"
+	}
+	if bw, ok := p.subsystemBlockWrappers[subsystem][name]; ok {
+		return bw, true
+	}
+	bw, ok := p.blockWrappers[name]
+	return bw, ok
+}
+
+// wrap turns a directive into its wrapper type, falling back to the plain
+// directive when the wrapper fails and error recovery is enabled
+func (p *Parser) wrap(d *config.Directive, wrapper func(*config.Directive) (config.IDirective, error)) (config.IDirective, error) {
+	wrapped, err := wrapper(d)
+	if err != nil {
+		return p.recoverDirective(d, err)
+	}
+	return wrapped, nil
+}
+
+// recoverDirective reports a directive that failed to wrap as a *DirectiveError,
+// it keeps the plain directive when error recovery is enabled
+func (p *Parser) recoverDirective(d *config.Directive, err error) (config.IDirective, error) {
+	err = &DirectiveError{
+		Pos:          d.Span.Start,
+		Name:         d.Name,
+		IncludeChain: p.includeChain,
+		Snippet:      p.snippet(d.Span.Start),
+		Err:          err,
+	}
+	if !p.tolerate(d.Span.Start, err) {
+		return nil, err
+	}
+	return d, nil
+}
+
+// attachTrivia records the source text of a parsed directive, which starts
+// after offset from, and returns the offset where the directive ends
+func (p *Parser) attachTrivia(s config.IDirective, from int) int {
+	span := s.GetSpan()
+	if !span.IsValid() {
+		return from
+	}
+
+	// comments before the end of the previous directive were already kept with it
+	start := span.Start.Offset
+	for _, comment := range s.GetCommentSpans() {
+		if comment.Start.Offset >= from {
+			start = comment.Start.Offset
+			break
 		}
 	}
+
+	headEnd, end := span.End.Offset, span.End.Offset
+	closing := ""
+	if b := s.GetBlock(); b != nil && b.GetCodeBlock() == "" {
+		lbrace, _ := b.GetBraces()
+		headEnd = lbrace.End.Offset
+		closing = p.closing
+	} else {
+		for _, comment := range s.GetInlineComment() {
+			if comment.Span.End.Offset > headEnd {
+				headEnd = comment.Span.End.Offset
+			}
+		}
+		end = headEnd
+	}
+
+	s.SetTrivia(config.NewTrivia(s,
+		p.lexer.sourceText(from, start),
+		p.lexer.sourceText(start, headEnd),
+		closing,
+	))
+	return end
 }
 
 // ParseInclude just parse include confs
 func (p *Parser) ParseInclude(include *config.Include) (config.IDirective, error) {
 	if p.opts.parseInclude {
-		includePath := include.IncludePath
-		if !filepath.IsAbs(includePath) {
-			includePath = filepath.Join(p.configRoot, include.IncludePath)
-		}
+		includePath := p.includePattern(include.IncludePath)
 		hasWildcard := hasGlobMeta(includePath)
-		includePaths, err := filepath.Glob(includePath)
+		includePaths, err := p.glob(includePath)
 		if err != nil && !p.opts.skipIncludeParsingErr {
-			return nil, err
+			err = p.includeError(include, include.IncludePath, err)
+			if !p.tolerate(include.Span.Start, err) {
+				return nil, err
+			}
 		}
 
 		for _, matchedPath := range includePaths {
@@ -450,9 +889,10 @@ func (p *Parser) ParseInclude(include *config.Include) (config.IDirective, error
 				continue
 			}
 
-			canonicalPath, err := filepath.Abs(filepath.Clean(matchedPath))
+			canonicalPath, err := p.canonicalPath(matchedPath)
 			if err != nil {
-				if p.opts.skipIncludeParsingErr {
+				err = p.includeError(include, matchedPath, err)
+				if p.opts.skipIncludeParsingErr || p.tolerate(include.Span.Start, err) {
 					continue
 				}
 				return nil, err
@@ -460,14 +900,21 @@ func (p *Parser) ParseInclude(include *config.Include) (config.IDirective, error
 
 			if _, inStack := p.includeStack[canonicalPath]; inStack {
 				if p.opts.includeCycleErr && !p.opts.skipIncludeParsingErr {
-					return nil, fmt.Errorf("include cycle detected for %s", canonicalPath)
+					err := p.includeError(include, canonicalPath, ErrIncludeCycle)
+					if !p.tolerate(include.Span.Start, err) {
+						return nil, err
+					}
 				}
 				// cyclic include graph, skip this branch and continue.
 				continue
 			}
 
-			if cached, ok := p.parsedIncludes[canonicalPath]; ok {
+			key := includeKey{path: canonicalPath, context: p.context, block: p.block, skipValid: p.skipValid}
+			if cached, ok := p.parsedIncludes[key]; ok {
 				if cached != nil {
+					if err := p.validateIncludedDuplicates(include, cached); err != nil {
+						return nil, err
+					}
 					include.Configs = append(include.Configs, cached)
 				}
 				continue
@@ -475,37 +922,275 @@ func (p *Parser) ParseInclude(include *config.Include) (config.IDirective, error
 
 			p.includeStack[canonicalPath] = struct{}{}
 
-			parser, err := NewParser(canonicalPath,
+			parser, err := p.openInclude(canonicalPath,
 				WithSameOptions(p),
+				WithRootContext(p.context),
 				withParsedIncludes(p.parsedIncludes),
 				withIncludeStack(p.includeStack),
 				withConfigRoot(p.configRoot),
+				withEnclosingBlock(p.block, p.skipValid),
+				withIncludeChain(append(slices.Clip(p.includeChain), include.Span.Start)),
+				withSeenDirectives(p.seen),
+				withHandler(p.handler, p.depth),
 			)
 			if err != nil {
 				delete(p.includeStack, canonicalPath)
-				if p.opts.skipIncludeParsingErr {
+				err = p.includeError(include, canonicalPath, err)
+				if p.opts.skipIncludeParsingErr || p.tolerate(include.Span.Start, err) {
 					continue
 				}
 				return nil, err
 			}
 
+			if p.handler != nil {
+				// streamed files are sent again each time they are included, nothing is cached
+				err := parser.streamFile()
+				delete(p.includeStack, canonicalPath)
+				if err != nil {
+					var handlerErr *handlerError
+					if errors.As(err, &handlerErr) || !(p.opts.skipIncludeParsingErr || p.tolerate(include.Span.Start, err)) {
+						return nil, err
+					}
+				}
+				continue
+			}
+
 			config, err := parser.Parse()
 			delete(p.includeStack, canonicalPath)
 			if err != nil {
 				if p.opts.skipIncludeParsingErr {
 					continue
 				}
-				return nil, err
+				if !p.tolerate(include.Span.Start, err) {
+					return nil, err
+				}
+				if config == nil {
+					continue
+				}
+				// keep the partial config of the included file
 			}
 
 			//TODO: link parent config or include direcitve?
-			p.parsedIncludes[canonicalPath] = config
+			p.parsedIncludes[key] = config
 			include.Configs = append(include.Configs, config)
 		}
 	}
 	return include, nil
 }
 
+// validateContext checks that the directive named by tok is allowed in the current context
+func (p *Parser) validateContext(tok token.Token) error {
+	if p.context == 0 {
+		return nil
+	}
+	allowed, ok := p.opts.customDirectiveContexts[tok.Literal]
+	if !ok {
+		allowed, ok = DirectiveContexts[tok.Literal]
+	}
+	if !ok || allowed == 0 || allowed&p.context != 0 {
+		return nil
+	}
+	return &ContextError{
+		Pos:          tok.Pos,
+		Token:        tok,
+		Name:         tok.Literal,
+		Context:      p.context,
+		Allowed:      allowed,
+		IncludeChain: p.includeChain,
+		Snippet:      p.snippet(tok.Pos),
+	}
+}
+
+// validateArguments checks the directive against its spec when argument validation is enabled,
+// it returns an error only when the caller should stop
+func (p *Parser) validateArguments(d *config.Directive, isSkipValidDirective bool) error {
+	if !p.opts.argumentValidation || isSkipValidDirective {
+		return nil
+	}
+	spec, ok := LookupDirectiveSpec(d.Name, p.context)
+	if !ok {
+		return nil
+	}
+	message := spec.check(d)
+	if message == "" {
+		return nil
+	}
+	err := &ArgumentError{
+		Pos:          d.Span.Start,
+		Name:         d.Name,
+		Message:      message,
+		IncludeChain: p.includeChain,
+		Snippet:      p.snippet(d.Span.Start),
+	}
+	if p.tolerate(d.Span.Start, err) {
+		return nil
+	}
+	return err
+}
+
+// validateDuplicate records the directive in the current block and reports it
+// when it was already there and nginx does not accept it more than once
+func (p *Parser) validateDuplicate(s config.IDirective) error {
+	spec, ok := LookupDirectiveSpec(s.GetName(), p.context)
+	if !ok || spec.Multiple {
+		return nil
+	}
+	pos := s.GetSpan().Start
+	previous, ok := p.seen[s.GetName()]
+	if !ok {
+		p.seen[s.GetName()] = pos
+		return nil
+	}
+	return &DuplicateError{
+		Pos:          pos,
+		Name:         s.GetName(),
+		Previous:     previous,
+		IncludeChain: p.includeChain,
+		Snippet:      p.snippet(pos),
+	}
+}
+
+// validateIncludedDuplicates checks the directives of a config included again, which was parsed and
+// cached for an earlier include, against the directives of the current block
+func (p *Parser) validateIncludedDuplicates(include *config.Include, cached *config.Config) error {
+	if !p.opts.duplicateValidation || p.skipValid {
+		return nil
+	}
+	for _, d := range cached.GetDirectives() {
+		if d.GetName() == "" {
+			continue
+		}
+		err := p.validateDuplicate(d)
+		var duplicateErr *DuplicateError
+		if !errors.As(err, &duplicateErr) {
+			continue
+		}
+		duplicateErr.IncludeChain = append(slices.Clip(p.includeChain), include.Span.Start)
+		duplicateErr.Snippet = p.fileSnippet(duplicateErr.Pos)
+		if !p.tolerate(duplicateErr.Pos, err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// syntaxError creates a *SyntaxError for the given token
+func (p *Parser) syntaxError(tok token.Token, message string) *SyntaxError {
+	return &SyntaxError{
+		Pos:          tok.Pos,
+		Token:        tok,
+		Message:      message,
+		IncludeChain: p.includeChain,
+		Snippet:      p.snippet(tok.Pos),
+	}
+}
+
+// unknownDirectiveError creates an *UnknownDirectiveError for the given directive name token
+func (p *Parser) unknownDirectiveError(tok token.Token) *UnknownDirectiveError {
+	return &UnknownDirectiveError{
+		Pos:          tok.Pos,
+		Token:        tok,
+		Name:         tok.Literal,
+		Suggestions:  suggestDirectives(tok.Literal, p.opts.customDirectives),
+		IncludeChain: p.includeChain,
+		Snippet:      p.snippet(tok.Pos),
+	}
+}
+
+// includeError creates an *IncludeError for the given include directive
+func (p *Parser) includeError(include *config.Include, path string, err error) *IncludeError {
+	return &IncludeError{
+		Pos:          include.Span.Start,
+		Path:         path,
+		IncludeChain: p.includeChain,
+		Snippet:      p.snippet(include.Span.Start),
+		Err:          err,
+	}
+}
+
+// decorateLexerErr adds the include chain and the snippet to the lexer error
+func (p *Parser) decorateLexerErr() {
+	var syntaxErr *SyntaxError
+	if errors.As(p.lexer.Err, &syntaxErr) && syntaxErr.Snippet == "" {
+		syntaxErr.IncludeChain = p.includeChain
+		syntaxErr.Snippet = p.snippet(syntaxErr.Pos)
+	}
+}
+
+// snippet renders the source line of pos, read back from the file when the lexer did not keep the source
+func (p *Parser) snippet(pos token.Position) string {
+	if p.lexer.source != nil {
+		return snippet(p.lexer.source.Bytes(), pos)
+	}
+	return p.fileSnippet(pos)
+}
+
+// fileSnippet returns the snippet of pos read from its file, e.g. for a position in an included file
+func (p *Parser) fileSnippet(pos token.Position) string {
+	if pos.Filename == "" {
+		return ""
+	}
+	var source []byte
+	var err error
+	if p.opts.fsys != nil {
+		source, err = fs.ReadFile(p.opts.fsys, pos.Filename)
+	} else {
+		source, err = os.ReadFile(pos.Filename)
+	}
+	if err != nil {
+		return ""
+	}
+	return snippet(source, pos)
+}
+
+// includePattern resolves an include path against the config root
+func (p *Parser) includePattern(includePath string) string {
+	if p.opts.fsys != nil {
+		if !path.IsAbs(includePath) {
+			includePath = path.Join(p.configRoot, includePath)
+		}
+		return fsPath(includePath)
+	}
+	if !filepath.IsAbs(includePath) {
+		includePath = filepath.Join(p.configRoot, includePath)
+	}
+	return includePath
+}
+
+func (p *Parser) glob(pattern string) ([]string, error) {
+	if p.opts.fsys != nil {
+		return fs.Glob(p.opts.fsys, pattern)
+	}
+	return filepath.Glob(pattern)
+}
+
+func (p *Parser) canonicalPath(name string) (string, error) {
+	if p.opts.fsys != nil {
+		return path.Clean(name), nil
+	}
+	return filepath.Abs(filepath.Clean(name))
+}
+
+func (p *Parser) openInclude(name string, opts ...Option) (*Parser, error) {
+	if p.opts.fsys != nil {
+		return NewFSParser(p.opts.fsys, name, opts...)
+	}
+	return NewParser(name, opts...)
+}
+
+// fsPath turns a path into a valid io/fs path, absolute paths are taken from the root of the FS
+func fsPath(name string) string {
+	name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func tokenSpan(t token.Token) config.Span {
+	return config.Span{Start: t.Pos, End: t.End}
+}
+
 func hasGlobMeta(path string) bool {
 	return strings.ContainsAny(path, "*?[")
 }
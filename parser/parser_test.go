@@ -163,14 +163,86 @@ func TestParser_VariableAsParameter(t *testing.T) {
 
 	assert.NilError(t, err, "no error expected here")
 
-	d, ok := c.Directives[0].(*config.Directive)
-	assert.Assert(t, ok, "expecting a directive(http) as first statement")
+	d, ok := c.Directives[0].(*config.Map)
+	assert.Assert(t, ok, "expecting a map as first statement")
 	assert.Equal(t, d.Name, "map", "first directive needs to be ")
 	assert.Equal(t, len(d.Parameters), 2, "map must have 2 parameters here")
 	assert.Equal(t, d.Parameters[0].GetValue(), "$host", "invalid first parameter")
 	assert.Equal(t, d.Parameters[1].GetValue(), "$clientname", "invalid second parameter")
 }
 
+func TestParser_ParameterKinds(t *testing.T) {
+	t.Parallel()
+	c, err := NewStringParser(`http {
+    map $uri $target {
+        ~^/old/(?<rest>.*) /new/$rest;
+        "~*^/Q" '/q';
+    }
+    server {
+        server_name example.com ~^(www\.)?(?<domain>.+)$;
+        location ~* "\.(gif|jpg)$" {
+            if ($http_user_agent !~ MSIE) {
+                rewrite ^/(.*)$ /msie/$1 break;
+            }
+            return 200 ` + "`${host}:$server_port`" + `;
+        }
+    }
+}`).Parse()
+	assert.NilError(t, err)
+
+	params := func(name string) []config.Parameter {
+		return c.FindDirectives(name)[0].GetParameters()
+	}
+
+	location := params("location")
+	assert.Assert(t, !location[0].Regex)
+	assert.Assert(t, location[1].Regex)
+	assert.Equal(t, location[1].Quote, config.QuoteDouble)
+
+	ifParams := params("if")
+	assert.DeepEqual(t, ifParams[0].Variables(), []string{"http_user_agent"})
+	assert.Assert(t, !ifParams[1].Regex)
+	assert.Assert(t, ifParams[2].Regex)
+
+	rewrite := params("rewrite")
+	assert.Assert(t, rewrite[0].Regex)
+	assert.Equal(t, len(rewrite[0].Variables()), 0)
+	assert.Assert(t, !rewrite[1].Regex)
+	assert.DeepEqual(t, rewrite[1].Variables(), []string{"1"})
+
+	serverName := params("server_name")
+	assert.Assert(t, !serverName[0].Regex)
+	assert.Assert(t, serverName[1].Regex)
+
+	ret := params("return")
+	assert.Equal(t, ret[1].Quote, config.QuoteBacktick)
+	assert.DeepEqual(t, ret[1].Variables(), []string{"host", "server_port"})
+
+	mapParams := params("map")
+	assert.Assert(t, mapParams[0].IsVariable())
+	assert.Equal(t, mapParams[0].Quote, config.QuoteNone)
+	values := c.FindDirectives("map")[0].GetBlock().GetDirectives()
+	assert.Equal(t, values[1].GetParameters()[0].Quote, config.QuoteSingle)
+}
+
+func TestParser_NewParameterRoundTrip(t *testing.T) {
+	t.Parallel()
+	values := []string{"example.com", "a; evil on", "}", "#x", `say "hi"`, `C:\`, "it's", "a\tb\nc", "", "$host"}
+	d := &config.Directive{Name: "add_header"}
+	for _, v := range values {
+		d.Parameters = append(d.Parameters, config.NewParameter(v))
+	}
+
+	c, err := NewStringParser(dumper.DumpDirective(d, dumper.NoIndentStyle)).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, len(c.Directives), 1)
+	params := c.Directives[0].GetParameters()
+	assert.Equal(t, len(params), len(values))
+	for i, v := range values {
+		assert.Equal(t, params[i].Unquoted(), v)
+	}
+}
+
 func TestParser_UnendedMultiParams(t *testing.T) {
 	t.Parallel()
 	_, err := NewParserFromLexer(
@@ -1043,7 +1115,8 @@ func TestParser_LuaError(t *testing.T) {
 	t.Parallel()
 	p := NewStringParser(`location / {
         content_by_lua_block { -- comment
-local foo = if -- comment }
+local foo = if -- comment
+}
     }`)
 	c, err := p.Parse()
 	assert.NilError(t, err, "no error expected here")
@@ -1057,6 +1130,61 @@ local foo = if -- comment
 }`, s)
 }
 
+func TestParser_LuaBlockVerbatim(t *testing.T) {
+	t.Parallel()
+	conf := `server {
+    location / {
+        set_by_lua_block $x {
+            local s = "}" -- }
+            local l = [==[ { ]==]
+            --[[ } ]]
+            return #s
+        }
+        content_by_lua_block {
+            ngx.say(ngx.var.x)
+        }
+    }
+}`
+	c, err := NewStringParser(conf, WithPreserveTrivia()).Parse()
+	assert.NilError(t, err)
+
+	lua := c.FindDirectives("set_by_lua_block")
+	assert.Equal(t, len(lua), 1)
+	assert.Equal(t, lua[0].GetBlock().GetCodeBlock(), `
+            local s = "}" -- }
+            local l = [==[ { ]==]
+            --[[ } ]]
+            return #s
+        `)
+	assert.Equal(t, lua[0].GetParameters()[0].Value, "$x")
+
+	assert.Equal(t, dumper.DumpConfig(c, dumper.LosslessStyle), conf)
+	assert.Equal(t, dumper.DumpConfig(c, dumper.NewStyle().WithLuaFormatting(false)), conf)
+}
+
+func TestParser_LuaLongBracketsRoundTrip(t *testing.T) {
+	t.Parallel()
+	tests := []string{
+		`location / {
+    content_by_lua_block {
+        local s = [==[ { ]] } ]==]
+        ngx.say(s)
+    }
+}`,
+		`location / {
+    content_by_lua_block {
+        --[[ } ]]
+        ngx.say("ok")
+    }
+}`,
+	}
+	for _, conf := range tests {
+		c, err := NewStringParser(conf).Parse()
+		assert.NilError(t, err)
+		assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), conf)
+	}
+}
+
 func collectDirectives(block config.IBlock) []config.IDirective {
 	out := make([]config.IDirective, 0)
 	for _, d := range block.GetDirectives() {
@@ -1072,3 +1200,434 @@ func collectDirectives(block config.IBlock) []config.IDirective {
 	}
 	return out
 }
+
+func TestParser_Positions(t *testing.T) {
+	t.Parallel()
+	conf, err := NewStringParser(`# the server
+server {
+    listen 80; # port
+}
+`).Parse()
+	assert.NilError(t, err)
+
+	server := conf.Directives[0]
+	assert.Equal(t, server.GetSpan().Start, token.Position{Offset: 13, Line: 2, Column: 1})
+	assert.Equal(t, server.GetSpan().End, token.Position{Offset: 45, Line: 4, Column: 2})
+	assert.DeepEqual(t, server.GetCommentSpans(), []config.Span{{
+		Start: token.Position{Offset: 0, Line: 1, Column: 1},
+		End:   token.Position{Offset: 12, Line: 1, Column: 13},
+	}})
+
+	lbrace, rbrace := server.GetBlock().GetBraces()
+	assert.Equal(t, lbrace.Start, token.Position{Offset: 20, Line: 2, Column: 8})
+	assert.Equal(t, rbrace.Start, token.Position{Offset: 44, Line: 4, Column: 1})
+
+	listen := server.GetBlock().GetDirectives()[0]
+	assert.Equal(t, listen.GetSpan().Start, token.Position{Offset: 26, Line: 3, Column: 5})
+	assert.Equal(t, listen.GetSpan().End, token.Position{Offset: 36, Line: 3, Column: 15})
+	param := listen.GetParameters()[0]
+	assert.Equal(t, param.GetSpan().Start, token.Position{Offset: 33, Line: 3, Column: 12})
+	assert.Equal(t, param.GetSpan().End, token.Position{Offset: 35, Line: 3, Column: 14})
+	assert.Equal(t, listen.GetInlineComment()[0].Span.Start, token.Position{Offset: 37, Line: 3, Column: 16})
+}
+
+func TestParser_PositionsInIncludedFiles(t *testing.T) {
+	t.Parallel()
+	p, err := NewParser("../testdata/include-glob/nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	conf, err := p.Parse()
+	assert.NilError(t, err)
+
+	user := conf.FindDirectives("user")[0]
+	assert.Equal(t, user.GetSpan().Start.String(), "../testdata/include-glob/nginx.conf:1:1")
+
+	events := conf.FindDirectives("events")[0]
+	assert.Equal(t, events.GetSpan().Start.Filename, filepath.Join(mustAbs(t, "../testdata/include-glob"), "events.conf"))
+	assert.Equal(t, events.GetSpan().Start.Line, 1)
+}
+
+func TestParser_DanglingComments(t *testing.T) {
+	t.Parallel()
+	conf := `# main
+http {
+    server {
+        listen 80;
+        # disabled:
+        # root /a;
+    }
+    # before server
+    server {
+        listen 81;
+    }
+    upstream backend {
+        server 127.0.0.1:80;
+        # end of upstream
+    }
+}
+# end of file
+`
+	c, err := NewStringParser(conf).Parse()
+	assert.NilError(t, err)
+
+	// comments before } stay in their block instead of moving to the next directive
+	servers := c.FindDirectives("server")
+	comment, ok := servers[0].GetBlock().GetDirectives()[1].(*config.Comment)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, comment.Comment, []string{"# disabled:", "# root /a;"})
+	assert.Equal(t, comment.GetSpan().Start.String(), "5:9")
+	assert.Equal(t, comment.GetSpan().End.String(), "6:19")
+	assert.DeepEqual(t, servers[1].GetComment(), []string{"# before server"})
+
+	upstream := c.FindUpstreams()[0]
+	directives := upstream.GetDirectives()
+	assert.DeepEqual(t, directives[len(directives)-1].GetComment(), []string{"# end of upstream"})
+
+	// the comment at the end of the file is kept
+	last := c.Directives[len(c.Directives)-1]
+	assert.DeepEqual(t, last.GetComment(), []string{"# end of file"})
+	assert.Equal(t, last.GetName(), "")
+
+	// http lists its servers after its other directives
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), `# main
+http {
+    upstream backend {
+        server 127.0.0.1:80;
+        # end of upstream
+    }
+    server {
+        listen 80;
+        # disabled:
+        # root /a;
+    }
+    # before server
+    server {
+        listen 81;
+    }
+}
+# end of file`)
+	c, err = NewStringParser(conf, WithPreserveTrivia()).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, dumper.DumpConfig(c, dumper.LosslessStyle), conf)
+
+	// comments are still skipped with WithSkipComments
+	c, err = NewStringParser(conf, WithSkipComments()).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, c.Directives[len(c.Directives)-1].GetName(), "http")
+}
+
+func TestParser_Annotations(t *testing.T) {
+	t.Parallel()
+	conf := `http {
+    # payments API
+    # @owner payments
+    # @ticket OPS-123
+    server {
+        listen 80;
+    }
+    upstream backend {
+        # @managed-by terraform
+        server 127.0.0.1:80;
+    }
+}
+`
+	c, err := NewStringParser(conf, WithAnnotations(config.DefaultAnnotationSyntax)).Parse()
+	assert.NilError(t, err)
+
+	server := c.FindDirectives("server")[0]
+	assert.DeepEqual(t, server.GetAnnotations(), map[string]string{"owner": "payments", "ticket": "OPS-123"})
+	upstreamServer := c.FindUpstreams()[0].UpstreamServers[0]
+	assert.DeepEqual(t, upstreamServer.GetAnnotations(), map[string]string{"managed-by": "terraform"})
+	// directives without annotations get an empty map to fill
+	listen := c.FindDirectives("listen")[0]
+	assert.DeepEqual(t, listen.GetAnnotations(), map[string]string{})
+
+	// without the option, annotations stay plain comments
+	plain, err := NewStringParser(conf).Parse()
+	assert.NilError(t, err)
+	assert.Assert(t, plain.FindDirectives("server")[0].GetAnnotations() == nil)
+
+	server.GetAnnotations()["owner"] = "billing"
+	delete(server.GetAnnotations(), "ticket")
+	listen.GetAnnotations()["deprecated"] = ""
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), `http {
+    upstream backend {
+        # @managed-by terraform
+        server 127.0.0.1:80;
+    }
+    # payments API
+    # @owner billing
+    server {
+        # @deprecated
+        listen 80;
+    }
+}`)
+
+	// lossless dumps rewrite only the annotated directives that changed
+	c, err = NewStringParser(conf, WithAnnotations(config.DefaultAnnotationSyntax), WithPreserveTrivia()).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, dumper.DumpConfig(c, dumper.LosslessStyle), conf)
+	c.FindUpstreams()[0].UpstreamServers[0].GetAnnotations()["managed-by"] = "hand"
+	assert.Equal(t, dumper.DumpConfig(c, dumper.LosslessStyle), strings.Replace(conf, "terraform", "hand", 1))
+}
+
+func TestParser_If(t *testing.T) {
+	t.Parallel()
+	conf := `server {
+    if ($http_x = "a b") {
+        return 403;
+    }
+    if (!-f $request_filename) {
+        rewrite ^ /index.php last;
+    }
+    if ($a = b)#comment
+    {
+        return 404;
+    }
+}
+`
+	c, err := NewStringParser(conf).Parse()
+	assert.NilError(t, err)
+	ifs := c.FindDirectives("if")
+	assert.Equal(t, len(ifs), 3)
+
+	header, ok := ifs[0].(*config.If)
+	assert.Assert(t, ok)
+	assert.Equal(t, header.Condition.Variable, "$http_x")
+	assert.Equal(t, header.Condition.Operator, config.OperatorEqual)
+	assert.Equal(t, header.Condition.Operand.Unquoted(), "a b")
+	assert.Equal(t, header.GetDirectives()[0].GetParent(), ifs[0])
+	matched, err := header.Eval(map[string]string{"http_x": "a b"}, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, matched)
+
+	file := ifs[1].(*config.If)
+	assert.Equal(t, file.Condition.Operator, config.OperatorNotFile)
+	file.SetCondition(config.Condition{Operator: config.OperatorNotExists, Operand: config.NewParameter("$request_filename")})
+
+	// nginx reads ")#comment" as one word, the condition is invalid and the directive stays plain
+	_, ok = ifs[2].(*config.Directive)
+	assert.Assert(t, ok)
+
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), `server {
+    if ($http_x = "a b" ) {
+        return 403;
+    }
+    if (!-e $request_filename) {
+        rewrite ^ /index.php last;
+    }
+    if ($a = b)#comment {
+        return 404;
+    }
+}`)
+}
+
+func TestParser_Geo(t *testing.T) {
+	t.Parallel()
+	c, err := NewStringParser(`http {
+    geo $remote_addr $blocked {
+        default 0;
+        proxy 10.0.0.0/8;
+        # office
+        192.168.0.0/16 1;
+        "2001:db8::/32" 1;
+    }
+}`, WithContextValidation(), WithArgumentValidation()).Parse()
+	assert.NilError(t, err)
+
+	geo, ok := c.FindDirectives("geo")[0].(*config.Geo)
+	assert.Assert(t, ok)
+	assert.Equal(t, geo.Address, "$remote_addr")
+	assert.Equal(t, len(geo.Entries), 2)
+	value, matched := geo.Lookup("192.168.10.1", "")
+	assert.Equal(t, value, "1")
+	assert.Assert(t, matched)
+	value, _ = geo.Lookup("2001:db8::5", "")
+	assert.Equal(t, value, "1")
+	value, _ = geo.Lookup("8.8.8.8", "")
+	assert.Equal(t, value, "0")
+}
+
+func TestParser_SplitClients(t *testing.T) {
+	t.Parallel()
+	c, err := NewStringParser(`http {
+    split_clients "${remote_addr}AAA" $variant {
+        0.5% v2;
+        * v1;
+    }
+}`, WithContextValidation(), WithArgumentValidation()).Parse()
+	assert.NilError(t, err)
+
+	split, ok := c.FindDirectives("split_clients")[0].(*config.SplitClients)
+	assert.Assert(t, ok)
+	assert.Equal(t, split.Key, "${remote_addr}AAA")
+	assert.Equal(t, split.Pick("10.0.3.113AAA"), "v2")
+	assert.Equal(t, split.Pick("10.0.0.1AAA"), "v1")
+
+	assert.NilError(t, split.Shift("v1", "v2", 9.5))
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), `http {
+    split_clients "${remote_addr}AAA" $variant {
+        10% v2;
+        * v1;
+    }
+}`)
+}
+
+func TestParser_StreamBlock(t *testing.T) {
+	t.Parallel()
+	conf := `stream {
+    map $ssl_preread_server_name $backend {
+        default tls;
+        db.example.com db;
+    }
+    geo $remote_addr $internal {
+        default 0;
+        10.0.0.0/8 1;
+    }
+    upstream dns {
+        server 10.0.0.1:53 weight=2;
+        server 10.0.0.2:53 backup;
+    }
+    server {
+        listen 53 udp reuseport;
+        proxy_pass dns;
+        proxy_responses 1;
+    }
+    server {
+        listen 443;
+        ssl_preread on;
+        proxy_pass $backend;
+    }
+}
+http {
+    server {
+        listen 80;
+    }
+}`
+	c, err := NewStringParser(conf, WithContextValidation(), WithArgumentValidation()).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), conf)
+
+	stream, ok := c.FindDirectives("stream")[0].(*config.Stream)
+	assert.Assert(t, ok)
+	_, ok = stream.FindDirectives("map")[0].(*config.Map)
+	assert.Assert(t, ok)
+	_, ok = stream.FindDirectives("geo")[0].(*config.Geo)
+	assert.Assert(t, ok)
+
+	servers := stream.Servers()
+	assert.Equal(t, len(servers), 2)
+	listens, err := servers[0].Listens()
+	assert.NilError(t, err)
+	assert.Assert(t, listens[0].UDP && listens[0].ReusePort)
+	assert.Equal(t, servers[0].Upstream().UpstreamName, "dns")
+	assert.Equal(t, len(servers[0].Upstream().UpstreamServers), 2)
+	assert.Equal(t, servers[0].Upstream().UpstreamServers[0].GetParent(), config.IDirective(servers[0].Upstream()))
+	assert.Equal(t, servers[0].Upstream().GetParent(), config.IDirective(stream))
+	assert.Assert(t, servers[1].SSLPreread())
+	assert.Equal(t, servers[1].ProxyPass(), "$backend")
+
+	// the servers of http blocks keep their type
+	_, ok = c.FindDirectives("http")[0].(*config.HTTP).Servers[0].GetParent().(*config.HTTP)
+	assert.Assert(t, ok)
+	assert.Equal(t, len(c.FindUpstreams()), 1)
+	_, ok = config.BlockWrappers["stream_server"]
+	assert.Assert(t, !ok)
+
+	_, err = NewStringParser("http {\n    server {\n        ssl_preread on;\n    }\n}", WithContextValidation()).Parse()
+	assert.ErrorContains(t, err, "directive 'ssl_preread' is not allowed in server context")
+}
+
+func TestParser_MailBlock(t *testing.T) {
+	t.Parallel()
+	conf := `mail {
+    auth_http localhost:9000/auth;
+    proxy_pass_error_message on;
+    server {
+        listen 143;
+        protocol imap;
+    }
+    server {
+        listen 25;
+        protocol smtp;
+        starttls only;
+        smtp_auth login plain;
+    }
+}`
+	c, err := NewStringParser(conf, WithContextValidation(), WithArgumentValidation()).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), conf)
+
+	mail, ok := c.FindDirectives("mail")[0].(*config.Mail)
+	assert.Assert(t, ok)
+	servers := mail.Servers()
+	assert.Equal(t, len(servers), 2)
+	assert.Equal(t, servers[0].Protocol(), config.MailProtocolIMAP)
+	assert.Equal(t, servers[0].AuthHTTP(), "localhost:9000/auth")
+	assert.Assert(t, servers[0].ProxyPassErrorMessage())
+	assert.Equal(t, servers[1].StartTLS(), config.StartTLSOnly)
+	listens, err := servers[1].Listens()
+	assert.NilError(t, err)
+	assert.Equal(t, listens[0].Port(), "25")
+
+	_, err = NewStringParser("http {\n    starttls on;\n}", WithContextValidation()).Parse()
+	assert.ErrorContains(t, err, "directive 'starttls' is not allowed in http context")
+}
+
+func TestParser_MainContext(t *testing.T) {
+	t.Parallel()
+	conf := `load_module modules/ngx_stream_module.so;
+user www-data;
+worker_processes 4;
+# custom
+lock_file /run/nginx.lock;
+error_log /var/log/nginx/error.log warn;
+events {
+    worker_connections 1024;
+    accept_mutex on;
+}
+http {
+    server_tokens off;
+}`
+	c, err := NewStringParser(conf, WithContextValidation(), WithArgumentValidation()).Parse()
+	assert.NilError(t, err)
+
+	main := config.NewMain(c)
+	workers, err := main.WorkerProcesses()
+	assert.NilError(t, err)
+	assert.Equal(t, workers, config.WorkerCount(4))
+	events := main.Events()
+	assert.Assert(t, events != nil)
+	connections, err := events.WorkerConnections()
+	assert.NilError(t, err)
+	assert.Equal(t, connections, 1024)
+
+	assert.NilError(t, main.SetWorkerProcesses(config.WorkerCountAuto))
+	main.AddLoadModule("modules/ngx_mail_module.so")
+	main.SetPID("/run/nginx.pid")
+	events.SetMultiAccept(true)
+	assert.NilError(t, events.SetUse("epoll"))
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), `load_module modules/ngx_stream_module.so;
+load_module modules/ngx_mail_module.so;
+user www-data;
+worker_processes auto;
+# custom
+lock_file /run/nginx.lock;
+error_log /var/log/nginx/error.log warn;
+pid /run/nginx.pid;
+events {
+    worker_connections 1024;
+    accept_mutex on;
+    multi_accept on;
+    use epoll;
+}
+http {
+    server_tokens off;
+}`)
+}
+
+func mustAbs(t *testing.T, path string) string {
+	t.Helper()
+	abs, err := filepath.Abs(path)
+	assert.NilError(t, err)
+	return abs
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/tufanbarisyildirim/gonginx/parser/token"
+)
+
+// RawLanguage describes the language of the body of raw-content blocks: the lexer reads the body
+// verbatim up to the closing brace, not counting the braces in the strings and comments of the language
+type RawLanguage struct {
+	Name          string      // the language name, kept in config.RawBlock.Language, e.g. "js"
+	Quotes        string      // runes opening a string closed by the same rune, a backslash escapes the next rune
+	LineComments  []string    // markers of the comments running to the end of the line, e.g. "//"
+	BlockComments [][2]string // start and end markers of block comments, e.g. {"/*", "*/"}
+}
+
+// Languages of raw-content blocks
+var (
+	// LanguageLua is the language of *_by_lua_block directives, read by a Lua-aware scanner
+	LanguageLua = RawLanguage{Name: "lua"}
+	// LanguageJS is the language of inline njs code
+	LanguageJS = RawLanguage{Name: "js", Quotes: "\"'`", LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}}
+	// LanguagePerl is the language of inline Perl code
+	LanguagePerl = RawLanguage{Name: "perl", Quotes: "\"'", LineComments: []string{"#"}}
+)
+
+// rawBlocks maps block directive names, or name suffixes prefixed with *, to the language of their body
+type rawBlocks map[string]RawLanguage
+
+// lookup returns the language of the body of the given block directive, *_by_lua_block directives are always Lua
+func (r rawBlocks) lookup(name string) (RawLanguage, bool) {
+	if isLuaBlock(name) {
+		return LanguageLua, true
+	}
+	return r.match(name)
+}
+
+// match returns the language registered for the given block directive
+func (r rawBlocks) match(name string) (RawLanguage, bool) {
+	if language, ok := r[name]; ok {
+		return language, true
+	}
+	// the longest matching suffix wins
+	var language RawLanguage
+	matched := ""
+	for pattern, l := range r {
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasSuffix(name, suffix) && len(suffix) > len(matched) {
+			language, matched = l, suffix
+		}
+	}
+	return language, matched != ""
+}
+
+// WithRawBlocks registers block directives whose body is kept verbatim as code written in language,
+// instead of being parsed as nginx directives. A name starting with * registers the directives
+// ending with the rest of the name, e.g. *_by_njs_block. The registered directives are accepted as
+// custom directives; the block is wrapped by the config.BlockWrappers entry of its name, if any,
+// and by a *config.RawBlock otherwise
+func WithRawBlocks(language RawLanguage, names ...string) Option {
+	return func(p *Parser) {
+		for _, name := range names {
+			p.opts.rawBlocks[name] = language
+		}
+	}
+}
+
+// scanRawCode returns the body of a raw-content block verbatim, up to the brace closing the block.
+// Braces inside the strings and comments of the language are not counted
+func (s *lexer) scanRawCode(language RawLanguage) token.Token {
+	ret := s.NewToken(token.RawCode)
+	code := strings.Builder{}
+	depth := 0
+
+scanning:
+	for {
+		prev := s.pos
+		ch := s.read()
+		switch {
+		case ch == rune(token.EOF):
+			s.setErrOnce(ret, "unexpected end of file while scanning %s code starting at line %d, column %d", language.Name, ret.Pos.Line, ret.Pos.Column)
+			return s.NewToken(token.EOF).Lit("")
+		case ch == '}' && depth == 0:
+			// the end of block
+			_ = s.reader.UnreadRune()
+			s.pos = prev
+			return ret.Lit(code.String())
+		case ch == '}':
+			depth--
+		case ch == '{':
+			depth++
+		case strings.ContainsRune(language.Quotes, ch):
+			code.WriteRune(ch)
+			code.WriteString(s.readRawUntil(string(ch), true))
+			continue
+		}
+
+		for _, marker := range language.LineComments {
+			if s.follows(ch, marker) {
+				code.WriteRune(ch)
+				code.WriteString(s.readLuaUntil(isEndOfLine))
+				continue scanning
+			}
+		}
+		for _, markers := range language.BlockComments {
+			if s.follows(ch, markers[0]) {
+				code.WriteRune(ch)
+				for range markers[0][1:] {
+					code.WriteRune(s.read())
+				}
+				code.WriteString(s.readRawUntil(markers[1], false))
+				continue scanning
+			}
+		}
+		code.WriteRune(ch)
+	}
+}
+
+// follows reports whether ch, already read, and the next runes are marker
+func (s *lexer) follows(ch rune, marker string) bool {
+	if !strings.HasPrefix(marker, string(ch)) {
+		return false
+	}
+	rest := marker[len(string(ch)):]
+	next, err := s.reader.Peek(len(rest))
+	return err == nil && string(next) == rest
+}
+
+// readRawUntil reads up to and including end, a string delimiter or the end of a block comment.
+// With escapes, a backslash escapes the next rune. At the end of file, the read text is returned
+// and the error is reported by scanRawCode
+func (s *lexer) readRawUntil(end string, escapes bool) string {
+	var buf strings.Builder
+	for {
+		ch := s.peek()
+		if isEOF(ch) {
+			return buf.String()
+		}
+		buf.WriteRune(s.read())
+		if escapes && ch == '\\' {
+			if !isEOF(s.peek()) {
+				buf.WriteRune(s.read())
+			}
+			continue
+		}
+		// only the last len(end) bytes are compared, once the rune read can close end
+		if n := buf.Len(); n >= len(end) && strings.HasSuffix(end, string(ch)) && buf.String()[n-len(end):] == end {
+			return buf.String()
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"github.com/tufanbarisyildirim/gonginx/dumper"
+	"github.com/tufanbarisyildirim/gonginx/parser/token"
+	"gotest.tools/v3/assert"
+)
+
+const njsConf = `http {
+    js_inline_block {
+        // } in a comment
+        function hello(r) {
+            r.return(200, "}" + '{' + ` + "`${r.uri} }`" + `);
+        }
+        /* { */
+    }
+    perl_block $name {
+        # } comment
+        sub { return "}"; }
+    }
+}`
+
+func TestParser_RawBlocks(t *testing.T) {
+	t.Parallel()
+	p := NewStringParser(njsConf,
+		WithRawBlocks(LanguageJS, "*_inline_block"),
+		WithRawBlocks(LanguagePerl, "perl_block"),
+		WithPreserveTrivia())
+	c, err := p.Parse()
+	assert.NilError(t, err)
+
+	js, ok := c.FindDirectives("js_inline_block")[0].(*config.RawBlock)
+	assert.Assert(t, ok)
+	assert.Equal(t, js.Language, "js")
+	assert.Equal(t, js.Code, `
+        // } in a comment
+        function hello(r) {
+            r.return(200, "}" + '{' + `+"`${r.uri} }`"+`);
+        }
+        /* { */
+    `)
+	assert.Equal(t, js.Span.End.Line, 8)
+
+	perl, ok := c.FindDirectives("perl_block")[0].(*config.RawBlock)
+	assert.Assert(t, ok)
+	assert.Equal(t, perl.Language, "perl")
+	assert.Equal(t, perl.Parameters[0].Value, "$name")
+	assert.Equal(t, perl.Code, "\n        # } comment\n        sub { return \"}\"; }\n    ")
+
+	assert.Equal(t, dumper.DumpConfig(c, dumper.LosslessStyle), njsConf)
+	assert.Equal(t, dumper.DumpConfig(c, dumper.IndentedStyle), njsConf)
+
+	// without registration the body is parsed as nginx directives
+	_, err = NewStringParser(njsConf).Parse()
+	var unknown *UnknownDirectiveError
+	assert.Assert(t, errors.As(err, &unknown))
+}
+
+func TestParser_RawBlocks_Wrapper(t *testing.T) {
+	t.Parallel()
+	p := NewStringParser("my_code_block {\n    a { b\n}\n", WithRawBlocks(RawLanguage{Name: "text"}, "my_code_block"))
+	p.blockWrappers = map[string]func(*config.Directive) (config.IDirective, error){
+		"my_code_block": func(d *config.Directive) (config.IDirective, error) {
+			return &config.Server{Block: d.Block}, nil
+		},
+	}
+	c, err := p.Parse()
+	assert.Error(t, err, "unexpected end of file while scanning text code starting at line 1, column 16")
+	assert.Assert(t, c == nil)
+
+	p = NewStringParser("my_code_block {\n    a { b }\n}\n", WithRawBlocks(RawLanguage{Name: "text"}, "my_code_block"))
+	p.blockWrappers = map[string]func(*config.Directive) (config.IDirective, error){
+		"my_code_block": func(d *config.Directive) (config.IDirective, error) {
+			return &config.Server{Block: d.Block}, nil
+		},
+	}
+	c, err = p.Parse()
+	assert.NilError(t, err)
+	server, ok := c.Directives[0].(*config.Server)
+	assert.Assert(t, ok)
+	assert.Equal(t, server.GetBlock().GetCodeBlock(), "\n    a { b }\n")
+	assert.Equal(t, server.GetBlock().(*config.Block).Language, "text")
+}
+
+func TestParser_RawBlocks_Stream(t *testing.T) {
+	t.Parallel()
+	var events []string
+	err := NewStringParser(njsConf, WithRawBlocks(LanguageJS, "js_inline_block"), WithRawBlocks(LanguagePerl, "perl_block")).Stream(eventLog(&events))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, events, []string{
+		"0 EnterBlock http",
+		"1 Directive js_inline_block",
+		"1 Directive perl_block",
+		"0 LeaveBlock http",
+	})
+}
+
+func TestRawBlocks_Lookup(t *testing.T) {
+	t.Parallel()
+	r := rawBlocks{"*_block": LanguagePerl, "*_js_block": LanguageJS, "exact": {Name: "text"}}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"content_by_lua_block", "lua"},
+		{"my_js_block", "js"},
+		{"my_block", "perl"},
+		{"exact", "text"},
+		{"server", ""},
+	}
+	for _, tt := range tests {
+		language, _ := r.lookup(tt.name)
+		assert.Equal(t, language.Name, tt.want, tt.name)
+	}
+}
+
+func TestScanner_LexRawCode(t *testing.T) {
+	t.Parallel()
+	l := lex("a {\n/* } */ 'x}' }")
+	l.rawBlocks = rawBlocks{"a": LanguageJS}
+	actual := l.all()
+	assert.Equal(t, len(actual), 4)
+	assert.Equal(t, actual[2].Type, token.RawCode)
+	assert.Equal(t, actual[2].Literal, "\n/* } */ 'x}' ")
+	assert.Equal(t, actual[3].Type, token.BlockEnd)
+}
+
+func TestScanner_LexRawCode_LongComment(t *testing.T) {
+	t.Parallel()
+	// the end of a comment is looked for in the last bytes read only
+	code := "\n/* " + strings.Repeat("} *", 1<<18) + "/ 'x}' "
+	l := lex("a {" + code + "}")
+	l.rawBlocks = rawBlocks{"a": LanguageJS}
+	actual := l.all()
+	assert.Equal(t, len(actual), 4)
+	assert.Equal(t, actual[2].Literal, code)
+}
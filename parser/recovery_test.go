@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"gotest.tools/v3/assert"
+)
+
+func TestParser_ErrorRecovery_ReportsAllErrors(t *testing.T) {
+	t.Parallel()
+	conf, err := NewStringParser(`
+http {
+    unknown_one on;
+    server {
+        listen 80;
+        location a b c {}
+        proxy_pass http://a
+    }
+    unknown_two off;
+    server {
+        listen 81;
+    }
+}
+include;
+`, WithErrorRecovery()).Parse()
+
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 5)
+	assert.Error(t, diagnostics[0], "unknown directive 'unknown_one' on line 3, column 5")
+	assert.Equal(t, diagnostics[0].Pos.Line, 3)
+	assert.Error(t, diagnostics[1], "too many arguments for location directive")
+	assert.Equal(t, diagnostics[1].Pos.Line, 6)
+	assert.Error(t, diagnostics[2], "unexpected token BlockEnd (}) on line 8, column 5")
+	assert.Error(t, diagnostics[3], "unknown directive 'unknown_two' on line 9, column 5")
+	assert.Error(t, diagnostics[4], "include directive requires exactly 1 parameter, got 0")
+
+	// the partial config is still built around the errors
+	assert.Assert(t, conf != nil)
+	http, ok := conf.Directives[0].(*config.HTTP)
+	assert.Assert(t, ok)
+	assert.Equal(t, len(http.Servers), 2)
+	assert.Equal(t, len(conf.FindDirectives("listen")), 2)
+	assert.Equal(t, len(conf.FindDirectives("unknown_two")), 1)
+	assert.Equal(t, len(conf.FindDirectives("proxy_pass")), 0)
+
+	// a location with invalid arguments is kept as a plain directive
+	_, isLocation := conf.FindDirectives("location")[0].(*config.Location)
+	assert.Assert(t, !isLocation)
+}
+
+func TestParser_ErrorRecovery_UnclosedBlocks(t *testing.T) {
+	t.Parallel()
+	conf, err := NewStringParser(`http {
+    server {
+        listen 80;
+`, WithErrorRecovery()).Parse()
+
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 1)
+	assert.Error(t, diagnostics[0], "unexpected eof in block")
+	assert.Equal(t, len(conf.FindDirectives("listen")), 1)
+}
+
+func TestParser_ErrorRecovery_UnfinishedDirective(t *testing.T) {
+	t.Parallel()
+	conf := "http {\n    server {\n        listen 80"
+	// the directive cut by the end of file is reported, not the blocks it leaves open
+	_, err := NewStringParser(conf, WithErrorRecovery()).Parse()
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 1)
+	assert.Equal(t, diagnostics[0].Pos.String(), "3:18")
+
+	err = NewStringParser(conf, WithErrorRecovery()).Stream(func(Event) error { return nil })
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 1)
+}
+
+func TestParser_ErrorRecovery_LexerError(t *testing.T) {
+	t.Parallel()
+	conf, err := NewStringParser(`user nginx;
+server {
+	set $a "unterminated
+}`, WithErrorRecovery()).Parse()
+
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.ErrorContains(t, diagnostics[0], "scanning quoted string")
+	assert.Equal(t, diagnostics[0].Pos.Line, 3)
+	assert.Equal(t, diagnostics[0].Pos.Column, 9)
+	assert.Equal(t, len(conf.FindDirectives("user")), 1)
+}
+
+func TestParser_ErrorRecovery_NoErrors(t *testing.T) {
+	t.Parallel()
+	conf, err := NewStringParser(`user nginx;`, WithErrorRecovery()).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, len(conf.Directives), 1)
+}
+
+func TestParser_ErrorRecovery_IncludedFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte("bad_main;\ninclude sub.conf;\n"), 0644))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "sub.conf"), []byte("bad_sub;\nuser nginx;\n"), 0644))
+
+	p, err := NewParser(filepath.Join(dir, "nginx.conf"), WithIncludeParsing(), WithErrorRecovery())
+	assert.NilError(t, err)
+	conf, err := p.Parse()
+
+	var diagnostics Diagnostics
+	assert.Assert(t, errors.As(err, &diagnostics))
+	assert.Equal(t, len(diagnostics), 2)
+	assert.Equal(t, diagnostics[0].Pos.Filename, filepath.Join(dir, "nginx.conf"))
+	assert.Equal(t, diagnostics[1].Pos.Filename, filepath.Join(dir, "sub.conf"))
+	assert.Equal(t, len(conf.FindDirectives("user")), 1)
+}
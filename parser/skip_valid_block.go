@@ -4,6 +4,8 @@ import "strings"
 
 var skipValidBlocks = `types
 map
+geo
+split_clients
 `
 
 // SkipValidBlocks defines a list of valid blocks to be skipped during initialization.
@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"errors"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"github.com/tufanbarisyildirim/gonginx/parser/token"
+)
+
+// EventType is the kind of a streaming parse event
+type EventType int
+
+// Streaming parse events
+const (
+	// EventEnterBlock is sent for a block directive, before its content.
+	// The directive has its name, parameters and comments but no block yet
+	EventEnterBlock EventType = iota
+	// EventLeaveBlock is sent after the content of a block, with the directive of EventEnterBlock
+	EventLeaveBlock
+	// EventDirective is sent for a directive without block content, Lua blocks included
+	EventDirective
+	// EventComment is sent for an outline comment, it is also kept on the directive that follows it
+	EventComment
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case EventEnterBlock:
+		return "EnterBlock"
+	case EventLeaveBlock:
+		return "LeaveBlock"
+	case EventDirective:
+		return "Directive"
+	case EventComment:
+		return "Comment"
+	}
+	return "Unknown"
+}
+
+// Event is sent to the handler of Stream
+type Event struct {
+	Type      EventType
+	Directive config.IDirective // the directive, nil for comments
+	Comment   string            // the comment text, for comments
+	Span      config.Span       // the source range of the event, up to the opening brace for EventEnterBlock
+	Depth     int               // block nesting depth, 0 at the top level of the parsed file
+}
+
+// SkipBlock can be returned by a handler on EventEnterBlock to skip the content of the block,
+// EventLeaveBlock is still sent. Returned on the EventDirective of an include, it skips the included files
+var SkipBlock = errors.New("skip this block")
+
+// SkipAll can be returned by a handler to stop streaming, Stream then returns nil
+var SkipAll = errors.New("skip everything")
+
+// handlerError carries an error returned by the handler up to Stream, error recovery does not apply to it
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *handlerError) Unwrap() error {
+	return e.err
+}
+
+// Stream parses the config without building it, sending every directive, block and comment
+// to handler in source order. It honours the parser options: comments are not sent with
+// WithSkipComments, directives are validated the same way as Parse, and with WithIncludeParsing
+// the included files are streamed right after their include directive, each time they are included.
+// A handler error stops the parse and is returned by Stream, see SkipBlock and SkipAll
+func (p *Parser) Stream(handler func(Event) error) error {
+	p.handler = handler
+	err := p.streamFile()
+
+	var handlerErr *handlerError
+	if errors.As(err, &handlerErr) {
+		if errors.Is(handlerErr.err, SkipAll) {
+			return nil
+		}
+		return handlerErr.err
+	}
+	return err
+}
+
+// streamFile sends the events of the parsed file to the handler, handler errors are kept wrapped
+func (p *Parser) streamFile() (err error) {
+	if p.file != nil {
+		defer p.closeFile(&err)
+	}
+
+	_, err = p.parseBlock(false, p.skipValid)
+	p.decorateLexerErr()
+
+	var handlerErr *handlerError
+	if errors.As(err, &handlerErr) {
+		return err
+	}
+	if p.opts.errorRecovery {
+		_, err = p.recoveredConfig(nil)
+		return err
+	}
+	if err != nil {
+		if p.lexer.Err != nil {
+			return errors.Join(p.lexer.Err, err)
+		}
+		return err
+	}
+	return p.lexer.Err
+}
+
+// emit sends an event to the handler, it returns SkipBlock as is and wraps other handler errors
+func (p *Parser) emit(e Event) error {
+	e.Depth = p.depth
+	err := p.handler(e)
+	if err == nil || errors.Is(err, SkipBlock) {
+		return err
+	}
+	return &handlerError{err: err}
+}
+
+// streamBlock sends the events of a block directive whose opening brace is the current token
+func (p *Parser) streamBlock(d *config.Directive, start token.Position, lbrace config.Span, isSkipValidDirective bool) (config.IDirective, error) {
+	err := p.emit(Event{Type: EventEnterBlock, Directive: d, Span: config.Span{Start: start, End: lbrace.End}})
+	if err != nil && !errors.Is(err, SkipBlock) {
+		return nil, err
+	}
+
+	parentContext, parentBlock, parentSkipValid := p.context, p.block, p.skipValid
+	p.context, p.block, p.skipValid = blockContext(d.Name, parentContext), d.Name, isSkipValidDirective
+	p.depth++
+	if errors.Is(err, SkipBlock) {
+		err = p.skipBlock()
+	} else {
+		_, err = p.parseBlock(true, isSkipValidDirective)
+	}
+	p.depth--
+	p.context, p.block, p.skipValid = parentContext, parentBlock, parentSkipValid
+	if err != nil {
+		return nil, err
+	}
+
+	d.Block = &config.Block{Directives: []config.IDirective{}}
+	d.Block.SetBraces(lbrace, tokenSpan(p.currentToken))
+	d.Span = config.Span{Start: start, End: p.currentToken.End}
+	if err := p.validateArguments(d, isSkipValidDirective); err != nil {
+		return nil, err
+	}
+	if err := p.emit(Event{Type: EventLeaveBlock, Directive: d, Span: d.Span}); err != nil && !errors.Is(err, SkipBlock) {
+		return nil, err
+	}
+	return d, nil
+}
+
+// skipBlock moves to the brace closing the block opened by the current token
+func (p *Parser) skipBlock() error {
+	depth := 1
+	for depth > 0 {
+		p.nextToken()
+		switch {
+		case p.curTokenIs(token.EOF):
+			err := p.syntaxError(p.currentToken, "unexpected eof in block")
+			if !p.opts.errorRecovery {
+				return err
+			}
+			if !p.eofReported {
+				p.tolerate(p.currentToken.Pos, err)
+				p.eofReported = true
+			}
+			return nil
+		case p.curTokenIs(token.BlockStart):
+			depth++
+		case p.curTokenIs(token.BlockEnd):
+			depth--
+		}
+	}
+	return nil
+}
+
+// isStreamed reports whether the events of a parsed directive were already sent
+func isStreamed(s config.IDirective) bool {
+	if _, ok := s.(*config.Include); ok {
+		return true
+	}
+	b, ok := s.GetBlock().(*config.Block)
+	return ok && b != nil && !b.IsLuaBlock && b.Language == ""
+}
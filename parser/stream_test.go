@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"gotest.tools/v3/assert"
+)
+
+// eventLog records events as "depth type name" lines
+func eventLog(events *[]string) func(Event) error {
+	return func(e Event) error {
+		name := e.Comment
+		if e.Directive != nil {
+			name = e.Directive.GetName()
+		}
+		*events = append(*events, fmt.Sprintf("%d %s %s", e.Depth, e.Type, name))
+		return nil
+	}
+}
+
+func TestParser_Stream(t *testing.T) {
+	t.Parallel()
+	var events []string
+	err := NewStringParser(`# main
+user nginx;
+http {
+    upstream backend {
+        server 127.0.0.1:80;
+    }
+    server {
+        listen 80; # inline
+        location / {
+            content_by_lua_block {
+                ngx.say("hello")
+            }
+        }
+    }
+}`).Stream(eventLog(&events))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, events, []string{
+		"0 Comment # main",
+		"0 Directive user",
+		"0 EnterBlock http",
+		"1 EnterBlock upstream",
+		"2 Directive server",
+		"1 LeaveBlock upstream",
+		"1 EnterBlock server",
+		"2 Directive listen",
+		"2 EnterBlock location",
+		"3 Directive content_by_lua_block",
+		"2 LeaveBlock location",
+		"1 LeaveBlock server",
+		"0 LeaveBlock http",
+	})
+}
+
+func TestParser_Stream_Directives(t *testing.T) {
+	t.Parallel()
+	var directives []config.IDirective
+	var spans []config.Span
+	err := NewStringParser("# comment\nhttp {\n    upstream backend {\n        server 127.0.0.1:80 weight=2;\n    }\n    gzip on; # inline\n}").Stream(func(e Event) error {
+		if e.Type == EventLeaveBlock || e.Type == EventDirective {
+			directives = append(directives, e.Directive)
+			spans = append(spans, e.Span)
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(directives), 4)
+
+	// leaf directives are wrapped like in Parse, block content is not kept
+	server, ok := directives[0].(*config.UpstreamServer)
+	assert.Assert(t, ok)
+	assert.Equal(t, server.Address, "127.0.0.1:80")
+	assert.Equal(t, len(directives[1].GetBlock().GetDirectives()), 0)
+	assert.Equal(t, directives[2].GetInlineComment()[0].Value, "# inline")
+	assert.DeepEqual(t, directives[3].GetComment(), []string{"# comment"})
+
+	assert.Equal(t, spans[2].Start.String(), "6:5")
+	assert.Equal(t, spans[3].Start.String(), "2:1")
+	assert.Equal(t, spans[3].End.String(), "7:2")
+}
+
+func TestParser_Stream_SkipBlock(t *testing.T) {
+	t.Parallel()
+	var events []string
+	log := eventLog(&events)
+	err := NewStringParser(`http {
+    map $a $b {
+        default 0;
+    }
+    server {
+        listen 80;
+        location / {
+            root /a;
+        }
+    }
+    server {
+        listen 81;
+    }
+}`).Stream(func(e Event) error {
+		_ = log(e)
+		if e.Type == EventEnterBlock && e.Directive.GetName() == "server" {
+			return SkipBlock
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, events, []string{
+		"0 EnterBlock http",
+		"1 EnterBlock map",
+		"2 Directive default",
+		"1 LeaveBlock map",
+		"1 EnterBlock server",
+		"1 LeaveBlock server",
+		"1 EnterBlock server",
+		"1 LeaveBlock server",
+		"0 LeaveBlock http",
+	})
+}
+
+func TestParser_Stream_Abort(t *testing.T) {
+	t.Parallel()
+	conf := "listen 80;\nlisten 81;\nlisten 82;\nunknown_directive;"
+
+	var count int
+	err := NewStringParser(conf).Stream(func(e Event) error {
+		count++
+		if count == 2 {
+			return SkipAll
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, count, 2)
+
+	errStop := errors.New("stop")
+	err = NewStringParser(conf, WithErrorRecovery()).Stream(func(e Event) error {
+		return errStop
+	})
+	assert.Assert(t, errors.Is(err, errStop))
+
+	// parse errors are returned as in Parse
+	err = NewStringParser(conf).Stream(func(e Event) error {
+		return nil
+	})
+	assert.Error(t, err, "unknown directive 'unknown_directive' on line 4, column 1")
+}
+
+func TestParser_Stream_Options(t *testing.T) {
+	t.Parallel()
+	var events []string
+	err := NewStringParser("# comment\nmy_directive on;", WithSkipComments(), WithCustomDirectives("my_directive")).Stream(eventLog(&events))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, events, []string{"0 Directive my_directive"})
+
+	err = NewStringParser("server {\n    gzip yes;\n}", WithArgumentValidation(), WithErrorRecovery()).Stream(func(Event) error { return nil })
+	assert.ErrorContains(t, err, `invalid value "yes" in "gzip" directive`)
+}
+
+func TestParser_Stream_Includes(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf":   {Data: []byte("http {\n    include servers.conf;\n    include servers.conf;\n    include skipped.conf;\n}\n")},
+		"servers.conf": {Data: []byte("server {\n    listen 80;\n}\n")},
+		"skipped.conf": {Data: []byte("server {\n    listen 81;\n}\n")},
+	}
+
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	var events []string
+	log := eventLog(&events)
+	err = p.Stream(func(e Event) error {
+		_ = log(e)
+		if e.Type == EventDirective && e.Directive.GetParameters()[0].Value == "skipped.conf" {
+			return SkipBlock
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Join(events, "\n"), strings.Join([]string{
+		"0 EnterBlock http",
+		"1 Directive include",
+		"1 EnterBlock server",
+		"2 Directive listen",
+		"1 LeaveBlock server",
+		"1 Directive include",
+		"1 EnterBlock server",
+		"2 Directive listen",
+		"1 LeaveBlock server",
+		"1 Directive include",
+		"0 LeaveBlock http",
+	}, "\n"))
+}
+
+func TestParser_Stream_MapInclude(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("http {\n    map $uri $x {\n        default 0;\n        include x.map;\n    }\n}\n")},
+		"x.map":      {Data: []byte("~^/api 1;\n/login 2;\n")},
+	}
+
+	// the included entries are read as map entries, as Parse does
+	p, err := NewFSParser(fsys, "nginx.conf", WithIncludeParsing())
+	assert.NilError(t, err)
+	var events []string
+	assert.NilError(t, p.Stream(eventLog(&events)))
+	assert.Equal(t, strings.Join(events, "\n"), strings.Join([]string{
+		"0 EnterBlock http",
+		"1 EnterBlock map",
+		"2 Directive default",
+		"2 Directive include",
+		"2 Directive ~^/api",
+		"2 Directive /login",
+		"1 LeaveBlock map",
+		"0 LeaveBlock http",
+	}, "\n"))
+}
@@ -34,6 +34,8 @@ const (
 	Regex
 	// LuaCode lua block
 	LuaCode
+	// RawCode body of a raw-content block, see parser.WithRawBlocks
+	RawCode
 )
 
 var (
@@ -49,6 +51,8 @@ var (
 		EndOfLine:    "EndOfLine",
 		Illegal:      "Illegal",
 		Regex:        "Regex",
+		LuaCode:      "LuaCode",
+		RawCode:      "RawCode",
 	}
 )
 
@@ -57,12 +61,41 @@ func (tt Type) String() string {
 	return tokenName[tt]
 }
 
+// Position represents a location in a source file
+type Position struct {
+	Filename string // file name, if any
+	Offset   int    // byte offset, starting at 0
+	Line     int    // line number, starting at 1
+	Column   int    // column number in runes, starting at 1
+}
+
+// IsValid reports whether the position is set
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as file:line:column (or line:column without file)
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
 // Token represents a config token
 type Token struct {
 	Type    Type
 	Literal string
 	Line    int
 	Column  int
+	Pos     Position // position of the first character of the token
+	End     Position // position immediately after the last character of the token
 }
 
 func (t Token) String() string {
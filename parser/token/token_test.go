@@ -398,3 +398,36 @@ func TestToken_IsParameterEligible(t *testing.T) {
 		})
 	}
 }
+
+func TestPosition_String(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  Position
+		want string
+	}{
+		{
+			name: "with file name",
+			pos:  Position{Filename: "nginx.conf", Offset: 10, Line: 2, Column: 3},
+			want: "nginx.conf:2:3",
+		},
+		{
+			name: "without file name",
+			pos:  Position{Offset: 10, Line: 2, Column: 3},
+			want: "2:3",
+		},
+		{
+			name: "invalid position",
+			pos:  Position{},
+			want: "-",
+		},
+	}
+	for _, tt := range tests {
+		tt2 := tt
+		t.Run(tt2.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt2.pos.String(); got != tt2.want {
+				t.Errorf("Position.String() = %v, want %v", got, tt2.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tufanbarisyildirim/gonginx/config"
+	"github.com/tufanbarisyildirim/gonginx/dumper"
+	"gotest.tools/v3/assert"
+)
+
+func TestParser_PreserveTrivia_RoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, file := range []string{
+		"../testdata/full_conf/nginx.conf",
+		"../testdata/full_conf/mime.types",
+		"../full-example/nginx.conf",
+		"../full-example/formatting/raw.conf",
+		"../testdata/issues/17.conf",
+		"../testdata/issues/20.conf",
+		"../testdata/issues/50.conf",
+	} {
+		source, err := os.ReadFile(file)
+		assert.NilError(t, err)
+
+		conf, err := NewStringParser(string(source), WithPreserveTrivia(), WithSkipValidDirectivesErr()).Parse()
+		assert.NilError(t, err, file)
+		assert.Equal(t, dumper.DumpConfig(conf, dumper.LosslessStyle), string(source), file)
+	}
+}
+
+func TestParser_PreserveTrivia_MinimalDiff(t *testing.T) {
+	t.Parallel()
+	source := "user  nginx;\r\n\r\nhttp {\r\n\tserver {\r\n\t\tlisten   80;   # plain http\r\n\r\n\t\tlocation / {\r\n\t\t\tproxy_pass   http://old:8080;\r\n\t\t}\r\n\t}\r\n}\r\n"
+	conf, err := NewStringParser(source, WithPreserveTrivia()).Parse()
+	assert.NilError(t, err)
+
+	proxyPass := conf.FindDirectives("proxy_pass")[0].(*config.Directive)
+	proxyPass.Parameters[0].SetValue("http://new:8080")
+
+	expected := strings.Replace(source, "proxy_pass   http://old:8080;", "proxy_pass http://new:8080;", 1)
+	assert.Equal(t, dumper.DumpConfig(conf, dumper.LosslessStyle), expected)
+}
+
+func TestParser_PreserveTrivia_AddedAndRemovedDirectives(t *testing.T) {
+	t.Parallel()
+	source := `server {
+  listen 80;
+
+  # static files
+  root   /srv;
+  index  index.html;
+}
+`
+	conf, err := NewStringParser(source, WithPreserveTrivia()).Parse()
+	assert.NilError(t, err)
+
+	block := conf.Directives[0].GetBlock().(*config.Block)
+	block.Directives = append(block.Directives[:2], &config.Directive{
+		Name:       "access_log",
+		Parameters: []config.Parameter{{Value: "off"}},
+		Block: &config.Block{
+			Directives: []config.IDirective{&config.Directive{Name: "x", Parameters: []config.Parameter{{Value: "1"}}}},
+		},
+	})
+
+	assert.Equal(t, dumper.DumpConfig(conf, dumper.LosslessStyle), `server {
+  listen 80;
+
+  # static files
+  root   /srv;
+  access_log off {
+      x 1;
+  }
+}
+`)
+}
+
+func TestParser_PreserveTrivia_KeepsSourceOrderOfWrappers(t *testing.T) {
+	t.Parallel()
+	source := `http {
+    server { listen 80; }
+    include mime.types;
+    upstream backend {
+        server 127.0.0.1:80  weight=5;
+        keepalive 16;
+    }
+}`
+	conf, err := NewStringParser(source, WithPreserveTrivia()).Parse()
+	assert.NilError(t, err)
+	assert.Equal(t, dumper.DumpConfig(conf, dumper.LosslessStyle), source)
+}